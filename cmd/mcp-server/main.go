@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
@@ -47,14 +48,31 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 
 	// Register pdf_to_images tool
 	pdfToImagesTool := mcp.NewTool("pdf_to_images",
-		mcp.WithDescription("Convert PDF pages to PNG or JPG images"),
+		mcp.WithDescription("Convert PDF pages to PNG, JPG, WebP or TIFF images"),
 		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file to convert")),
 		mcp.WithString("output_dir", mcp.Required(), mcp.Description("Directory where images will be saved")),
-		mcp.WithString("format", mcp.Description("Output format: 'png' or 'jpg' (default: png)")),
+		mcp.WithString("format", mcp.Description("Output format: 'png', 'jpg', 'webp' or 'tiff' (default: png)"), mcp.Enum("png", "jpg", "webp", "tiff")),
 		mcp.WithNumber("dpi", mcp.Description("DPI for rendering (default: 150)")),
+		mcp.WithNumber("width", mcp.Description("Render pages this many pixels wide instead of using dpi")),
+		mcp.WithNumber("height", mcp.Description("Render pages this many pixels tall instead of using dpi (with width: fit inside the box)")),
+		mcp.WithNumber("max_pixels", mcp.Description("Scale down pages larger than this many pixels (default: 0, no limit)")),
+		mcp.WithString("pages", mcp.Description("Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all; replaces start_page/end_page)")),
 		mcp.WithNumber("start_page", mcp.Description("Start page number (1-indexed, 0 for first page)")),
 		mcp.WithNumber("end_page", mcp.Description("End page number (1-indexed, 0 for last page)")),
 		mcp.WithString("prefix", mcp.Description("Prefix for output filenames (default: page_)")),
+		mcp.WithString("name_template", mcp.Description("Output file name template, e.g. '{basename}_{page:05}_{dpi}dpi.{ext}' (placeholders: basename, prefix, page, label, total, dpi, width, height, hash, ext; default: {prefix}{page}.{ext})")),
+		mcp.WithBoolean("resume", mcp.Description("Skip pages a previous call already rendered into output_dir, as recorded in its manifest (default: false)")),
+		mcp.WithNumber("page_timeout", mcp.Description("Give up on a page after this many seconds (default: 0, no limit)")),
+		mcp.WithNumber("quality", mcp.Description("JPEG quality 1-100 (default: 90)")),
+		mcp.WithString("png_compression", mcp.Description("PNG compression level (default: default)"), mcp.Enum("default", "none", "fast", "best")),
+		mcp.WithBoolean("palette", mcp.Description("Reduce png/tiff/webp output to an 8-bit palette of 256 colors (default: false)")),
+		mcp.WithString("color_mode", mcp.Description("Colors of the output: full color, 8-bit gray, or 1-bit black and white split at threshold (bilevel) or dithered (default: color)"), mcp.Enum("color", "gray", "bilevel", "dither")),
+		mcp.WithNumber("threshold", mcp.Description("Gray level 1-255 below which bilevel and dither pixels turn black (default: 128)")),
+		mcp.WithString("render_flags", mcp.Description("Comma-separated rendering options: annotations, forms (form field values), print, lcd_text, no_smooth_text, no_smooth_image, no_smooth_path (default: page content only)")),
+		mcp.WithString("background", mcp.Description("Page background: #rrggbb, #rrggbbaa or transparent for png/webp/tiff; jpg gets an opaque color (default: white)")),
+		mcp.WithString("page_box", mcp.Description("Page boundary to render: media (whole sheet), crop (what viewers show), bleed, trim (finished size) or art (default: crop)"), mcp.Enum("media", "crop", "bleed", "trim", "art")),
+		mcp.WithBoolean("multi_page", mcp.Description("Write all pages into a single file named after the PDF (tiff only, default: false)")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
 	)
 
 	s.AddTool(pdfToImagesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -71,9 +89,24 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 		// Extract optional parameters from Arguments map
 		format := "png"
 		dpi := 150.0
+		width, height, maxPixels := 0, 0, 0
+		pages := ""
 		startPage := 0
 		endPage := 0
 		prefix := "page_"
+		nameTemplate := ""
+		pageTimeout := 0.0
+		multiPage := false
+		resume := false
+		quality := 0
+		pngCompression := ""
+		palette := false
+		colorMode := ""
+		threshold := 0
+		renderFlags := ""
+		background := ""
+		pageBox := ""
+		password := ""
 
 		if args, ok := request.Params.Arguments.(map[string]interface{}); ok {
 			if f, ok := args["format"].(string); ok && f != "" {
@@ -82,6 +115,18 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 			if d, ok := args["dpi"].(float64); ok && d > 0 {
 				dpi = d
 			}
+			if w, ok := args["width"].(float64); ok {
+				width = int(w)
+			}
+			if h, ok := args["height"].(float64); ok {
+				height = int(h)
+			}
+			if mp, ok := args["max_pixels"].(float64); ok {
+				maxPixels = int(mp)
+			}
+			if p, ok := args["pages"].(string); ok {
+				pages = p
+			}
 			if sp, ok := args["start_page"].(float64); ok {
 				startPage = int(sp)
 			}
@@ -91,18 +136,79 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 			if p, ok := args["prefix"].(string); ok && p != "" {
 				prefix = p
 			}
+			if nt, ok := args["name_template"].(string); ok {
+				nameTemplate = nt
+			}
+			if pt, ok := args["page_timeout"].(float64); ok && pt > 0 {
+				pageTimeout = pt
+			}
+			if mp, ok := args["multi_page"].(bool); ok {
+				multiPage = mp
+			}
+			if r, ok := args["resume"].(bool); ok {
+				resume = r
+			}
+			if q, ok := args["quality"].(float64); ok {
+				quality = int(q)
+			}
+			if pc, ok := args["png_compression"].(string); ok {
+				pngCompression = pc
+			}
+			if pl, ok := args["palette"].(bool); ok {
+				palette = pl
+			}
+			if cm, ok := args["color_mode"].(string); ok {
+				colorMode = cm
+			}
+			if th, ok := args["threshold"].(float64); ok {
+				threshold = int(th)
+			}
+			if rf, ok := args["render_flags"].(string); ok {
+				renderFlags = rf
+			}
+			if bg, ok := args["background"].(string); ok {
+				background = bg
+			}
+			if pb, ok := args["page_box"].(string); ok {
+				pageBox = pb
+			}
+			if pw, ok := args["password"].(string); ok {
+				password = pw
+			}
 		}
 
-		// Execute tool using local server
-		result, err := localServer.ExecuteTool("pdf_to_images", []byte(fmt.Sprintf(`{
-			"pdf_path": "%s",
-			"output_dir": "%s",
-			"format": "%s",
-			"dpi": %f,
-			"start_page": %d,
-			"end_page": %d,
-			"prefix": "%s"
-		}`, pdfPath, outputDir, format, dpi, startPage, endPage, prefix)))
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path":        pdfPath,
+			"output_dir":      outputDir,
+			"format":          format,
+			"dpi":             dpi,
+			"width":           width,
+			"height":          height,
+			"max_pixels":      maxPixels,
+			"pages":           pages,
+			"start_page":      startPage,
+			"end_page":        endPage,
+			"prefix":          prefix,
+			"name_template":   nameTemplate,
+			"page_timeout":    pageTimeout,
+			"multi_page":      multiPage,
+			"resume":          resume,
+			"quality":         quality,
+			"png_compression": pngCompression,
+			"palette":         palette,
+			"color_mode":      colorMode,
+			"threshold":       threshold,
+			"render_flags":    renderFlags,
+			"background":      background,
+			"page_box":        pageBox,
+			"password":        password,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		// Execute tool using local server, cancelled together with the request
+		result, err := localServer.ExecuteToolContext(withProgressNotifications(ctx, s, request), "pdf_to_images", input)
 
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Conversion error: %v", err)), nil
@@ -113,8 +219,9 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 
 	// Register pdf_info tool
 	pdfInfoTool := mcp.NewTool("pdf_info",
-		mcp.WithDescription("Get information about a PDF file (pages, size, dimensions)"),
+		mcp.WithDescription("Get information about a PDF file (size, version, pages, encryption, outline, page boxes and rotation)"),
 		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
 	)
 
 	s.AddTool(pdfInfoTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -123,9 +230,424 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 			return mcp.NewToolResultError("pdf_path is required"), nil
 		}
 
+		password := request.GetString("password", "")
+
 		// Execute tool using local server
-		result, err := localServer.ExecuteTool("pdf_info", []byte(fmt.Sprintf(`{"pdf_path": "%s"}`, pdfPath)))
+		input, err := json.Marshal(map[string]interface{}{"pdf_path": pdfPath, "password": password})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_info", input)
+
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	// Register pdf_extract_text tool
+	pdfExtractTextTool := mcp.NewTool("pdf_extract_text",
+		mcp.WithDescription("Extract the plain text of PDF pages"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("pages", mcp.Description("Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)")),
+		mcp.WithNumber("max_chars_per_page", mcp.Description("Truncate the text of each page to this many characters (default: 0, no limit)")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfExtractTextTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path":           pdfPath,
+			"pages":              request.GetString("pages", ""),
+			"max_chars_per_page": request.GetInt("max_chars_per_page", 0),
+			"password":           request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_extract_text", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	// Register pdf_text_layout tool
+	pdfTextLayoutTool := mcp.NewTool("pdf_text_layout",
+		mcp.WithDescription("Extract PDF text with bounding boxes (blocks, lines, words, chars) in points and in pixels of the rendered pages"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("pages", mcp.Description("Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)")),
+		mcp.WithString("level", mcp.Description("Finest level to include (default: words)"), mcp.Enum("blocks", "lines", "words", "chars")),
+		mcp.WithNumber("dpi", mcp.Description("DPI of the images pixel coordinates refer to, as passed to pdf_to_images (default: 150)")),
+		mcp.WithNumber("width", mcp.Description("Pixel coordinates for images rendered this many pixels wide")),
+		mcp.WithNumber("height", mcp.Description("Pixel coordinates for images rendered this many pixels tall")),
+		mcp.WithNumber("max_pixels", mcp.Description("Pixel coordinates for images scaled down to this many pixels")),
+		mcp.WithString("page_box", mcp.Description("Page boundary of the images pixel coordinates refer to, as passed to pdf_to_images (default: crop)"), mcp.Enum("media", "crop", "bleed", "trim", "art")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfTextLayoutTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path":   pdfPath,
+			"pages":      request.GetString("pages", ""),
+			"level":      request.GetString("level", ""),
+			"dpi":        request.GetFloat("dpi", 0),
+			"width":      request.GetInt("width", 0),
+			"height":     request.GetInt("height", 0),
+			"max_pixels": request.GetInt("max_pixels", 0),
+			"page_box":   request.GetString("page_box", ""),
+			"password":   request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_text_layout", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	// Register pdf_search tool
+	pdfSearchTool := mcp.NewTool("pdf_search",
+		mcp.WithDescription("Find a string or regex in a PDF: page numbers, snippets and hit rectangles, optionally rendering the pages with the hits highlighted"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Text to find (case-insensitive unless case_sensitive), or a regular expression with regex")),
+		mcp.WithString("pages", mcp.Description("Pages to search, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)")),
+		mcp.WithBoolean("regex", mcp.Description("Treat query as a regular expression (RE2 syntax)")),
+		mcp.WithBoolean("case_sensitive", mcp.Description("Match case (default: false)")),
+		mcp.WithNumber("context_chars", mcp.Description("Characters of context on each side of a hit (default: 40)")),
+		mcp.WithNumber("max_hits", mcp.Description("Stop after this many hits (default: 0, no limit)")),
+		mcp.WithString("highlight_dir", mcp.Description("Render the pages with hits into this directory with the hits highlighted")),
+		mcp.WithString("format", mcp.Description("Format of the highlighted pages (default: png)"), mcp.Enum("png", "jpg", "webp", "tiff")),
+		mcp.WithNumber("dpi", mcp.Description("DPI of the highlighted pages and pixel rectangles (default: 150)")),
+		mcp.WithString("page_box", mcp.Description("Page boundary of the highlighted pages and pixel rectangles: media, crop, bleed, trim or art (default: crop)"), mcp.Enum("media", "crop", "bleed", "trim", "art")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfSearchTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+		query, err := request.RequireString("query")
+		if err != nil {
+			return mcp.NewToolResultError("query is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path":       pdfPath,
+			"query":          query,
+			"pages":          request.GetString("pages", ""),
+			"regex":          request.GetBool("regex", false),
+			"case_sensitive": request.GetBool("case_sensitive", false),
+			"context_chars":  request.GetInt("context_chars", 0),
+			"max_hits":       request.GetInt("max_hits", 0),
+			"highlight_dir":  request.GetString("highlight_dir", ""),
+			"format":         request.GetString("format", ""),
+			"dpi":            request.GetFloat("dpi", 0),
+			"page_box":       request.GetString("page_box", ""),
+			"password":       request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_search", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	// Register pdf_extract_images tool
+	pdfExtractImagesTool := mcp.NewTool("pdf_extract_images",
+		mcp.WithDescription("Extract the raster images embedded in PDF pages in their native format, with page, size, color space and placement"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("output_dir", mcp.Description("Directory to write the images to (omit to only list them)")),
+		mcp.WithString("pages", mcp.Description("Pages to extract from, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)")),
+		mcp.WithString("format", mcp.Description("Re-encode every image in this format (default: keep JPEG/JPEG 2000, PNG otherwise)"), mcp.Enum("png", "jpg", "webp", "tiff")),
+		mcp.WithNumber("min_size", mcp.Description("Skip images narrower or shorter than this many pixels (default: 0)")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfExtractImagesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path":   pdfPath,
+			"output_dir": request.GetString("output_dir", ""),
+			"pages":      request.GetString("pages", ""),
+			"format":     request.GetString("format", ""),
+			"min_size":   request.GetInt("min_size", 0),
+			"password":   request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_extract_images", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfMetadataGetTool := mcp.NewTool("pdf_metadata_get",
+		mcp.WithDescription("Read the document metadata of a PDF: title, author, subject, keywords, creator, producer, creation and modification dates, and the raw XMP packet"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfMetadataGetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path": pdfPath,
+			"password": request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_metadata_get", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfMetadataSetTool := mcp.NewTool("pdf_metadata_set",
+		mcp.WithDescription("Change or strip the document metadata of a PDF. Omitted fields are kept, empty strings remove the entry"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("output_path", mcp.Description("Output PDF file (default: overwrite the input)")),
+		mcp.WithString("title", mcp.Description("Document title")),
+		mcp.WithString("author", mcp.Description("Author")),
+		mcp.WithString("subject", mcp.Description("Subject")),
+		mcp.WithString("keywords", mcp.Description("Keywords")),
+		mcp.WithString("creator", mcp.Description("Application that created the original document")),
+		mcp.WithString("producer", mcp.Description("Application that converted it to PDF")),
+		mcp.WithString("creation_date", mcp.Description("Creation date: RFC 3339, YYYY-MM-DD or 'now'")),
+		mcp.WithString("mod_date", mcp.Description("Modification date: RFC 3339, YYYY-MM-DD or 'now'")),
+		mcp.WithString("xmp", mcp.Description("New raw XMP packet (empty string removes it)")),
+		mcp.WithBoolean("strip", mcp.Description("Remove all metadata and XMP instead, rewriting the file without earlier revisions and without adding a Producer or dates")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfMetadataSetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, err := request.RequireString("pdf_path"); err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		// Pass the arguments through as given: an omitted field is kept,
+		// an empty one removes the entry
+		input, err := json.Marshal(request.GetArguments())
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_metadata_set", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfOutlineTool := mcp.NewTool("pdf_outline",
+		mcp.WithDescription("Get the outline (bookmarks) of a PDF as a tree. Every entry has its title, level and the pages of its section (page to end_page), ready to pass as \"page-end_page\" to pdf_to_images or pdf_extract_text"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfOutlineTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path": pdfPath,
+			"password": request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_outline", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfAnnotationsTool := mcp.NewTool("pdf_annotations",
+		mcp.WithDescription("List the annotations of PDF pages without rendering them: reviewer notes and comments, highlights, links (with their URI or destination page), form field widgets... with type, rectangle, contents, author and color"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("pages", mcp.Description("Pages to list, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)")),
+		mcp.WithString("types", mcp.Description("Comma-separated annotation types to return, e.g. 'Text,Highlight,Link' (default: all but Popup)")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfAnnotationsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path": pdfPath,
+			"pages":    request.GetString("pages", ""),
+			"types":    request.GetString("types", ""),
+			"password": request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_annotations", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfFormFieldsTool := mcp.NewTool("pdf_form_fields",
+		mcp.WithDescription("List the fields of a PDF form: name, type (text, date, checkbox, radio, combobox, listbox), current value, allowed options, and the page and rectangle of each widget"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfFormFieldsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path": pdfPath,
+			"password": request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_form_fields", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfFormFillTool := mcp.NewTool("pdf_form_fill",
+		mcp.WithDescription("Fill the fields of a PDF form from an object that maps field names (as listed by pdf_form_fields) to values: text for text, date, radio and combo box fields, true/false for checkboxes, a list of options for list boxes. Optionally flatten the result so it can no longer be edited"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("output_path", mcp.Description("Output PDF file (default: overwrite the input)")),
+		mcp.WithObject("values", mcp.Required(), mcp.Description("Field values by name, e.g. {\"name\": \"Ana López\", \"newsletter\": true}")),
+		mcp.WithBoolean("flatten", mcp.Description("Turn the filled fields into page content (default: false)")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfFormFillTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, err := request.RequireString("pdf_path"); err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+
+		// Pass the values through as given: checkboxes take booleans,
+		// list boxes arrays
+		input, err := json.Marshal(request.GetArguments())
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
 
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_form_fill", input)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(result.Content), nil
+	})
+
+	pdfRenderRegionTool := mcp.NewTool("pdf_render_region",
+		mcp.WithDescription("Render a rectangle of a PDF page, e.g. a figure, table or signature, at a high DPI without rendering the whole page. The rectangle is measured from the top left of the displayed page, in points or as fractions of the page"),
+		mcp.WithString("pdf_path", mcp.Required(), mcp.Description("Path to the PDF file")),
+		mcp.WithString("output_path", mcp.Required(), mcp.Description("Image file to write; its extension selects the format unless format is given")),
+		mcp.WithNumber("page", mcp.Required(), mcp.Description("Page number (1-indexed)")),
+		mcp.WithNumber("x", mcp.Required(), mcp.Description("Left edge of the rectangle")),
+		mcp.WithNumber("y", mcp.Required(), mcp.Description("Top edge of the rectangle, measured downwards")),
+		mcp.WithNumber("width", mcp.Required(), mcp.Description("Width of the rectangle")),
+		mcp.WithNumber("height", mcp.Required(), mcp.Description("Height of the rectangle")),
+		mcp.WithString("units", mcp.Description("Units of x, y, width and height: PDF points (1/72 inch) or fractions 0-1 of the page size (default: points)"), mcp.Enum("points", "fraction")),
+		mcp.WithNumber("dpi", mcp.Description("Rendering DPI (default: 300)")),
+		mcp.WithString("format", mcp.Description("Output format (default: from the output_path extension, png otherwise)"), mcp.Enum("png", "jpg", "webp", "tiff")),
+		mcp.WithString("background", mcp.Description("Background: #rrggbb, #rrggbbaa or transparent (default: white)")),
+		mcp.WithString("render_flags", mcp.Description("Comma-separated rendering options: annotations, forms, print, lcd_text, no_smooth_text, no_smooth_image, no_smooth_path")),
+		mcp.WithString("page_box", mcp.Description("Page boundary the rectangle is measured in: media, crop, bleed, trim or art (default: crop)"), mcp.Enum("media", "crop", "bleed", "trim", "art")),
+		mcp.WithString("password", mcp.Description("Password of an encrypted PDF (user or owner password)")),
+	)
+
+	s.AddTool(pdfRenderRegionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pdfPath, err := request.RequireString("pdf_path")
+		if err != nil {
+			return mcp.NewToolResultError("pdf_path is required"), nil
+		}
+		outputPath, err := request.RequireString("output_path")
+		if err != nil {
+			return mcp.NewToolResultError("output_path is required"), nil
+		}
+		page, err := request.RequireInt("page")
+		if err != nil {
+			return mcp.NewToolResultError("page is required"), nil
+		}
+
+		input, err := json.Marshal(map[string]interface{}{
+			"pdf_path":     pdfPath,
+			"output_path":  outputPath,
+			"page":         page,
+			"x":            request.GetFloat("x", 0),
+			"y":            request.GetFloat("y", 0),
+			"width":        request.GetFloat("width", 0),
+			"height":       request.GetFloat("height", 0),
+			"units":        request.GetString("units", ""),
+			"dpi":          request.GetFloat("dpi", 0),
+			"format":       request.GetString("format", ""),
+			"background":   request.GetString("background", ""),
+			"render_flags": request.GetString("render_flags", ""),
+			"page_box":     request.GetString("page_box", ""),
+			"password":     request.GetString("password", ""),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid arguments: %v", err)), nil
+		}
+
+		result, err := localServer.ExecuteToolContext(ctx, "pdf_render_region", input)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
 		}
@@ -140,3 +662,24 @@ func registerTools(s *server.MCPServer, localServer *localmcp.MCPServer) error {
 
 	return nil
 }
+
+// withProgressNotifications forwards tool progress to the client as
+// notifications/progress when the request carries a progress token
+func withProgressNotifications(ctx context.Context, s *server.MCPServer, request mcp.CallToolRequest) context.Context {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return ctx
+	}
+
+	token := request.Params.Meta.ProgressToken
+	return localmcp.WithProgress(ctx, func(progress, total float64, message string) {
+		err := s.SendNotificationToClient(ctx, "notifications/progress", map[string]interface{}{
+			"progressToken": token,
+			"progress":      progress,
+			"total":         total,
+			"message":       message,
+		})
+		if err != nil {
+			log.Printf("Failed to send progress notification: %v", err)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tu-usuario/pdf2img/pkg/converter"
+)
+
+var (
+	annotsPages    string
+	annotsTypes    []string
+	annotsJSON     bool
+	annotsPassword string
+)
+
+var annotationsCmd = &cobra.Command{
+	Use:   "annotations <pdf-file>",
+	Short: "List the annotations and links of a PDF",
+	Long: "List the annotations of the selected pages: notes, highlights, links, form field\n" +
+		"widgets... with their rectangle, contents, author and color, and the target of links.\n" +
+		"Popup annotations, which only show the contents of another one, are left out unless\n" +
+		"asked for with --type.",
+	Args: cobra.ExactArgs(1),
+	RunE: runAnnotations,
+}
+
+func init() {
+	annotationsCmd.Flags().StringVarP(&annotsPages, "pages", "p", "", "Pages to list, e.g. \"1-3,7,10-\", \"last 5\", \"odd\", \"!1\" (default: all)")
+	annotationsCmd.Flags().StringSliceVarP(&annotsTypes, "type", "t", nil, "Only these types, e.g. \"Text,Highlight\" (default: all but Popup)")
+	annotationsCmd.Flags().BoolVar(&annotsJSON, "json", false, "Print the annotations as JSON")
+	annotationsCmd.Flags().StringVar(&annotsPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	rootCmd.AddCommand(annotationsCmd)
+}
+
+func runAnnotations(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	pdfBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	conv, err := converter.New()
+	if err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	defer conv.Close()
+
+	opts := &converter.AnnotationOptions{
+		Types:    annotsTypes,
+		Password: annotsPassword,
+	}
+	result, err := conv.Annotations(context.Background(), pdfBytes, annotsPages, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read annotations: %w", err)
+	}
+
+	if annotsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	for _, a := range result.Annotations {
+		r := a.Rect
+		fmt.Printf("page %d #%d: %s at [%.1f %.1f %.1f %.1f]", a.Page, a.Index, a.Type, r.X0, r.Y0, r.X1, r.Y1)
+		if a.Author != "" {
+			fmt.Printf(" by %s", a.Author)
+		}
+		if a.Color != "" {
+			fmt.Printf(" %s", a.Color)
+		}
+		if a.InReplyTo > 0 {
+			fmt.Printf(", reply to #%d", a.InReplyTo)
+		}
+		switch {
+		case a.URI != "":
+			fmt.Printf(" → %s", a.URI)
+		case a.DestPage > 0:
+			fmt.Printf(" → page %d", a.DestPage)
+		}
+		fmt.Println()
+		if a.Contents != "" {
+			fmt.Printf("  %s\n", strings.ReplaceAll(a.Contents, "\n", "\n  "))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d annotation(s) found\n", len(result.Annotations))
+	return nil
+}
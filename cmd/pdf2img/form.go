@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tu-usuario/pdf2img/pkg/converter"
+	"github.com/tu-usuario/pdf2img/pkg/forms"
+)
+
+var (
+	formJSON     bool
+	formData     string
+	formSet      []string
+	formFlatten  bool
+	formOutput   string
+	formPassword string
+)
+
+var formCmd = &cobra.Command{
+	Use:   "form",
+	Short: "List and fill the fields of a PDF form",
+}
+
+var formListCmd = &cobra.Command{
+	Use:   "list <pdf-file>",
+	Short: "List the fields of a PDF form",
+	Long: "List the AcroForm fields of a PDF in reading order with their name, type, value,\n" +
+		"options and the page and rectangle of each widget.",
+	Args: cobra.ExactArgs(1),
+	RunE: runFormList,
+}
+
+var formFillCmd = &cobra.Command{
+	Use:   "fill <pdf-file>",
+	Short: "Fill the fields of a PDF form",
+	Long: "Fill form fields from a JSON object that maps field names to values, e.g.\n" +
+		"{\"name\": \"Ana López\", \"newsletter\": true, \"languages\": [\"en\", \"es\"]},\n" +
+		"and/or --set name=value. Checkboxes take true/false, list boxes a list of options.\n" +
+		"The file is rewritten, which drops digital signatures. --flatten then turns the\n" +
+		"fields and other annotations into page content.",
+	Args: cobra.ExactArgs(1),
+	RunE: runFormFill,
+}
+
+func init() {
+	formListCmd.Flags().BoolVar(&formJSON, "json", false, "Print the fields as JSON")
+	formListCmd.Flags().StringVar(&formPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	formFillCmd.Flags().StringVar(&formData, "data", "", "JSON file with the field values (\"-\" = standard input)")
+	formFillCmd.Flags().StringArrayVar(&formSet, "set", nil, "Set a field, e.g. --set name=\"Ana López\" (repeatable)")
+	formFillCmd.Flags().BoolVar(&formFlatten, "flatten", false, "Flatten the filled form so it can no longer be edited")
+	formFillCmd.Flags().StringVarP(&formOutput, "output", "o", "", "Output PDF file (default: overwrite the input)")
+	formFillCmd.Flags().StringVar(&formPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	formCmd.AddCommand(formListCmd, formFillCmd)
+	rootCmd.AddCommand(formCmd)
+}
+
+func runFormList(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	pdfBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+	fields, err := forms.List(pdfBytes, formPassword)
+	if err != nil {
+		return err
+	}
+
+	if formJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fields)
+	}
+
+	for _, f := range fields {
+		fmt.Printf("%s (%s)", f.Name, f.Type)
+		if f.AltName != "" && f.AltName != f.Name {
+			fmt.Printf(" %q", f.AltName)
+		}
+		if len(f.Widgets) > 0 {
+			w := f.Widgets[0]
+			fmt.Printf(" page %d at [%.1f %.1f %.1f %.1f]", w.Page, w.Rect.X0, w.Rect.Y0, w.Rect.X1, w.Rect.Y1)
+		}
+		if f.ReadOnly {
+			fmt.Print(", read-only")
+		}
+		fmt.Println()
+		fmt.Printf("  value: %v\n", formatValue(f.Value))
+		if len(f.Options) > 0 {
+			fmt.Printf("  options: %s\n", strings.Join(f.Options, ", "))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d field(s) found\n", len(fields))
+	return nil
+}
+
+func runFormFill(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	values := map[string]any{}
+	if formData != "" {
+		var data []byte
+		var err error
+		if formData == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(formData)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read form data: %w", err)
+		}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("invalid form data: %w", err)
+		}
+	}
+	for _, s := range formSet {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("invalid --set %q: expected name=value", s)
+		}
+		values[name] = value
+	}
+
+	pdfBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+	out, err := forms.Fill(pdfBytes, formPassword, values)
+	if err != nil {
+		return err
+	}
+
+	if formFlatten {
+		conv, err := converter.New()
+		if err != nil {
+			return fmt.Errorf("initialization failed: %w", err)
+		}
+		defer conv.Close()
+
+		if out, err = conv.FlattenForm(context.Background(), out, formPassword); err != nil {
+			return fmt.Errorf("failed to flatten form: %w", err)
+		}
+	}
+
+	outputPath := formOutput
+	if outputPath == "" {
+		outputPath = args[0]
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	fmt.Printf("✓ %d field(s) filled, written to %s\n", len(values), outputPath)
+	return nil
+}
+
+// formatValue formats a field value for humans
+func formatValue(v any) string {
+	if values, ok := v.([]string); ok {
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tu-usuario/pdf2img/pkg/converter"
@@ -14,18 +19,38 @@ var (
 	outputDir    string
 	format       string
 	dpi          float64
+	width        int
+	height       int
+	maxPixels    int
+	pages        string
 	startPage    int
 	endPage      int
 	prefix       string
+	nameTemplate string
 	verbose      bool
 	retryFailed  bool
+	resume       bool
 	maxPoolSize  int
 	refreshEvery int
+	pageTimeout  time.Duration
+	multiPage    bool
+	quality      int
+	pngLevel     string
+	palette      bool
+	colorMode    string
+	threshold    int
+	renderFlags  converter.RenderFlags
+	background   string
+	crop         string
+	pageBox      string
+	password     string
+	infoPassword string
+	infoJSON     bool
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "pdf2img",
-	Short: "Convert PDF pages to images (PNG or JPG)",
+	Short: "Convert PDF pages to images (PNG, JPG, WebP or TIFF)",
 	Long:  "A command-line tool to render PDF pages as images with configurable DPI and format.",
 	RunE:  runConvert,
 }
@@ -42,15 +67,41 @@ var infoCmd = &cobra.Command{
 func init() {
 	rootCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input PDF file (required)")
 	rootCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory (default: current directory)")
-	rootCmd.Flags().StringVarP(&format, "format", "f", "png", "Output format: png or jpg (default: png)")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "png", "Output format: png, jpg, webp or tiff (default: png)")
 	rootCmd.Flags().Float64VarP(&dpi, "dpi", "d", 150, "DPI for rendering (default: 150)")
+	rootCmd.Flags().IntVar(&width, "width", 0, "Render pages this many pixels wide instead of using --dpi")
+	rootCmd.Flags().IntVar(&height, "height", 0, "Render pages this many pixels tall instead of using --dpi (with --width: fit inside the box)")
+	rootCmd.Flags().IntVar(&maxPixels, "max-pixels", 0, "Scale down pages larger than this many pixels, e.g. 4000000 (default: 0, no limit)")
+	rootCmd.Flags().StringVarP(&pages, "pages", "p", "", "Pages to convert, e.g. \"1-3,7,10-\", \"last 5\", \"odd\", \"!1\" (default: all)")
 	rootCmd.Flags().IntVar(&startPage, "start", 0, "Start page number (1-indexed, 0 for first)")
 	rootCmd.Flags().IntVar(&endPage, "end", 0, "End page number (1-indexed, 0 for last)")
 	rootCmd.Flags().StringVar(&prefix, "prefix", "page_", "Prefix for output files (default: page_)")
+	rootCmd.Flags().StringVar(&nameTemplate, "name-template", "", "Output file name template, e.g. \"{basename}_{page:05}_{dpi}dpi.{ext}\" (default: {prefix}{page}.{ext})")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.Flags().BoolVar(&retryFailed, "retry", false, "Retry failed pages with reduced DPI")
-	rootCmd.Flags().IntVar(&maxPoolSize, "pool-size", 2, "Max PDFium instances in pool (default: 2, increase for large PDFs)")
+	rootCmd.Flags().BoolVar(&resume, "resume", false, "Skip pages a previous run already rendered (from the manifest in the output directory)")
+	rootCmd.Flags().IntVar(&maxPoolSize, "pool-size", 2, "PDFium instances rendering pages in parallel (default: 2, increase for large PDFs)")
 	rootCmd.Flags().IntVar(&refreshEvery, "refresh-every", 50, "Refresh PDFium instance every N pages (default: 50, 0 to disable)")
+	rootCmd.Flags().BoolVar(&multiPage, "multi-page", false, "Write all pages into a single <input name>.tiff file (tiff only)")
+	rootCmd.Flags().IntVar(&quality, "quality", 90, "JPEG quality 1-100 (default: 90)")
+	rootCmd.Flags().StringVar(&pngLevel, "png-compression", "default", "PNG compression: default, none, fast or best")
+	rootCmd.Flags().BoolVar(&palette, "palette", false, "Reduce png/tiff/webp output to an 8-bit palette (256 colors)")
+	rootCmd.Flags().StringVar(&colorMode, "color-mode", "color", "Colors: color, gray (8-bit), bilevel or dither (1-bit black and white)")
+	rootCmd.Flags().IntVar(&threshold, "threshold", 128, "Gray level 1-255 below which bilevel/dither pixels turn black")
+	rootCmd.Flags().BoolVar(&renderFlags.Annotations, "annotations", false, "Draw annotations: notes, highlights, stamps...")
+	rootCmd.Flags().BoolVar(&renderFlags.Forms, "forms", false, "Draw form fields with their values")
+	rootCmd.Flags().BoolVar(&renderFlags.Print, "print", false, "Render as for printing (print-only annotations)")
+	rootCmd.Flags().BoolVar(&renderFlags.LCDText, "lcd-text", false, "Optimize text for LCD screens")
+	rootCmd.Flags().BoolVar(&renderFlags.NoSmoothText, "no-smooth-text", false, "Disable anti-aliasing of text")
+	rootCmd.Flags().BoolVar(&renderFlags.NoSmoothImage, "no-smooth-image", false, "Disable anti-aliasing of images")
+	rootCmd.Flags().BoolVar(&renderFlags.NoSmoothPath, "no-smooth-path", false, "Disable anti-aliasing of paths")
+	rootCmd.Flags().StringVar(&background, "background", "", "Page background: #rrggbb, #rrggbbaa or transparent (default white)")
+	rootCmd.Flags().StringVar(&crop, "crop", "", "Render only x,y,w,h of each page: points from the top left, or fractions of the page if all are 0-1")
+	rootCmd.Flags().StringVar(&pageBox, "page-box", "", "Page box to render: media, crop, bleed, trim or art (default crop)")
+	rootCmd.Flags().DurationVar(&pageTimeout, "page-timeout", 0, "Give up on a page after this long, e.g. 30s (default: 0, no limit)")
+	rootCmd.Flags().StringVar(&password, "password", "", "Password of an encrypted PDF (user or owner password)")
+	infoCmd.Flags().StringVar(&infoPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "Print the information as JSON, with every page")
 
 	rootCmd.MarkFlagRequired("input")
 	rootCmd.AddCommand(infoCmd)
@@ -64,8 +115,30 @@ func main() {
 }
 
 func runConvert(cmd *cobra.Command, args []string) error {
-	// Initialize converter
-	conv, err := converter.New()
+	// Flags are valid at this point, don't bury runtime errors under usage
+	// (main already prints the error)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	mode, err := converter.ParseColorMode(colorMode)
+	if err != nil {
+		return err
+	}
+	bg, err := converter.ParseBackground(background)
+	if err != nil {
+		return err
+	}
+	region, err := converter.ParseRegion(crop)
+	if err != nil {
+		return err
+	}
+	box, err := converter.ParsePageBox(pageBox)
+	if err != nil {
+		return err
+	}
+
+	// Initialize converter with one pool instance per parallel render worker
+	conv, err := converter.NewWithPoolSize(maxPoolSize)
 	if err != nil {
 		return fmt.Errorf("initialization failed: %w", err)
 	}
@@ -77,12 +150,32 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		OutputDir:    outputDir,
 		Format:       format,
 		DPI:          dpi,
+		Width:        width,
+		Height:       height,
+		MaxPixels:    maxPixels,
+		Pages:        pages,
 		StartPage:    startPage,
 		EndPage:      endPage,
 		Prefix:       prefix,
+		NameTemplate: nameTemplate,
 		RetryFailed:  retryFailed,
+		Resume:       resume,
 		MaxPoolSize:  maxPoolSize,
 		RefreshEvery: refreshEvery,
+		PageTimeout:  pageTimeout,
+		MultiPage:    multiPage,
+		Password:     password,
+		ColorMode:    mode,
+		Threshold:    threshold,
+		Flags:        renderFlags,
+		Background:   bg,
+		Crop:         region,
+		PageBox:      box,
+		Encoding: converter.EncodeOptions{
+			Quality:        quality,
+			PNGCompression: pngLevel,
+			Palette:        palette,
+		},
 	}
 
 	if verbose {
@@ -91,17 +184,38 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		log.Printf("Format: %s, DPI: %.0f\n", format, dpi)
 	}
 
-	// Convert
-	result, err := conv.Convert(opts)
-	if err != nil {
+	// Show live progress on the terminal, or per-page logs when verbose
+	bar := newProgressBar(verbose)
+	if bar != nil {
+		opts.Progress = bar.update
+	}
+
+	// Stop between pages on Ctrl+C and still report what was done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Convert, a partial result is returned when interrupted
+	result, err := conv.ConvertContext(ctx, opts)
+	bar.finish()
+	if result == nil {
 		return fmt.Errorf("conversion failed: %w", err)
 	}
 
 	// Print results
-	fmt.Printf("\n✓ Conversion Complete\n")
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		fmt.Printf("\n✗ Conversion Interrupted\n")
+	} else if err != nil {
+		fmt.Printf("\n✗ Conversion Failed\n")
+	} else {
+		fmt.Printf("\n✓ Conversion Complete\n")
+	}
 	fmt.Printf("Total pages: %d\n", result.TotalPages)
 	fmt.Printf("Successful: %d\n", result.Successful)
 	fmt.Printf("Failed: %d\n", result.Failed)
+	if len(result.ResumedPages) > 0 {
+		fmt.Printf("Resumed from previous run: %d\n", len(result.ResumedPages))
+	}
 
 	if verbose && len(result.OutputFiles) > 0 {
 		fmt.Println("\nOutput files:")
@@ -117,6 +231,13 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if len(result.TimedOutPages) > 0 {
+		fmt.Printf("\n⚠ Pages that exceeded the %s page timeout:\n", pageTimeout)
+		for _, pageNum := range result.TimedOutPages {
+			fmt.Printf("  - Page %d\n", pageNum)
+		}
+	}
+
 	if len(result.Errors) > 0 {
 		fmt.Println("\nErrors:")
 		for _, errMsg := range result.Errors {
@@ -128,6 +249,17 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		fmt.Println("\nTip: Some pages failed. Try running with --retry flag to attempt rendering with reduced DPI.")
 	}
 
+	if interrupted || result.Failed > 0 {
+		fmt.Printf("\nTip: Run again with --resume to render only the missing pages (manifest: %s).\n", result.ManifestPath)
+	}
+
+	if interrupted {
+		return fmt.Errorf("interrupted, %d pages not rendered: %w", len(result.CancelledPages), err)
+	}
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -138,23 +270,89 @@ func runInfo(pdfPath string) error {
 	}
 	defer conv.Close()
 
-	info, err := conv.GetPDFInfo(pdfPath)
+	info, err := conv.GetPDFInfoWithPassword(context.Background(), pdfPath, infoPassword)
 	if err != nil {
 		return fmt.Errorf("failed to get PDF info: %w", err)
 	}
 
+	if infoJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
 	fmt.Printf("\nPDF Information\n")
 	fmt.Printf("===============\n")
-	fmt.Printf("File: %s\n", info["file"])
-	fmt.Printf("Pages: %d\n", info["pages"])
-	fmt.Printf("Size: %s\n", info["file_size"])
+	fmt.Printf("File: %s\n", info.File)
+	fmt.Printf("Size: %s (%d bytes)\n", converter.FormatSize(info.Size), info.Size)
+	fmt.Printf("Version: %s\n", info.Version)
+	fmt.Printf("Pages: %d\n", info.PageCount)
+	if info.Encryption.Encrypted {
+		allows := strings.Join(info.Encryption.Permissions, ", ")
+		if allows == "" {
+			allows = "nothing"
+		}
+		fmt.Printf("Encrypted: yes (revision %d, allows: %s)\n", info.Encryption.Revision, allows)
+	} else {
+		fmt.Printf("Encrypted: no\n")
+	}
+	fmt.Printf("Tagged: %s\n", yesNo(info.Tagged))
+	fmt.Printf("Linearized: %s\n", yesNo(info.Linearized))
 
-	if width, ok := info["width"]; ok {
-		fmt.Printf("Width: %.2f pt\n", width)
+	if len(info.Pages) == 0 {
+		return nil
+	}
+	first := info.Pages[0]
+	fmt.Printf("Page 1: %.2f x %.2f pt, rotated %d°\n", first.Width, first.Height, first.Rotation)
+	fmt.Printf("  Media box: %s\n", formatRect(first.MediaBox))
+	fmt.Printf("  Crop box: %s\n", formatRect(first.CropBox))
+	fmt.Printf("  Bleed box: %s\n", formatRect(first.BleedBox))
+	fmt.Printf("  Trim box: %s\n", formatRect(first.TrimBox))
+	fmt.Printf("  Art box: %s\n", formatRect(first.ArtBox))
+	if first.UserUnit != 1 {
+		fmt.Printf("  User unit: %g pt\n", first.UserUnit)
+	}
+	for _, page := range info.Pages[1:] {
+		if page.Width != first.Width || page.Height != first.Height || page.Rotation != first.Rotation {
+			fmt.Printf("Other pages differ in size or rotation, see --json\n")
+			break
+		}
 	}
-	if height, ok := info["height"]; ok {
-		fmt.Printf("Height: %.2f pt\n", height)
+
+	if len(info.Outline) > 0 {
+		fmt.Println("Outline:")
+		printOutline(info.Outline)
 	}
 
 	return nil
 }
+
+// printOutline prints outline entries indented by level, with the pages of
+// their section
+func printOutline(items []converter.OutlineItem) {
+	for _, item := range items {
+		indent := strings.Repeat("  ", item.Level)
+		switch {
+		case item.Page == item.EndPage && item.Page > 0:
+			fmt.Printf("%s%s (p. %d)\n", indent, item.Title, item.Page)
+		case item.Page > 0:
+			fmt.Printf("%s%s (pp. %d-%d)\n", indent, item.Title, item.Page, item.EndPage)
+		case item.URI != "":
+			fmt.Printf("%s%s → %s\n", indent, item.Title, item.URI)
+		default:
+			fmt.Printf("%s%s\n", indent, item.Title)
+		}
+		printOutline(item.Children)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatRect(r converter.Rect) string {
+	return fmt.Sprintf("[%.2f %.2f %.2f %.2f]", r.X0, r.Y0, r.X1, r.Y1)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tu-usuario/pdf2img/pkg/converter"
+	"github.com/tu-usuario/pdf2img/pkg/metadata"
+)
+
+var (
+	metaJSON     bool
+	metaXMP      bool
+	metaOutput   string
+	metaPassword string
+
+	metaTitle        string
+	metaAuthor       string
+	metaSubject      string
+	metaKeywords     string
+	metaCreator      string
+	metaProducer     string
+	metaCreationDate string
+	metaModDate      string
+	metaXMPFile      string
+	metaNoXMP        bool
+)
+
+var metaCmd = &cobra.Command{
+	Use:   "meta",
+	Short: "Read, change or remove the metadata of a PDF",
+	Long: "Read, change or remove the document information (Title, Author, Subject, Keywords,\n" +
+		"Creator, Producer, creation and modification dates) and the XMP packet of a PDF.",
+}
+
+var metaGetCmd = &cobra.Command{
+	Use:   "get <pdf-file>",
+	Short: "Print the metadata of a PDF",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMetaGet,
+}
+
+var metaSetCmd = &cobra.Command{
+	Use:   "set <pdf-file>",
+	Short: "Change the metadata of a PDF",
+	Long: "Change the given metadata entries; an empty value (--author \"\") removes the entry.\n" +
+		"The changes are appended as an incremental update, so the rest of the file is kept\n" +
+		"byte for byte. Dates are RFC 3339 (2024-01-31T12:00:00+01:00), 2024-01-31 or \"now\".",
+	Args: cobra.ExactArgs(1),
+	RunE: runMetaSet,
+}
+
+var metaStripCmd = &cobra.Command{
+	Use:   "strip <pdf-file>",
+	Short: "Remove the metadata of a PDF",
+	Long: "Remove the document information and the XMP packet. The PDF is rewritten so that\n" +
+		"earlier revisions of the metadata go too. No Producer or dates are added.",
+	Args: cobra.ExactArgs(1),
+	RunE: runMetaStrip,
+}
+
+func init() {
+	metaGetCmd.Flags().BoolVar(&metaJSON, "json", false, "Print the metadata as JSON, with the XMP packet")
+	metaGetCmd.Flags().BoolVar(&metaXMP, "xmp", false, "Print only the raw XMP packet")
+	metaGetCmd.Flags().StringVar(&metaPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	metaSetCmd.Flags().StringVar(&metaTitle, "title", "", "Document title")
+	metaSetCmd.Flags().StringVar(&metaAuthor, "author", "", "Author")
+	metaSetCmd.Flags().StringVar(&metaSubject, "subject", "", "Subject")
+	metaSetCmd.Flags().StringVar(&metaKeywords, "keywords", "", "Keywords")
+	metaSetCmd.Flags().StringVar(&metaCreator, "creator", "", "Application that created the original document")
+	metaSetCmd.Flags().StringVar(&metaProducer, "producer", "", "Application that converted it to PDF")
+	metaSetCmd.Flags().StringVar(&metaCreationDate, "creation-date", "", "Creation date")
+	metaSetCmd.Flags().StringVar(&metaModDate, "mod-date", "", "Modification date")
+	metaSetCmd.Flags().StringVar(&metaXMPFile, "xmp-file", "", "Replace the XMP packet with the contents of this file")
+	metaSetCmd.Flags().BoolVar(&metaNoXMP, "no-xmp", false, "Remove the XMP packet")
+	metaSetCmd.Flags().StringVarP(&metaOutput, "output", "o", "", "Output PDF file (default: overwrite the input)")
+	metaSetCmd.Flags().StringVar(&metaPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+	metaSetCmd.MarkFlagsMutuallyExclusive("xmp-file", "no-xmp")
+
+	metaStripCmd.Flags().StringVarP(&metaOutput, "output", "o", "", "Output PDF file (default: overwrite the input)")
+	metaStripCmd.Flags().StringVar(&metaPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	metaCmd.AddCommand(metaGetCmd, metaSetCmd, metaStripCmd)
+	rootCmd.AddCommand(metaCmd)
+}
+
+func runMetaGet(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	pdfBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	conv, err := converter.New()
+	if err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	defer conv.Close()
+
+	meta, err := conv.Metadata(context.Background(), pdfBytes, metaPassword)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	switch {
+	case metaXMP:
+		fmt.Print(meta.XMP)
+		return nil
+	case metaJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(meta)
+	}
+
+	fields := []struct{ name, value string }{
+		{"Title", meta.Title},
+		{"Author", meta.Author},
+		{"Subject", meta.Subject},
+		{"Keywords", meta.Keywords},
+		{"Creator", meta.Creator},
+		{"Producer", meta.Producer},
+		{"Created", formatDate(meta.CreationDate)},
+		{"Modified", formatDate(meta.ModDate)},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Printf("%s: %s\n", f.name, f.value)
+		}
+	}
+	if meta.XMP != "" {
+		fmt.Printf("XMP: %d bytes (see --xmp)\n", len(meta.XMP))
+	}
+	return nil
+}
+
+func runMetaSet(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	changes := &metadata.Changes{}
+	text := []struct {
+		flag  string
+		value string
+		field **string
+	}{
+		{"title", metaTitle, &changes.Title},
+		{"author", metaAuthor, &changes.Author},
+		{"subject", metaSubject, &changes.Subject},
+		{"keywords", metaKeywords, &changes.Keywords},
+		{"creator", metaCreator, &changes.Creator},
+		{"producer", metaProducer, &changes.Producer},
+	}
+	for _, t := range text {
+		if cmd.Flags().Changed(t.flag) {
+			*t.field = &t.value
+		}
+	}
+
+	var err error
+	if cmd.Flags().Changed("creation-date") {
+		if changes.CreationDate, err = metadata.ParseDate(metaCreationDate); err != nil {
+			return fmt.Errorf("invalid --creation-date: %w", err)
+		}
+	}
+	if cmd.Flags().Changed("mod-date") {
+		if changes.ModDate, err = metadata.ParseDate(metaModDate); err != nil {
+			return fmt.Errorf("invalid --mod-date: %w", err)
+		}
+	}
+	switch {
+	case metaXMPFile != "":
+		packet, err := os.ReadFile(metaXMPFile)
+		if err != nil {
+			return fmt.Errorf("failed to read XMP file: %w", err)
+		}
+		xmp := string(packet)
+		changes.XMP = &xmp
+	case metaNoXMP:
+		changes.XMP = new(string)
+	}
+
+	return rewritePDF(args[0], func(pdf []byte) ([]byte, error) {
+		return metadata.Set(pdf, metaPassword, changes)
+	})
+}
+
+func runMetaStrip(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	return rewritePDF(args[0], func(pdf []byte) ([]byte, error) {
+		return metadata.Strip(pdf, metaPassword)
+	})
+}
+
+// rewritePDF applies fn to a PDF and writes the result to --output or back
+// to the input file
+func rewritePDF(path string, fn func(pdf []byte) ([]byte, error)) error {
+	pdfBytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+	out, err := fn(pdfBytes)
+	if err != nil {
+		return err
+	}
+
+	outputPath := metaOutput
+	if outputPath == "" {
+		outputPath = path
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	fmt.Printf("✓ Metadata written to %s\n", outputPath)
+	return nil
+}
+
+// formatDate formats an optional date for humans
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tu-usuario/pdf2img/pkg/converter"
+)
+
+const progressBarWidth = 30
+
+// progressBar renders converter progress events as a single live line on
+// a terminal, or as log lines in verbose mode
+type progressBar struct {
+	out     io.Writer
+	verbose bool
+	drawn   bool
+}
+
+// newProgressBar returns nil when there is nothing to show: stderr is not a
+// terminal and verbose logging is off
+func newProgressBar(verbose bool) *progressBar {
+	if !verbose && !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{
+		out:     os.Stderr,
+		verbose: verbose,
+	}
+}
+
+// update handles a single progress event
+func (b *progressBar) update(ev converter.ProgressEvent) {
+	if b.verbose {
+		switch ev.Type {
+		case converter.PageDone:
+			log.Printf("[%d/%d] Page %d: %s (%s, %s)\n", ev.Completed, ev.Total, ev.Page, ev.Path, formatBytes(ev.Size), ev.Duration.Round(time.Millisecond))
+		case converter.PageFailed:
+			log.Printf("[%d/%d] Page %d failed: %s\n", ev.Completed, ev.Total, ev.Page, ev.Error)
+		case converter.PageRetry:
+			log.Printf("Retrying page %d with reduced DPI\n", ev.Page)
+		case converter.InstanceRefreshed:
+			log.Printf("PDFium instance refreshed\n")
+		}
+		return
+	}
+
+	if ev.Type != converter.PageDone && ev.Type != converter.PageFailed {
+		return
+	}
+
+	filled := 0
+	if ev.Total > 0 {
+		filled = ev.Completed * progressBarWidth / ev.Total
+	}
+	fmt.Fprintf(b.out, "\r[%s%s] %d/%d pages", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), ev.Completed, ev.Total)
+	b.drawn = true
+}
+
+// finish moves past the live line so the summary starts on a fresh line
+func (b *progressBar) finish() {
+	if b != nil && b.drawn {
+		fmt.Fprintln(b.out)
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// formatBytes formats a byte count for humans
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tu-usuario/pdf2img/pkg/converter"
+)
+
+var (
+	searchPages         string
+	searchRegex         bool
+	searchCaseSensitive bool
+	searchContext       int
+	searchMaxHits       int
+	searchJSON          bool
+	searchHighlightDir  string
+	searchFormat        string
+	searchDPI           float64
+	searchPageBox       string
+	searchCrop          string
+	searchPassword      string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <pdf-file> <query>",
+	Short: "Find text in a PDF",
+	Long: "Find a string (case-insensitive by default) or a regular expression in the selected\n" +
+		"pages and print every hit with its page number and surrounding text. With --highlight\n" +
+		"the pages with hits are rendered into a directory with the hits highlighted.",
+	Args: cobra.ExactArgs(2),
+	RunE: runSearch,
+}
+
+func init() {
+	searchCmd.Flags().StringVarP(&searchPages, "pages", "p", "", "Pages to search, e.g. \"1-3,7,10-\", \"last 5\", \"odd\", \"!1\" (default: all)")
+	searchCmd.Flags().BoolVarP(&searchRegex, "regex", "e", false, "Treat the query as a regular expression (RE2 syntax)")
+	searchCmd.Flags().BoolVar(&searchCaseSensitive, "case-sensitive", false, "Match case")
+	searchCmd.Flags().IntVar(&searchContext, "context", 40, "Characters of context on each side of a hit (default: 40)")
+	searchCmd.Flags().IntVar(&searchMaxHits, "max-hits", 0, "Stop after N hits (default: 0, no limit)")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Print the hits as JSON, with their rectangles in points and pixels")
+	searchCmd.Flags().StringVar(&searchHighlightDir, "highlight", "", "Render the pages with hits into this directory with the hits highlighted")
+	searchCmd.Flags().StringVarP(&searchFormat, "format", "f", "png", "Format of the highlighted pages: png, jpg, webp or tiff (default: png)")
+	searchCmd.Flags().Float64VarP(&searchDPI, "dpi", "d", 150, "DPI of the highlighted pages and pixel rectangles (default: 150)")
+	searchCmd.Flags().StringVar(&searchPageBox, "page-box", "", "Page box of the highlighted pages and pixel rectangles: media, crop, bleed, trim or art (default crop)")
+	searchCmd.Flags().StringVar(&searchCrop, "crop", "", "Highlighted pages and pixel rectangles show only x,y,w,h of each page, as in convert --crop")
+	searchCmd.Flags().StringVar(&searchPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	rootCmd.AddCommand(searchCmd)
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	box, err := converter.ParsePageBox(searchPageBox)
+	if err != nil {
+		return err
+	}
+	region, err := converter.ParseRegion(searchCrop)
+	if err != nil {
+		return err
+	}
+
+	pdfBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	conv, err := converter.New()
+	if err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	defer conv.Close()
+
+	result, err := conv.Search(context.Background(), pdfBytes, searchPages, args[1], &converter.SearchOptions{
+		Regex:         searchRegex,
+		CaseSensitive: searchCaseSensitive,
+		ContextChars:  searchContext,
+		MaxHits:       searchMaxHits,
+		DPI:           searchDPI,
+		PageBox:       box,
+		Crop:          region,
+		Password:      searchPassword,
+		HighlightDir:  searchHighlightDir,
+		Format:        searchFormat,
+	})
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if searchJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	for _, page := range result.Pages {
+		for _, hit := range page.Hits {
+			fmt.Printf("page %d: %s\n", page.Page, hit.Snippet)
+		}
+		if page.Image != "" {
+			fmt.Printf("  → %s\n", page.Image)
+		}
+	}
+
+	summary := fmt.Sprintf("%d hit(s) on %d page(s)", result.Hits, len(result.Pages))
+	if result.Truncated {
+		summary += fmt.Sprintf(", stopped at --max-hits %d", searchMaxHits)
+	}
+	fmt.Fprintln(os.Stderr, summary)
+	return nil
+}
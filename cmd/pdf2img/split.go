@@ -11,24 +11,28 @@ import (
 var (
 	splitInputFile  string
 	splitOutputFile string
+	splitPages      string
 	splitStartPage  int
 	splitEndPage    int
 	splitVerbose    bool
+	splitPassword   string
 )
 
 var splitCmd = &cobra.Command{
 	Use:   "split",
 	Short: "Extract pages from a PDF into a new PDF file",
-	Long:  "Extract a range of pages from a PDF document and save them to a new PDF file.",
+	Long:  "Extract selected pages from a PDF document and save them to a new PDF file.",
 	RunE:  runSplit,
 }
 
 func init() {
 	splitCmd.Flags().StringVarP(&splitInputFile, "input", "i", "", "Input PDF file (required)")
 	splitCmd.Flags().StringVarP(&splitOutputFile, "output", "o", "", "Output PDF file (required)")
+	splitCmd.Flags().StringVarP(&splitPages, "pages", "p", "", "Pages to extract, e.g. \"1-3,7,10-\", \"last 5\", \"odd\", \"!1\" (default: all)")
 	splitCmd.Flags().IntVar(&splitStartPage, "start", 0, "Start page number (1-indexed, 0 for first)")
 	splitCmd.Flags().IntVar(&splitEndPage, "end", 0, "End page number (1-indexed, 0 for last)")
 	splitCmd.Flags().BoolVarP(&splitVerbose, "verbose", "v", false, "Verbose output")
+	splitCmd.Flags().StringVar(&splitPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
 
 	splitCmd.MarkFlagRequired("input")
 	splitCmd.MarkFlagRequired("output")
@@ -42,8 +46,10 @@ func runSplit(cmd *cobra.Command, args []string) error {
 	opts := &splitter.SplitOptions{
 		InputPath:  splitInputFile,
 		OutputPath: splitOutputFile,
+		Pages:      splitPages,
 		StartPage:  splitStartPage,
 		EndPage:    splitEndPage,
+		Password:   splitPassword,
 	}
 
 	if splitVerbose {
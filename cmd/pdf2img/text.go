@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tu-usuario/pdf2img/pkg/converter"
+)
+
+var (
+	textOutputFile string
+	textPages      string
+	textMaxChars   int
+	textJSON       bool
+	textPassword   string
+)
+
+var textCmd = &cobra.Command{
+	Use:   "text <pdf-file>",
+	Short: "Extract the text of PDF pages",
+	Long: "Extract the plain text of the selected pages. Pages are separated by a form feed\n" +
+		"character (like pdftotext); use --json for per-page output.",
+	Args: cobra.ExactArgs(1),
+	RunE: runText,
+}
+
+func init() {
+	textCmd.Flags().StringVarP(&textOutputFile, "output", "o", "", "Write the text to this file (default: standard output)")
+	textCmd.Flags().StringVarP(&textPages, "pages", "p", "", "Pages to extract, e.g. \"1-3,7,10-\", \"last 5\", \"odd\", \"!1\" (default: all)")
+	textCmd.Flags().IntVar(&textMaxChars, "max-chars", 0, "Truncate the text of each page to N characters (default: 0, no limit)")
+	textCmd.Flags().BoolVar(&textJSON, "json", false, "Print a JSON array with the text of every page")
+	textCmd.Flags().StringVar(&textPassword, "password", "", "Password of an encrypted PDF (user or owner password)")
+
+	rootCmd.AddCommand(textCmd)
+}
+
+func runText(cmd *cobra.Command, args []string) error {
+	// main prints the error
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	pdfBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	conv, err := converter.New()
+	if err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	defer conv.Close()
+
+	result, err := conv.ExtractText(context.Background(), pdfBytes, textPages, &converter.TextOptions{
+		MaxChars: textMaxChars,
+		Password: textPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("text extraction failed: %w", err)
+	}
+
+	if textOutputFile == "" {
+		return writeText(os.Stdout, result.Pages)
+	}
+
+	file, err := os.Create(textOutputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+	if err := writeText(file, result.Pages); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// writeText prints the pages as JSON with --json, or as plain text with a
+// form feed between pages
+func writeText(out io.Writer, pages []converter.PageText) error {
+	if textJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pages)
+	}
+
+	for i, page := range pages {
+		if i > 0 {
+			if _, err := fmt.Fprint(out, "\f"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(out, page.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
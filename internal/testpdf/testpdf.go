@@ -0,0 +1,30 @@
+// Package testpdf writes small PDFs for tests from their objects
+package testpdf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Build writes a PDF of objects, numbered from 1, with a cross-reference
+// table. Object 1 is the catalog; trailer adds entries to the trailer
+// dictionary, e.g. "/Info 4 0 R".
+func Build(objects []string, trailer string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	if trailer != "" {
+		trailer = " " + trailer
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,20 @@
+package mcp
+
+import "context"
+
+// ProgressFunc receives progress updates for a running tool call
+type ProgressFunc func(progress, total float64, message string)
+
+type progressKey struct{}
+
+// WithProgress returns a context that makes long-running tools report
+// their progress to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFromContext returns the progress function set by WithProgress, or nil
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
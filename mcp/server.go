@@ -1,11 +1,20 @@
 package mcp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/tu-usuario/pdf2img/pkg/converter"
+	"github.com/tu-usuario/pdf2img/pkg/forms"
+	"github.com/tu-usuario/pdf2img/pkg/metadata"
+	"github.com/tu-usuario/pdf2img/pkg/password"
 	"github.com/tu-usuario/pdf2img/pkg/splitter"
 )
 
@@ -44,7 +53,7 @@ func (s *MCPServer) GetTools() []Tool {
 	return []Tool{
 		{
 			Name:        "pdf_to_images",
-			Description: "Convert PDF pages to PNG or JPG images",
+			Description: "Convert PDF pages to PNG, JPG, WebP or TIFF images",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -58,13 +67,29 @@ func (s *MCPServer) GetTools() []Tool {
 					},
 					"format": map[string]interface{}{
 						"type":        "string",
-						"description": "Output format: 'png' or 'jpg' (default: png)",
-						"enum":        []string{"png", "jpg"},
+						"description": "Output format: 'png', 'jpg', 'webp' or 'tiff' (default: png)",
+						"enum":        []string{"png", "jpg", "webp", "tiff"},
 					},
 					"dpi": map[string]interface{}{
 						"type":        "number",
 						"description": "DPI for rendering (default: 150)",
 					},
+					"width": map[string]interface{}{
+						"type":        "integer",
+						"description": "Render pages this many pixels wide instead of using dpi",
+					},
+					"height": map[string]interface{}{
+						"type":        "integer",
+						"description": "Render pages this many pixels tall instead of using dpi (with width: fit inside the box)",
+					},
+					"max_pixels": map[string]interface{}{
+						"type":        "integer",
+						"description": "Scale down pages larger than this many pixels (default: 0, no limit)",
+					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all; replaces start_page/end_page)",
+					},
 					"start_page": map[string]interface{}{
 						"type":        "integer",
 						"description": "Start page number (1-indexed, 0 for first page)",
@@ -77,13 +102,68 @@ func (s *MCPServer) GetTools() []Tool {
 						"type":        "string",
 						"description": "Prefix for output filenames (default: page_)",
 					},
+					"name_template": map[string]interface{}{
+						"type":        "string",
+						"description": "Output file name template, e.g. '{basename}_{page:05}_{dpi}dpi.{ext}' (placeholders: basename, prefix, page, label, total, dpi, width, height, hash, ext; default: {prefix}{page}.{ext})",
+					},
+					"resume": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Skip pages a previous call already rendered into output_dir, as recorded in its manifest (default: false)",
+					},
+					"page_timeout": map[string]interface{}{
+						"type":        "number",
+						"description": "Give up on a page after this many seconds (default: 0, no limit)",
+					},
+					"quality": map[string]interface{}{
+						"type":        "integer",
+						"description": "JPEG quality 1-100 (default: 90)",
+					},
+					"png_compression": map[string]interface{}{
+						"type":        "string",
+						"description": "PNG compression level (default: default)",
+						"enum":        []string{"default", "none", "fast", "best"},
+					},
+					"palette": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Reduce png/tiff/webp output to an 8-bit palette of 256 colors (default: false)",
+					},
+					"color_mode": map[string]interface{}{
+						"type":        "string",
+						"description": "Colors of the output: full color, 8-bit gray, or 1-bit black and white split at threshold (bilevel) or dithered (default: color)",
+						"enum":        []string{"color", "gray", "bilevel", "dither"},
+					},
+					"threshold": map[string]interface{}{
+						"type":        "integer",
+						"description": "Gray level 1-255 below which bilevel and dither pixels turn black (default: 128)",
+					},
+					"render_flags": map[string]interface{}{
+						"type":        "string",
+						"description": "Comma-separated rendering options: annotations, forms (form field values), print, lcd_text, no_smooth_text, no_smooth_image, no_smooth_path (default: page content only)",
+					},
+					"background": map[string]interface{}{
+						"type":        "string",
+						"description": "Page background: #rrggbb, #rrggbbaa or transparent for png/webp/tiff; jpg gets an opaque color (default: white)",
+					},
+					"page_box": map[string]interface{}{
+						"type":        "string",
+						"description": "Page boundary to render: media (whole sheet), crop (what viewers show), bleed, trim (finished size) or art (default: crop)",
+						"enum":        []string{"media", "crop", "bleed", "trim", "art"},
+					},
+					"multi_page": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Write all pages into a single file named after the PDF (tiff only, default: false)",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
 				},
 				"required": []string{"pdf_path", "output_dir"},
 			},
 		},
 		{
 			Name:        "pdf_info",
-			Description: "Get information about a PDF file",
+			Description: "Get information about a PDF file: size, version, page count, encryption and permissions, tagged/linearized flags, the outline (bookmarks) and every page's size, media, crop, bleed, trim and art boxes, rotation and user unit",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -91,6 +171,10 @@ func (s *MCPServer) GetTools() []Tool {
 						"type":        "string",
 						"description": "Path to the PDF file",
 					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
 				},
 				"required": []string{"pdf_path"},
 			},
@@ -109,13 +193,17 @@ func (s *MCPServer) GetTools() []Tool {
 						"type":        "string",
 						"description": "Path for the compressed PDF output",
 					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
 				},
 				"required": []string{"pdf_path", "output_path"},
 			},
 		},
 		{
 			Name:        "pdf_split",
-			Description: "Extract a range of pages from a PDF into a new PDF file",
+			Description: "Extract selected pages from a PDF into a new PDF file",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -127,6 +215,10 @@ func (s *MCPServer) GetTools() []Tool {
 						"type":        "string",
 						"description": "Path for the output PDF file",
 					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all; replaces start_page/end_page)",
+					},
 					"start_page": map[string]interface{}{
 						"type":        "integer",
 						"description": "Start page number (1-indexed, 0 for first page)",
@@ -135,80 +227,977 @@ func (s *MCPServer) GetTools() []Tool {
 						"type":        "integer",
 						"description": "End page number (1-indexed, 0 for last page)",
 					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
 				},
 				"required": []string{"pdf_path", "output_path"},
 			},
 		},
+		{
+			Name:        "pdf_extract_text",
+			Description: "Extract the plain text of PDF pages",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)",
+					},
+					"max_chars_per_page": map[string]interface{}{
+						"type":        "integer",
+						"description": "Truncate the text of each page to this many characters (default: 0, no limit)",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_text_layout",
+			Description: "Extract PDF text with bounding boxes (blocks, lines, words, chars) in points and in pixels of the rendered pages",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to use, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)",
+					},
+					"level": map[string]interface{}{
+						"type":        "string",
+						"description": "Finest level to include (default: words)",
+						"enum":        []string{"blocks", "lines", "words", "chars"},
+					},
+					"dpi": map[string]interface{}{
+						"type":        "number",
+						"description": "DPI of the images pixel coordinates refer to, as passed to pdf_to_images (default: 150)",
+					},
+					"width": map[string]interface{}{
+						"type":        "integer",
+						"description": "Pixel coordinates for images rendered this many pixels wide",
+					},
+					"height": map[string]interface{}{
+						"type":        "integer",
+						"description": "Pixel coordinates for images rendered this many pixels tall",
+					},
+					"max_pixels": map[string]interface{}{
+						"type":        "integer",
+						"description": "Pixel coordinates for images scaled down to this many pixels",
+					},
+					"page_box": map[string]interface{}{
+						"type":        "string",
+						"description": "Page boundary of the images pixel coordinates refer to, as passed to pdf_to_images (default: crop)",
+						"enum":        []string{"media", "crop", "bleed", "trim", "art"},
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_search",
+			Description: "Find a string or regex in a PDF: page numbers, snippets and hit rectangles, optionally rendering the pages with the hits highlighted",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Text to find (case-insensitive unless case_sensitive), or a regular expression with regex",
+					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to search, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)",
+					},
+					"regex": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat query as a regular expression (RE2 syntax)",
+					},
+					"case_sensitive": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Match case (default: false)",
+					},
+					"context_chars": map[string]interface{}{
+						"type":        "integer",
+						"description": "Characters of context on each side of a hit (default: 40)",
+					},
+					"max_hits": map[string]interface{}{
+						"type":        "integer",
+						"description": "Stop after this many hits (default: 0, no limit)",
+					},
+					"highlight_dir": map[string]interface{}{
+						"type":        "string",
+						"description": "Render the pages with hits into this directory with the hits highlighted",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"description": "Format of the highlighted pages (default: png)",
+						"enum":        []string{"png", "jpg", "webp", "tiff"},
+					},
+					"dpi": map[string]interface{}{
+						"type":        "number",
+						"description": "DPI of the highlighted pages and pixel rectangles (default: 150)",
+					},
+					"page_box": map[string]interface{}{
+						"type":        "string",
+						"description": "Page boundary of the highlighted pages and pixel rectangles: media, crop, bleed, trim or art (default: crop)",
+						"enum":        []string{"media", "crop", "bleed", "trim", "art"},
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path", "query"},
+			},
+		},
+		{
+			Name:        "pdf_extract_images",
+			Description: "Extract the raster images embedded in PDF pages in their native format, with page, size, color space and placement",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"output_dir": map[string]interface{}{
+						"type":        "string",
+						"description": "Directory to write the images to (omit to only list them)",
+					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to extract from, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"description": "Re-encode every image in this format (default: keep JPEG/JPEG 2000, PNG otherwise)",
+						"enum":        []string{"png", "jpg", "webp", "tiff"},
+					},
+					"min_size": map[string]interface{}{
+						"type":        "integer",
+						"description": "Skip images narrower or shorter than this many pixels (default: 0)",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_metadata_get",
+			Description: "Read the document metadata of a PDF: title, author, subject, keywords, creator, producer, creation and modification dates, and the raw XMP packet",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_metadata_set",
+			Description: "Change or strip the document metadata of a PDF. Omitted fields are kept, empty strings remove the entry",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"output_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Output PDF file (default: overwrite the input)",
+					},
+					"title": map[string]interface{}{
+						"type":        "string",
+						"description": "Document title",
+					},
+					"author": map[string]interface{}{
+						"type":        "string",
+						"description": "Author",
+					},
+					"subject": map[string]interface{}{
+						"type":        "string",
+						"description": "Subject",
+					},
+					"keywords": map[string]interface{}{
+						"type":        "string",
+						"description": "Keywords",
+					},
+					"creator": map[string]interface{}{
+						"type":        "string",
+						"description": "Application that created the original document",
+					},
+					"producer": map[string]interface{}{
+						"type":        "string",
+						"description": "Application that converted it to PDF",
+					},
+					"creation_date": map[string]interface{}{
+						"type":        "string",
+						"description": "Creation date: RFC 3339, YYYY-MM-DD or 'now'",
+					},
+					"mod_date": map[string]interface{}{
+						"type":        "string",
+						"description": "Modification date: RFC 3339, YYYY-MM-DD or 'now'",
+					},
+					"xmp": map[string]interface{}{
+						"type":        "string",
+						"description": "New raw XMP packet (empty string removes it)",
+					},
+					"strip": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Remove all metadata and XMP instead, rewriting the file without earlier revisions and without adding a Producer or dates",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_outline",
+			Description: "Get the outline (bookmarks) of a PDF as a tree. Every entry has its title, level and the pages of its section (page to end_page), ready to pass as \"page-end_page\" to pdf_to_images or pdf_extract_text",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_annotations",
+			Description: "List the annotations of PDF pages without rendering them: reviewer notes and comments, highlights, links (with their URI or destination page), form field widgets... with type, rectangle, contents, author and color",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"pages": map[string]interface{}{
+						"type":        "string",
+						"description": "Pages to list, e.g. '1-3,7,10-', 'last 5', 'odd', '!1' (default: all)",
+					},
+					"types": map[string]interface{}{
+						"type":        "string",
+						"description": "Comma-separated annotation types to return, e.g. 'Text,Highlight,Link' (default: all but Popup)",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_form_fields",
+			Description: "List the fields of a PDF form: name, type (text, date, checkbox, radio, combobox, listbox), current value, allowed options, and the page and rectangle of each widget",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path"},
+			},
+		},
+		{
+			Name:        "pdf_form_fill",
+			Description: "Fill the fields of a PDF form from an object that maps field names (as listed by pdf_form_fields) to values: text for text, date, radio and combo box fields, true/false for checkboxes, a list of options for list boxes. Optionally flatten the result so it can no longer be edited",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"output_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Output PDF file (default: overwrite the input)",
+					},
+					"values": map[string]interface{}{
+						"type":        "object",
+						"description": "Field values by name, e.g. {\"name\": \"Ana López\", \"newsletter\": true}",
+					},
+					"flatten": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Turn the filled fields into page content (default: false)",
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path", "values"},
+			},
+		},
+		{
+			Name:        "pdf_render_region",
+			Description: "Render a rectangle of a PDF page, e.g. a figure, table or signature, at a high DPI without rendering the whole page. The rectangle is measured from the top left of the displayed page, in points or as fractions of the page",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pdf_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to the PDF file",
+					},
+					"output_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Image file to write; its extension selects the format unless format is given",
+					},
+					"page": map[string]interface{}{
+						"type":        "integer",
+						"description": "Page number (1-indexed)",
+					},
+					"x": map[string]interface{}{
+						"type":        "number",
+						"description": "Left edge of the rectangle",
+					},
+					"y": map[string]interface{}{
+						"type":        "number",
+						"description": "Top edge of the rectangle, measured downwards",
+					},
+					"width": map[string]interface{}{
+						"type":        "number",
+						"description": "Width of the rectangle",
+					},
+					"height": map[string]interface{}{
+						"type":        "number",
+						"description": "Height of the rectangle",
+					},
+					"units": map[string]interface{}{
+						"type":        "string",
+						"description": "Units of x, y, width and height: PDF points (1/72 inch) or fractions 0-1 of the page size (default: points)",
+						"enum":        []string{"points", "fraction"},
+					},
+					"dpi": map[string]interface{}{
+						"type":        "number",
+						"description": "Rendering DPI (default: 300)",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"description": "Output format (default: from the output_path extension, png otherwise)",
+						"enum":        []string{"png", "jpg", "webp", "tiff"},
+					},
+					"background": map[string]interface{}{
+						"type":        "string",
+						"description": "Background: #rrggbb, #rrggbbaa or transparent (default: white)",
+					},
+					"render_flags": map[string]interface{}{
+						"type":        "string",
+						"description": "Comma-separated rendering options: annotations, forms, print, lcd_text, no_smooth_text, no_smooth_image, no_smooth_path",
+					},
+					"page_box": map[string]interface{}{
+						"type":        "string",
+						"description": "Page boundary the rectangle is measured in: media, crop, bleed, trim or art (default: crop)",
+						"enum":        []string{"media", "crop", "bleed", "trim", "art"},
+					},
+					"password": map[string]interface{}{
+						"type":        "string",
+						"description": "Password of an encrypted PDF (user or owner password)",
+					},
+				},
+				"required": []string{"pdf_path", "output_path", "page", "x", "y", "width", "height"},
+			},
+		},
+	}
+}
+
+// ExecuteTool executes a tool with the given input
+func (s *MCPServer) ExecuteTool(toolName string, input json.RawMessage) (ToolResult, error) {
+	return s.ExecuteToolContext(context.Background(), toolName, input)
+}
+
+// ExecuteToolContext executes a tool and abandons long-running work when ctx is done
+func (s *MCPServer) ExecuteToolContext(ctx context.Context, toolName string, input json.RawMessage) (ToolResult, error) {
+	switch toolName {
+	case "pdf_to_images":
+		return s.handlePDFToImages(ctx, input)
+	case "pdf_info":
+		return s.handlePDFInfo(ctx, input)
+	case "pdf_compress":
+		return s.handlePDFCompress(input)
+	case "pdf_split":
+		return s.handlePDFSplit(input)
+	case "pdf_extract_text":
+		return s.handlePDFExtractText(ctx, input)
+	case "pdf_text_layout":
+		return s.handlePDFTextLayout(ctx, input)
+	case "pdf_search":
+		return s.handlePDFSearch(ctx, input)
+	case "pdf_extract_images":
+		return s.handlePDFExtractImages(ctx, input)
+	case "pdf_metadata_get":
+		return s.handlePDFMetadataGet(ctx, input)
+	case "pdf_metadata_set":
+		return s.handlePDFMetadataSet(ctx, input)
+	case "pdf_outline":
+		return s.handlePDFOutline(ctx, input)
+	case "pdf_annotations":
+		return s.handlePDFAnnotations(ctx, input)
+	case "pdf_form_fields":
+		return s.handlePDFFormFields(ctx, input)
+	case "pdf_form_fill":
+		return s.handlePDFFormFill(ctx, input)
+	case "pdf_render_region":
+		return s.handlePDFRenderRegion(ctx, input)
+	default:
+		return ToolResult{}, fmt.Errorf("unknown tool: %s", toolName)
+	}
+}
+
+// Private tool handlers
+
+func (s *MCPServer) handlePDFToImages(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath     string  `json:"pdf_path"`
+		OutputDir   string  `json:"output_dir"`
+		Format      string  `json:"format"`
+		DPI         float64 `json:"dpi"`
+		Width       int     `json:"width"`
+		Height      int     `json:"height"`
+		MaxPixels   int     `json:"max_pixels"`
+		Pages       string  `json:"pages"`
+		StartPage   int     `json:"start_page"`
+		EndPage     int     `json:"end_page"`
+		Prefix      string  `json:"prefix"`
+		NameTmpl    string  `json:"name_template"`
+		PageTimeout float64 `json:"page_timeout"`
+		MultiPage   bool    `json:"multi_page"`
+		Resume      bool    `json:"resume"`
+		Quality     int     `json:"quality"`
+		PNGLevel    string  `json:"png_compression"`
+		Palette     bool    `json:"palette"`
+		ColorMode   string  `json:"color_mode"`
+		Threshold   int     `json:"threshold"`
+		RenderFlags string  `json:"render_flags"`
+		Background  string  `json:"background"`
+		PageBox     string  `json:"page_box"`
+		Password    string  `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	colorMode, err := converter.ParseColorMode(req.ColorMode)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	flags, err := converter.ParseRenderFlags(req.RenderFlags)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	background, err := converter.ParseBackground(req.Background)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	pageBox, err := converter.ParsePageBox(req.PageBox)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	if req.Format == "" {
+		req.Format = "png"
+	}
+	if req.DPI == 0 {
+		req.DPI = 150
+	}
+	if req.Prefix == "" {
+		req.Prefix = "page_"
 	}
+
+	opts := &converter.ConvertOptions{
+		InputPath:    req.PDFPath,
+		OutputDir:    req.OutputDir,
+		Format:       req.Format,
+		DPI:          req.DPI,
+		Width:        req.Width,
+		Height:       req.Height,
+		MaxPixels:    req.MaxPixels,
+		Pages:        req.Pages,
+		StartPage:    req.StartPage,
+		EndPage:      req.EndPage,
+		Prefix:       req.Prefix,
+		NameTemplate: req.NameTmpl,
+		PageTimeout:  time.Duration(req.PageTimeout * float64(time.Second)),
+		MultiPage:    req.MultiPage,
+		Resume:       req.Resume,
+		Password:     req.Password,
+		ColorMode:    colorMode,
+		Threshold:    req.Threshold,
+		Flags:        flags,
+		Background:   background,
+		PageBox:      pageBox,
+		Encoding: converter.EncodeOptions{
+			Quality:        req.Quality,
+			PNGCompression: req.PNGLevel,
+			Palette:        req.Palette,
+		},
+	}
+
+	if progress := progressFromContext(ctx); progress != nil {
+		opts.Progress = func(ev converter.ProgressEvent) {
+			switch ev.Type {
+			case converter.PageDone:
+				progress(float64(ev.Completed), float64(ev.Total), fmt.Sprintf("Page %d rendered", ev.Page))
+			case converter.PageFailed:
+				progress(float64(ev.Completed), float64(ev.Total), fmt.Sprintf("Page %d failed", ev.Page))
+			}
+		}
+	}
+
+	// A cancelled conversion still returns the pages rendered so far
+	result, err := s.converter.ConvertContext(ctx, opts)
+	if result == nil {
+		return ToolResult{}, err
+	}
+
+	response := map[string]interface{}{
+		"total_pages": result.TotalPages,
+		"successful":  result.Successful,
+		"failed":      result.Failed,
+		"files":       result.OutputFiles,
+	}
+
+	if len(result.Errors) > 0 {
+		response["errors"] = result.Errors
+	}
+	if len(result.TimedOutPages) > 0 {
+		response["timed_out_pages"] = result.TimedOutPages
+	}
+	if len(result.CancelledPages) > 0 {
+		response["cancelled_pages"] = result.CancelledPages
+	}
+	if len(result.ResumedPages) > 0 {
+		response["resumed_pages"] = result.ResumedPages
+	}
+	response["manifest"] = result.ManifestPath
+
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFInfo(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath  string `json:"pdf_path"`
+		Password string `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	info, err := s.converter.GetPDFInfoWithPassword(ctx, req.PDFPath, req.Password)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	responseJSON, _ := json.MarshalIndent(info, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFCompress(input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath    string `json:"pdf_path"`
+		OutputPath string `json:"output_path"`
+		Password   string `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	// Usar pdfcpu para optimizar el PDF
+	err := s.compressPDF(req.PDFPath, req.OutputPath, req.Password)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to compress PDF: %w", err)
+	}
+
+	return ToolResult{
+		Type:    "text",
+		Content: fmt.Sprintf("PDF compressed successfully. Output saved to: %s", req.OutputPath),
+	}, nil
+}
+
+// compressPDF usa pdfcpu para optimizar y comprimir el PDF
+func (s *MCPServer) compressPDF(inputPath, outputPath, pass string) error {
+	// Configurar opciones de optimización
+	conf := password.Configuration(pass)
+
+	// Optimizar: comprimir imágenes, remover elementos innecesarios
+	return password.Check(api.OptimizeFile(inputPath, outputPath, conf), pass)
+}
+
+func (s *MCPServer) handlePDFSplit(input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath    string `json:"pdf_path"`
+		OutputPath string `json:"output_path"`
+		Pages      string `json:"pages"`
+		StartPage  int    `json:"start_page"`
+		EndPage    int    `json:"end_page"`
+		Password   string `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	split := splitter.New()
+	opts := &splitter.SplitOptions{
+		InputPath:  req.PDFPath,
+		OutputPath: req.OutputPath,
+		Pages:      req.Pages,
+		StartPage:  req.StartPage,
+		EndPage:    req.EndPage,
+		Password:   req.Password,
+	}
+
+	result, err := split.Split(opts)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to split PDF: %w", err)
+	}
+
+	response := map[string]interface{}{
+		"total_pages":     result.TotalPages,
+		"extracted_pages": result.ExtractedPages,
+		"output_path":     result.OutputPath,
+	}
+
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
 }
 
-// ExecuteTool executes a tool with the given input
-func (s *MCPServer) ExecuteTool(toolName string, input json.RawMessage) (ToolResult, error) {
-	switch toolName {
-	case "pdf_to_images":
-		return s.handlePDFToImages(input)
-	case "pdf_info":
-		return s.handlePDFInfo(input)
-	case "pdf_compress":
-		return s.handlePDFCompress(input)
-	case "pdf_split":
-		return s.handlePDFSplit(input)
-	default:
-		return ToolResult{}, fmt.Errorf("unknown tool: %s", toolName)
+func (s *MCPServer) handlePDFExtractText(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath  string `json:"pdf_path"`
+		Pages    string `json:"pages"`
+		MaxChars int    `json:"max_chars_per_page"`
+		Password string `json:"password"`
 	}
-}
 
-// Private tool handlers
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	result, err := s.converter.ExtractText(ctx, pdfBytes, req.Pages, &converter.TextOptions{
+		MaxChars: req.MaxChars,
+		Password: req.Password,
+	})
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to extract text: %w", err)
+	}
+
+	response := map[string]interface{}{
+		"total_pages": result.TotalPages,
+		"pages":       result.Pages,
+	}
+
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
 
-func (s *MCPServer) handlePDFToImages(input json.RawMessage) (ToolResult, error) {
+func (s *MCPServer) handlePDFTextLayout(ctx context.Context, input json.RawMessage) (ToolResult, error) {
 	var req struct {
 		PDFPath   string  `json:"pdf_path"`
-		OutputDir string  `json:"output_dir"`
-		Format    string  `json:"format"`
+		Pages     string  `json:"pages"`
+		Level     string  `json:"level"`
 		DPI       float64 `json:"dpi"`
-		StartPage int     `json:"start_page"`
-		EndPage   int     `json:"end_page"`
-		Prefix    string  `json:"prefix"`
+		Width     int     `json:"width"`
+		Height    int     `json:"height"`
+		MaxPixels int     `json:"max_pixels"`
+		PageBox   string  `json:"page_box"`
+		Password  string  `json:"password"`
 	}
 
 	if err := json.Unmarshal(input, &req); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
 	}
 
-	if req.Format == "" {
-		req.Format = "png"
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
 	}
-	if req.DPI == 0 {
-		req.DPI = 150
+
+	pageBox, err := converter.ParsePageBox(req.PageBox)
+	if err != nil {
+		return ToolResult{}, err
 	}
-	if req.Prefix == "" {
-		req.Prefix = "page_"
+
+	layouts, err := s.converter.ExtractTextLayout(ctx, pdfBytes, req.Pages, &converter.LayoutOptions{
+		Level:     req.Level,
+		DPI:       req.DPI,
+		Width:     req.Width,
+		Height:    req.Height,
+		MaxPixels: req.MaxPixels,
+		PageBox:   pageBox,
+		Password:  req.Password,
+	})
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to extract text layout: %w", err)
 	}
 
-	opts := &converter.ConvertOptions{
-		InputPath: req.PDFPath,
+	// Not indented: layouts at word or char level get large
+	responseJSON, _ := json.Marshal(map[string]interface{}{"pages": layouts})
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFSearch(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath       string  `json:"pdf_path"`
+		Query         string  `json:"query"`
+		Pages         string  `json:"pages"`
+		Regex         bool    `json:"regex"`
+		CaseSensitive bool    `json:"case_sensitive"`
+		ContextChars  int     `json:"context_chars"`
+		MaxHits       int     `json:"max_hits"`
+		HighlightDir  string  `json:"highlight_dir"`
+		Format        string  `json:"format"`
+		DPI           float64 `json:"dpi"`
+		PageBox       string  `json:"page_box"`
+		Password      string  `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	pageBox, err := converter.ParsePageBox(req.PageBox)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	result, err := s.converter.Search(ctx, pdfBytes, req.Pages, req.Query, &converter.SearchOptions{
+		Regex:         req.Regex,
+		CaseSensitive: req.CaseSensitive,
+		ContextChars:  req.ContextChars,
+		MaxHits:       req.MaxHits,
+		DPI:           req.DPI,
+		PageBox:       pageBox,
+		Password:      req.Password,
+		HighlightDir:  req.HighlightDir,
+		Format:        req.Format,
+	})
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("search failed: %w", err)
+	}
+
+	responseJSON, _ := json.Marshal(result)
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFExtractImages(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath   string `json:"pdf_path"`
+		OutputDir string `json:"output_dir"`
+		Pages     string `json:"pages"`
+		Format    string `json:"format"`
+		MinSize   int    `json:"min_size"`
+		Password  string `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	result, err := s.converter.ExtractImages(ctx, pdfBytes, req.Pages, &converter.ImageOptions{
 		OutputDir: req.OutputDir,
+		MinSize:   req.MinSize,
 		Format:    req.Format,
-		DPI:       req.DPI,
-		StartPage: req.StartPage,
-		EndPage:   req.EndPage,
-		Prefix:    req.Prefix,
+		Password:  req.Password,
+	})
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to extract images: %w", err)
+	}
+
+	responseJSON, _ := json.MarshalIndent(result, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFMetadataGet(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath  string `json:"pdf_path"`
+		Password string `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	meta, err := s.converter.Metadata(ctx, pdfBytes, req.Password)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	responseJSON, _ := json.MarshalIndent(meta, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFMetadataSet(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	// Pointers tell omitted fields (kept) from empty ones (removed)
+	var req struct {
+		PDFPath      string  `json:"pdf_path"`
+		OutputPath   string  `json:"output_path"`
+		Title        *string `json:"title"`
+		Author       *string `json:"author"`
+		Subject      *string `json:"subject"`
+		Keywords     *string `json:"keywords"`
+		Creator      *string `json:"creator"`
+		Producer     *string `json:"producer"`
+		CreationDate *string `json:"creation_date"`
+		ModDate      *string `json:"mod_date"`
+		XMP          *string `json:"xmp"`
+		Strip        bool    `json:"strip"`
+		Password     string  `json:"password"`
+	}
+
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	changes := &metadata.Changes{
+		Title:    req.Title,
+		Author:   req.Author,
+		Subject:  req.Subject,
+		Keywords: req.Keywords,
+		Creator:  req.Creator,
+		Producer: req.Producer,
+		XMP:      req.XMP,
+	}
+	var err error
+	if req.CreationDate != nil {
+		if changes.CreationDate, err = metadata.ParseDate(*req.CreationDate); err != nil {
+			return ToolResult{}, fmt.Errorf("invalid creation_date: %w", err)
+		}
+	}
+	if req.ModDate != nil {
+		if changes.ModDate, err = metadata.ParseDate(*req.ModDate); err != nil {
+			return ToolResult{}, fmt.Errorf("invalid mod_date: %w", err)
+		}
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
 	}
 
-	result, err := s.converter.Convert(opts)
+	var out []byte
+	if req.Strip {
+		if *changes != (metadata.Changes{}) {
+			return ToolResult{}, fmt.Errorf("strip cannot be combined with metadata fields")
+		}
+		out, err = metadata.Strip(pdfBytes, req.Password)
+	} else {
+		out, err = metadata.Set(pdfBytes, req.Password, changes)
+	}
 	if err != nil {
 		return ToolResult{}, err
 	}
 
-	response := map[string]interface{}{
-		"total_pages": result.TotalPages,
-		"successful":  result.Successful,
-		"failed":      result.Failed,
-		"files":       result.OutputFiles,
+	outputPath := req.OutputPath
+	if outputPath == "" {
+		outputPath = req.PDFPath
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		return ToolResult{}, fmt.Errorf("failed to write PDF: %w", err)
 	}
 
-	if len(result.Errors) > 0 {
-		response["errors"] = result.Errors
+	meta, err := s.converter.Metadata(ctx, out, req.Password)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	response := map[string]interface{}{
+		"output_path": outputPath,
+		"metadata":    meta,
 	}
 
 	responseJSON, _ := json.MarshalIndent(response, "", "  ")
@@ -218,89 +1207,252 @@ func (s *MCPServer) handlePDFToImages(input json.RawMessage) (ToolResult, error)
 	}, nil
 }
 
-func (s *MCPServer) handlePDFInfo(input json.RawMessage) (ToolResult, error) {
+func (s *MCPServer) handlePDFOutline(ctx context.Context, input json.RawMessage) (ToolResult, error) {
 	var req struct {
-		PDFPath string `json:"pdf_path"`
+		PDFPath  string `json:"pdf_path"`
+		Password string `json:"password"`
 	}
 
 	if err := json.Unmarshal(input, &req); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
 	}
 
-	info, err := s.converter.GetPDFInfo(req.PDFPath)
+	pdfBytes, err := os.ReadFile(req.PDFPath)
 	if err != nil {
-		return ToolResult{}, err
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
 	}
 
-	responseJSON, _ := json.MarshalIndent(info, "", "  ")
+	outline, err := s.converter.Outline(ctx, pdfBytes, req.Password)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read outline: %w", err)
+	}
+
+	response := map[string]interface{}{
+		"pdf_path": req.PDFPath,
+		"outline":  outline,
+	}
+
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
 	return ToolResult{
 		Type:    "text",
 		Content: string(responseJSON),
 	}, nil
 }
 
-func (s *MCPServer) handlePDFCompress(input json.RawMessage) (ToolResult, error) {
+func (s *MCPServer) handlePDFAnnotations(ctx context.Context, input json.RawMessage) (ToolResult, error) {
 	var req struct {
-		PDFPath    string `json:"pdf_path"`
-		OutputPath string `json:"output_path"`
+		PDFPath  string `json:"pdf_path"`
+		Pages    string `json:"pages"`
+		Types    string `json:"types"`
+		Password string `json:"password"`
 	}
 
 	if err := json.Unmarshal(input, &req); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
 	}
 
-	// Usar pdfcpu para optimizar el PDF
-	err := s.compressPDF(req.PDFPath, req.OutputPath)
+	pdfBytes, err := os.ReadFile(req.PDFPath)
 	if err != nil {
-		return ToolResult{}, fmt.Errorf("failed to compress PDF: %w", err)
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	opts := &converter.AnnotationOptions{Password: req.Password}
+	for _, t := range strings.Split(req.Types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			opts.Types = append(opts.Types, t)
+		}
+	}
+	result, err := s.converter.Annotations(ctx, pdfBytes, req.Pages, opts)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read annotations: %w", err)
 	}
 
+	responseJSON, _ := json.MarshalIndent(result, "", "  ")
 	return ToolResult{
 		Type:    "text",
-		Content: fmt.Sprintf("PDF compressed successfully. Output saved to: %s", req.OutputPath),
+		Content: string(responseJSON),
 	}, nil
 }
 
-// compressPDF usa pdfcpu para optimizar y comprimir el PDF
-func (s *MCPServer) compressPDF(inputPath, outputPath string) error {
-	// Configurar opciones de optimización
-	conf := api.LoadConfiguration()
+func (s *MCPServer) handlePDFFormFields(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath  string `json:"pdf_path"`
+		Password string `json:"password"`
+	}
 
-	// Optimizar: comprimir imágenes, remover elementos innecesarios
-	return api.OptimizeFile(inputPath, outputPath, conf)
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return ToolResult{}, err
+	}
+	fields, err := forms.List(pdfBytes, req.Password)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	response := map[string]interface{}{
+		"total_fields": len(fields),
+		"fields":       fields,
+	}
+
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
 }
 
-func (s *MCPServer) handlePDFSplit(input json.RawMessage) (ToolResult, error) {
+func (s *MCPServer) handlePDFFormFill(ctx context.Context, input json.RawMessage) (ToolResult, error) {
 	var req struct {
-		PDFPath    string `json:"pdf_path"`
-		OutputPath string `json:"output_path"`
-		StartPage  int    `json:"start_page"`
-		EndPage    int    `json:"end_page"`
+		PDFPath    string         `json:"pdf_path"`
+		OutputPath string         `json:"output_path"`
+		Values     map[string]any `json:"values"`
+		Flatten    bool           `json:"flatten"`
+		Password   string         `json:"password"`
 	}
 
 	if err := json.Unmarshal(input, &req); err != nil {
 		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
 	}
 
-	split := splitter.New()
-	opts := &splitter.SplitOptions{
-		InputPath:  req.PDFPath,
-		OutputPath: req.OutputPath,
-		StartPage:  req.StartPage,
-		EndPage:    req.EndPage,
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
 	}
 
-	result, err := split.Split(opts)
+	out, err := forms.Fill(pdfBytes, req.Password, req.Values)
 	if err != nil {
-		return ToolResult{}, fmt.Errorf("failed to split PDF: %w", err)
+		return ToolResult{}, err
+	}
+	if req.Flatten {
+		if out, err = s.converter.FlattenForm(ctx, out, req.Password); err != nil {
+			return ToolResult{}, fmt.Errorf("failed to flatten form: %w", err)
+		}
+	}
+
+	outputPath := req.OutputPath
+	if outputPath == "" {
+		outputPath = req.PDFPath
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		return ToolResult{}, fmt.Errorf("failed to write PDF: %w", err)
 	}
 
 	response := map[string]interface{}{
-		"total_pages":     result.TotalPages,
-		"extracted_pages": result.ExtractedPages,
-		"output_path":     result.OutputPath,
+		"output_path":   outputPath,
+		"fields_filled": len(req.Values),
+		"flattened":     req.Flatten,
+	}
+	if !req.Flatten {
+		fields, err := forms.List(out, req.Password)
+		if err != nil {
+			return ToolResult{}, err
+		}
+		response["fields"] = fields
+	}
+
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return ToolResult{
+		Type:    "text",
+		Content: string(responseJSON),
+	}, nil
+}
+
+func (s *MCPServer) handlePDFRenderRegion(ctx context.Context, input json.RawMessage) (ToolResult, error) {
+	var req struct {
+		PDFPath     string  `json:"pdf_path"`
+		OutputPath  string  `json:"output_path"`
+		Page        int     `json:"page"`
+		X           float64 `json:"x"`
+		Y           float64 `json:"y"`
+		Width       float64 `json:"width"`
+		Height      float64 `json:"height"`
+		Units       string  `json:"units"`
+		DPI         float64 `json:"dpi"`
+		Format      string  `json:"format"`
+		Background  string  `json:"background"`
+		RenderFlags string  `json:"render_flags"`
+		PageBox     string  `json:"page_box"`
+		Password    string  `json:"password"`
 	}
 
+	if err := json.Unmarshal(input, &req); err != nil {
+		return ToolResult{}, fmt.Errorf("invalid input: %w", err)
+	}
+	if req.OutputPath == "" {
+		return ToolResult{}, fmt.Errorf("output_path is required")
+	}
+
+	region := converter.Region{X: req.X, Y: req.Y, Width: req.Width, Height: req.Height}
+	switch req.Units {
+	case "", "points":
+	case "fraction":
+		region.Fraction = true
+	default:
+		return ToolResult{}, fmt.Errorf("units must be 'points' or 'fraction'")
+	}
+	background, err := converter.ParseBackground(req.Background)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	flags, err := converter.ParseRenderFlags(req.RenderFlags)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	pageBox, err := converter.ParsePageBox(req.PageBox)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	if req.DPI == 0 {
+		req.DPI = 300
+	}
+	if req.Format == "" {
+		req.Format = strings.TrimPrefix(filepath.Ext(req.OutputPath), ".")
+		if req.Format == "" {
+			req.Format = "png"
+		}
+	}
+
+	pdfBytes, err := os.ReadFile(req.PDFPath)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	img, err := s.converter.RenderRegion(ctx, pdfBytes, req.Page, region, &converter.RenderOptions{
+		DPI:        req.DPI,
+		Password:   req.Password,
+		Flags:      flags,
+		Background: background,
+		PageBox:    pageBox,
+	})
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to render region: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := converter.EncodeImage(&buf, img, req.Format, nil); err != nil {
+		return ToolResult{}, err
+	}
+	if err := os.WriteFile(req.OutputPath, buf.Bytes(), 0644); err != nil {
+		return ToolResult{}, fmt.Errorf("failed to write image: %w", err)
+	}
+
+	response := map[string]interface{}{
+		"output_path": req.OutputPath,
+		"page":        req.Page,
+		"region":      region.String(),
+		"fraction":    region.Fraction,
+		"page_box":    pageBox,
+		"dpi":         req.DPI,
+		"format":      req.Format,
+		"size":        buf.Len(),
+	}
 	responseJSON, _ := json.MarshalIndent(response, "", "  ")
 	return ToolResult{
 		Type:    "text",
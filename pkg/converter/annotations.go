@@ -0,0 +1,339 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+	"github.com/tu-usuario/pdf2img/pkg/password"
+)
+
+// AnnotationOptions controls Annotations
+type AnnotationOptions struct {
+	// Types lists the annotation types to return, e.g. "Highlight" or
+	// "Text" (case-insensitive). By default all types are returned except
+	// Popup, the window that shows the contents of another annotation.
+	Types []string
+
+	Password string // Password of an encrypted PDF (user or owner password)
+}
+
+// AnnotationsResult lists the annotations of the selected pages
+type AnnotationsResult struct {
+	TotalPages  int          `json:"total_pages"` // Pages in the document
+	Annotations []Annotation `json:"annotations"` // In page order, then in the order of the page
+}
+
+// Annotation is an annotation of a page: a note, highlight, link, form
+// field widget...
+type Annotation struct {
+	Page  int    `json:"page"`  // Page number (1-indexed)
+	Index int    `json:"index"` // Position among the annotations of the page (1-indexed)
+	Type  string `json:"type"`  // PDF subtype, e.g. Text (a sticky note), Highlight, Link, Widget
+	Rect  Rect   `json:"rect"`  // In PDF user space
+
+	Contents string `json:"contents,omitempty"` // Text of a note or comment
+	Author   string `json:"author,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Color    string `json:"color,omitempty"` // "#rrggbb"
+
+	// Index of the annotation this one replies to, on the same page
+	InReplyTo int `json:"in_reply_to,omitempty"`
+
+	// Target of a link: a web page or a page of the document (1-indexed)
+	URI      string `json:"uri,omitempty"`
+	DestPage int    `json:"dest_page,omitempty"`
+}
+
+// annotationTypes maps PDFium annotation subtypes to their PDF names
+var annotationTypes = map[enums.FPDF_ANNOTATION_SUBTYPE]string{
+	enums.FPDF_ANNOT_SUBTYPE_TEXT:           "Text",
+	enums.FPDF_ANNOT_SUBTYPE_LINK:           "Link",
+	enums.FPDF_ANNOT_SUBTYPE_FREETEXT:       "FreeText",
+	enums.FPDF_ANNOT_SUBTYPE_LINE:           "Line",
+	enums.FPDF_ANNOT_SUBTYPE_SQUARE:         "Square",
+	enums.FPDF_ANNOT_SUBTYPE_CIRCLE:         "Circle",
+	enums.FPDF_ANNOT_SUBTYPE_POLYGON:        "Polygon",
+	enums.FPDF_ANNOT_SUBTYPE_POLYLINE:       "PolyLine",
+	enums.FPDF_ANNOT_SUBTYPE_HIGHLIGHT:      "Highlight",
+	enums.FPDF_ANNOT_SUBTYPE_UNDERLINE:      "Underline",
+	enums.FPDF_ANNOT_SUBTYPE_SQUIGGLY:       "Squiggly",
+	enums.FPDF_ANNOT_SUBTYPE_STRIKEOUT:      "StrikeOut",
+	enums.FPDF_ANNOT_SUBTYPE_STAMP:          "Stamp",
+	enums.FPDF_ANNOT_SUBTYPE_CARET:          "Caret",
+	enums.FPDF_ANNOT_SUBTYPE_INK:            "Ink",
+	enums.FPDF_ANNOT_SUBTYPE_POPUP:          "Popup",
+	enums.FPDF_ANNOT_SUBTYPE_FILEATTACHMENT: "FileAttachment",
+	enums.FPDF_ANNOT_SUBTYPE_SOUND:          "Sound",
+	enums.FPDF_ANNOT_SUBTYPE_MOVIE:          "Movie",
+	enums.FPDF_ANNOT_SUBTYPE_WIDGET:         "Widget",
+	enums.FPDF_ANNOT_SUBTYPE_SCREEN:         "Screen",
+	enums.FPDF_ANNOT_SUBTYPE_PRINTERMARK:    "PrinterMark",
+	enums.FPDF_ANNOT_SUBTYPE_TRAPNET:        "TrapNet",
+	enums.FPDF_ANNOT_SUBTYPE_WATERMARK:      "Watermark",
+	enums.FPDF_ANNOT_SUBTYPE_THREED:         "3D",
+	enums.FPDF_ANNOT_SUBTYPE_RICHMEDIA:      "RichMedia",
+	enums.FPDF_ANNOT_SUBTYPE_XFAWIDGET:      "XFAWidget",
+	enums.FPDF_ANNOT_SUBTYPE_REDACT:         "Redact",
+}
+
+// Annotations lists the annotations of the selected pages (see pagesel,
+// "" = all pages) of an in-memory PDF
+func (c *Converter) Annotations(ctx context.Context, pdf []byte, pages string, opts *AnnotationOptions) (*AnnotationsResult, error) {
+	if opts == nil {
+		opts = &AnnotationOptions{}
+	}
+	filter, err := annotationFilter(opts.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	sel, err := pagesel.Parse(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newRenderWorker(c.pool, pdf, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	pageCount, err := w.pageCount()
+	if err != nil {
+		return nil, err
+	}
+	pageNums, err := sel.Pages(pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnnotationsResult{TotalPages: pageCount, Annotations: []Annotation{}}
+	for _, pageNum := range pageNums {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		annots, err := w.pageAnnotations(pageNum, filter)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		result.Annotations = append(result.Annotations, annots...)
+	}
+
+	setAnnotationColors(result.Annotations, pdf, opts.Password)
+	return result, nil
+}
+
+// annotationFilter returns a function reporting whether an annotation type
+// was asked for
+func annotationFilter(types []string) (func(typ string) bool, error) {
+	if len(types) == 0 {
+		return func(typ string) bool { return typ != "Popup" }, nil
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range types {
+		name := ""
+		for _, known := range annotationTypes {
+			if strings.EqualFold(t, known) {
+				name = known
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("unknown annotation type %q", t)
+		}
+		wanted[name] = true
+	}
+	return func(typ string) bool { return wanted[typ] }, nil
+}
+
+// pageAnnotations lists the annotations of pageNum (1-indexed) that filter
+// accepts. Entries of the page that are not annotations are skipped.
+func (w *renderWorker) pageAnnotations(pageNum int, filter func(typ string) bool) ([]Annotation, error) {
+	page := w.page(pageNum)
+	count, err := w.instance.FPDFPage_GetAnnotCount(&requests.FPDFPage_GetAnnotCount{Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count annotations: %w", err)
+	}
+
+	var annots []Annotation
+	for i := 0; i < count.Count; i++ {
+		annot, err := w.instance.FPDFPage_GetAnnot(&requests.FPDFPage_GetAnnot{Page: page, Index: i})
+		if err != nil {
+			continue
+		}
+		a, ok := w.annotation(page, annot.Annotation, filter)
+		w.instance.FPDFPage_CloseAnnot(&requests.FPDFPage_CloseAnnot{Annotation: annot.Annotation})
+		if !ok {
+			continue
+		}
+		a.Page = pageNum
+		a.Index = i + 1
+		annots = append(annots, a)
+	}
+	return annots, nil
+}
+
+// annotation reads an annotation, reporting false if filter rejects its type
+func (w *renderWorker) annotation(page requests.Page, annot references.FPDF_ANNOTATION, filter func(typ string) bool) (Annotation, bool) {
+	subtype, err := w.instance.FPDFAnnot_GetSubtype(&requests.FPDFAnnot_GetSubtype{Annotation: annot})
+	if err != nil {
+		return Annotation{}, false
+	}
+	a := Annotation{Type: annotationTypes[subtype.Subtype]}
+	if a.Type == "" {
+		a.Type = "Unknown"
+	}
+	if !filter(a.Type) {
+		return Annotation{}, false
+	}
+
+	if rect, err := w.instance.FPDFAnnot_GetRect(&requests.FPDFAnnot_GetRect{Annotation: annot}); err == nil {
+		r := rect.Rect
+		a.Rect = roundRect(Rect{
+			X0: float64(min(r.Left, r.Right)), Y0: float64(min(r.Bottom, r.Top)),
+			X1: float64(max(r.Left, r.Right)), Y1: float64(max(r.Bottom, r.Top)),
+		})
+	}
+	a.Contents = w.annotationText(annot, "Contents")
+	a.Author = w.annotationText(annot, "T")
+	a.Subject = w.annotationText(annot, "Subj")
+
+	a.InReplyTo = w.replyTarget(page, annot)
+
+	if a.Type == "Link" {
+		a.DestPage, a.URI = w.linkTarget(annot)
+	}
+	return a, true
+}
+
+// annotationText returns a text entry of an annotation, or "" if it has none
+func (w *renderWorker) annotationText(annot references.FPDF_ANNOTATION, key string) string {
+	value, err := w.instance.FPDFAnnot_GetStringValue(&requests.FPDFAnnot_GetStringValue{Annotation: annot, Key: key})
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(cleanText(value.Value))
+}
+
+// replyTarget returns the index (1-indexed) of the annotation annot replies
+// to, or 0 if it is not a reply
+func (w *renderWorker) replyTarget(page requests.Page, annot references.FPDF_ANNOTATION) int {
+	linked, err := w.instance.FPDFAnnot_GetLinkedAnnot(&requests.FPDFAnnot_GetLinkedAnnot{Annotation: annot, Key: "IRT"})
+	if err != nil {
+		return 0
+	}
+	defer w.instance.FPDFPage_CloseAnnot(&requests.FPDFPage_CloseAnnot{Annotation: linked.LinkedAnnotation})
+
+	index, err := w.instance.FPDFPage_GetAnnotIndex(&requests.FPDFPage_GetAnnotIndex{Page: page, Annotation: linked.LinkedAnnotation})
+	if err != nil || index.Index < 0 {
+		return 0
+	}
+	return index.Index + 1
+}
+
+// linkTarget returns the page (1-indexed) or the URI a link annotation
+// points to
+func (w *renderWorker) linkTarget(annot references.FPDF_ANNOTATION) (page int, uri string) {
+	link, err := w.instance.FPDFAnnot_GetLink(&requests.FPDFAnnot_GetLink{Annotation: annot})
+	if err != nil {
+		return 0, ""
+	}
+
+	dest, err := w.instance.FPDFLink_GetDest(&requests.FPDFLink_GetDest{Document: w.doc, Link: link.Link})
+	if err == nil && dest.Dest != nil {
+		return destPage(w.instance, w.doc, *dest.Dest), ""
+	}
+	action, err := w.instance.FPDFLink_GetAction(&requests.FPDFLink_GetAction{Link: link.Link})
+	if err == nil && action.Action != nil {
+		return actionTarget(w.instance, w.doc, *action.Action)
+	}
+	return 0, ""
+}
+
+// setAnnotationColors sets the color of annotations from their C entry.
+// PDFium only reads it for annotations without an appearance stream, which
+// most have, and makes up a default when there is none. It is best effort:
+// with a PDF pdfcpu cannot read the annotations have no color.
+func setAnnotationColors(annots []Annotation, pdf []byte, pass string) {
+	if len(annots) == 0 {
+		return
+	}
+
+	conf := password.Configuration(pass)
+	conf.ValidationMode = model.ValidationRelaxed
+
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil || ctx.EnsurePageCount() != nil {
+		return
+	}
+	for i := range annots {
+		annots[i].Color = annotationColor(ctx, annots[i].Page, annots[i].Index)
+	}
+}
+
+// annotationColor returns the C entry of the index-th (1-indexed)
+// annotation of pageNum as "#rrggbb", or "" if it has none
+func annotationColor(ctx *model.Context, pageNum, index int) string {
+	d, _, _, err := ctx.PageDict(pageNum, false)
+	if err != nil || d == nil {
+		return ""
+	}
+	obj, ok := d.Find("Annots")
+	if !ok {
+		return ""
+	}
+	annots, err := ctx.DereferenceArray(obj)
+	if err != nil || index > len(annots) {
+		return ""
+	}
+	annot, err := ctx.DereferenceDict(annots[index-1])
+	if err != nil || annot == nil {
+		return ""
+	}
+	obj, ok = annot.Find("C")
+	if !ok {
+		return ""
+	}
+	arr, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return ""
+	}
+
+	components := make([]float64, 0, len(arr))
+	for _, c := range arr {
+		v, err := ctx.DereferenceNumber(c)
+		if err != nil {
+			return ""
+		}
+		components = append(components, v)
+	}
+	return hexColor(components)
+}
+
+// hexColor formats gray, RGB or CMYK components (0-1) as "#rrggbb". An
+// empty array, which makes the annotation transparent, gives "".
+func hexColor(components []float64) string {
+	var r, g, b float64
+	switch len(components) {
+	case 1:
+		r, g, b = components[0], components[0], components[0]
+	case 3:
+		r, g, b = components[0], components[1], components[2]
+	case 4:
+		c, m, y, k := components[0], components[1], components[2], components[3]
+		r, g, b = (1-c)*(1-k), (1-m)*(1-k), (1-y)*(1-k)
+	default:
+		return ""
+	}
+	byteOf := func(v float64) int { return int(math.Round(math.Min(math.Max(v, 0), 1) * 255)) }
+	return fmt.Sprintf("#%02x%02x%02x", byteOf(r), byteOf(g), byteOf(b))
+}
@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+)
+
+// ParseBackground parses a page background: "#rgb", "#rrggbb",
+// "#rrggbbaa" or "transparent". "" returns nil, the default white.
+func ParseBackground(s string) (color.Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return nil, nil
+	case "transparent":
+		return color.NRGBA{}, nil
+	}
+
+	hex, ok := strings.CutPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || len(hex) != 8 || err != nil {
+		return nil, fmt.Errorf("invalid background %q: expected #rrggbb, #rrggbbaa or transparent", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// backgroundString formats a background as ParseBackground accepts it,
+// "" for the default
+func backgroundString(bg color.Color) string {
+	if bg == nil {
+		return ""
+	}
+	c := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	if c.A == 0 {
+		return "transparent"
+	}
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// opaqueFormats are the output formats that cannot store transparency
+var opaqueFormats = map[string]bool{"jpg": true, "jpeg": true}
+
+// formatBackground returns the background to render for format: formats
+// without transparency get the color made opaque, and white when it is
+// fully transparent
+func formatBackground(format string, bg color.Color) color.Color {
+	if bg == nil || !opaqueFormats[format] {
+		return bg
+	}
+	c := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	if c.A == 0 {
+		return nil
+	}
+	c.A = 0xff
+	return c
+}
+
+// flattenOnWhite composites an image that may have transparent pixels onto
+// white, for formats without an alpha channel
+func flattenOnWhite(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(out, b, img, b.Min, draw.Over)
+	return out
+}
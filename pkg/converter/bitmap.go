@@ -0,0 +1,200 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+// bitmapRender is where a page lands in a bitmap: the bitmap is width x
+// height pixels, and the whole page, sizeX x sizeY pixels, is drawn with
+// its top left corner at startX, startY (negative when cropping)
+type bitmapRender struct {
+	width, height  int
+	startX, startY int
+	sizeX, sizeY   int
+}
+
+// bitmapLayout places a page of widthPt x heightPt points so that the
+// bitmap shows box, the page box selected in displayed points (see
+// displayedBox), or opts.Crop within it, at the size renderPage uses
+func bitmapLayout(widthPt, heightPt float64, box Rect, opts *RenderOptions) (bitmapRender, error) {
+	area, err := renderArea(box, opts.Crop)
+	if err != nil {
+		return bitmapRender{}, err
+	}
+
+	var b bitmapRender
+	areaWidth, areaHeight := area.X1-area.X0, area.Y1-area.Y0
+	b.width, b.height = renderSize(areaWidth, areaHeight, opts)
+	scaleX, scaleY := float64(b.width)/areaWidth, float64(b.height)/areaHeight
+	b.startX, b.startY = -int(math.Round(area.X0*scaleX)), -int(math.Round(area.Y0*scaleY))
+	b.sizeX, b.sizeY = int(math.Round(widthPt*scaleX)), int(math.Round(heightPt*scaleY))
+	return b, nil
+}
+
+// renderArea returns the displayed points a render shows: box, the page
+// box selected in displayed points, or crop within it
+func renderArea(box Rect, crop *Region) (Rect, error) {
+	if crop == nil {
+		return box, nil
+	}
+	r, err := crop.points(box.X1-box.X0, box.Y1-box.Y0)
+	if err != nil {
+		return Rect{}, err
+	}
+	return Rect{X0: box.X0 + r.X0, Y0: box.Y0 + r.Y0, X1: box.X0 + r.X1, Y1: box.Y0 + r.Y1}, nil
+}
+
+// renderBitmap renders a page into a bitmap of our own, for what
+// go-pdfium's render functions can't do: they always fill pages white
+// and draw whole pages. The bitmap is filled with opts.Background (white
+// by default) and shows opts.PageBox, or opts.Crop within it. Form fields
+// are drawn in a form fill environment of their own, as go-pdfium does.
+func renderBitmap(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT, page requests.Page, opts *RenderOptions) renderResult {
+	size, err := instance.GetPageSize(&requests.GetPageSize{Page: page})
+	if err != nil {
+		return renderResult{err: fmt.Errorf("failed to get page size: %w", err)}
+	}
+	box, err := displayedBox(instance, page, size, opts)
+	if err != nil {
+		return renderResult{err: err}
+	}
+	b, err := bitmapLayout(size.Width, size.Height, box, opts)
+	if err != nil {
+		return renderResult{err: err}
+	}
+
+	bitmap, err := instance.FPDFBitmap_Create(&requests.FPDFBitmap_Create{Width: b.width, Height: b.height, Alpha: 1})
+	if err != nil {
+		return renderResult{err: fmt.Errorf("failed to create bitmap: %w", err)}
+	}
+	defer instance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{Bitmap: bitmap.Bitmap})
+
+	var bg color.Color = color.White
+	if opts.Background != nil {
+		bg = opts.Background
+	}
+	c := color.NRGBAModel.Convert(bg).(color.NRGBA)
+	argb := uint64(c.A)<<24 | uint64(c.R)<<16 | uint64(c.G)<<8 | uint64(c.B)
+	if _, err := instance.FPDFBitmap_FillRect(&requests.FPDFBitmap_FillRect{
+		Bitmap: bitmap.Bitmap,
+		Width:  b.width,
+		Height: b.height,
+		Color:  argb,
+	}); err != nil {
+		return renderResult{err: fmt.Errorf("failed to fill background: %w", err)}
+	}
+
+	flags := renderFlags(opts)
+	if customBox(opts.PageBox) {
+		// FPDF_RenderPageBitmap clips to the crop box, which would leave
+		// the bleed of the larger boxes blank. Rendering with a matrix
+		// clips to the bitmap instead. The matrix applies to the displayed
+		// page in points.
+		_, err = instance.FPDF_RenderPageBitmapWithMatrix(&requests.FPDF_RenderPageBitmapWithMatrix{
+			Bitmap: bitmap.Bitmap,
+			Page:   page,
+			Matrix: structs.FPDF_FS_MATRIX{
+				A: float32(float64(b.sizeX) / size.Width),
+				D: float32(float64(b.sizeY) / size.Height),
+				E: float32(b.startX),
+				F: float32(b.startY),
+			},
+			Clipping: structs.FPDF_FS_RECTF{Right: float32(b.width), Bottom: float32(b.height)},
+			Flags:    flags,
+		})
+	} else {
+		_, err = instance.FPDF_RenderPageBitmap(&requests.FPDF_RenderPageBitmap{
+			Bitmap: bitmap.Bitmap,
+			Page:   page,
+			StartX: b.startX,
+			StartY: b.startY,
+			SizeX:  b.sizeX,
+			SizeY:  b.sizeY,
+			Flags:  flags,
+		})
+	}
+	if err != nil {
+		return renderResult{err: err}
+	}
+	if opts.Flags.Forms {
+		if err := drawForms(instance, doc, bitmap.Bitmap, page, b, flags); err != nil {
+			return renderResult{err: err}
+		}
+	}
+
+	buffer, err := instance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{Bitmap: bitmap.Bitmap})
+	if err != nil {
+		return renderResult{err: fmt.Errorf("failed to read bitmap: %w", err)}
+	}
+	stride, err := instance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{Bitmap: bitmap.Bitmap})
+	if err != nil {
+		return renderResult{err: fmt.Errorf("failed to read bitmap: %w", err)}
+	}
+
+	// The buffer lives in WASM memory until the bitmap is destroyed
+	img := bgraToRGBA(buffer.Buffer, b.width, b.height, stride.Stride)
+	return renderResult{img: img, cleanup: func() {}}
+}
+
+// drawForms draws the form fields of a page onto bitmap
+func drawForms(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT, bitmap references.FPDF_BITMAP, page requests.Page, b bitmapRender, flags enums.FPDF_RENDER_FLAG) error {
+	// Nothing is interactive here, but go-pdfium requires these callbacks
+	form, err := instance.FPDFDOC_InitFormFillEnvironment(&requests.FPDFDOC_InitFormFillEnvironment{
+		Document: doc,
+		FormFillInfo: structs.FPDF_FORMFILLINFO{
+			FFI_Invalidate:         func(references.FPDF_PAGE, float64, float64, float64, float64) {},
+			FFI_SetCursor:          func(enums.FXCT) {},
+			FFI_SetTimer:           func(int, func(int)) int { return 0 },
+			FFI_KillTimer:          func(int) {},
+			FFI_GetLocalTime:       func() structs.FPDF_SYSTEMTIME { return structs.FPDF_SYSTEMTIME{} },
+			FFI_GetPage:            func(references.FPDF_DOCUMENT, int) *references.FPDF_PAGE { return nil },
+			FFI_GetRotation:        func(references.FPDF_PAGE) enums.FPDF_PAGE_ROTATION { return enums.FPDF_PAGE_ROTATION_NONE },
+			FFI_ExecuteNamedAction: func(string) {},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to draw form fields: %w", err)
+	}
+	defer instance.FPDFDOC_ExitFormFillEnvironment(&requests.FPDFDOC_ExitFormFillEnvironment{FormHandle: form.FormHandle})
+
+	if _, err := instance.FPDF_FFLDraw(&requests.FPDF_FFLDraw{
+		FormHandle: form.FormHandle,
+		Bitmap:     bitmap,
+		Page:       page,
+		StartX:     b.startX,
+		StartY:     b.startY,
+		SizeX:      b.sizeX,
+		SizeY:      b.sizeY,
+		Flags:      flags,
+	}); err != nil {
+		return fmt.Errorf("failed to draw form fields: %w", err)
+	}
+	return nil
+}
+
+// bgraToRGBA copies a PDFium BGRA bitmap, which has straight alpha, into a
+// premultiplied Go image
+func bgraToRGBA(buf []byte, width, height, stride int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		src := buf[y*stride : y*stride+4*width]
+		dst := img.Pix[y*img.Stride : y*img.Stride+4*width]
+		for i := 0; i < len(src); i += 4 {
+			b, g, r, a := uint32(src[i]), uint32(src[i+1]), uint32(src[i+2]), uint32(src[i+3])
+			if a != 0xff {
+				r, g, b = (r*a+127)/255, (g*a+127)/255, (b*a+127)/255
+			}
+			dst[i], dst[i+1], dst[i+2], dst[i+3] = uint8(r), uint8(g), uint8(b), uint8(a)
+		}
+	}
+	return img
+}
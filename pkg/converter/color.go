@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/tu-usuario/pdf2img/pkg/imgenc"
+)
+
+// ColorMode selects the colors of rendered pages
+type ColorMode string
+
+const (
+	ColorModeColor   ColorMode = "color"   // Full color RGBA (default)
+	ColorModeGray    ColorMode = "gray"    // 8-bit grayscale
+	ColorModeBilevel ColorMode = "bilevel" // 1-bit black and white, split at the threshold
+	ColorModeDither  ColorMode = "dither"  // 1-bit black and white with Floyd–Steinberg dithering
+)
+
+// defaultThreshold is the gray level below which bilevel pixels turn black
+const defaultThreshold = 128
+
+// ParseColorMode parses a color mode name. "" is ColorModeColor and
+// "grayscale" is accepted for ColorModeGray.
+func ParseColorMode(name string) (ColorMode, error) {
+	switch mode := ColorMode(strings.ToLower(name)); mode {
+	case "":
+		return ColorModeColor, nil
+	case "grayscale":
+		return ColorModeGray, nil
+	case ColorModeColor, ColorModeGray, ColorModeBilevel, ColorModeDither:
+		return mode, nil
+	}
+	return "", fmt.Errorf("color mode must be 'color', 'gray', 'bilevel' or 'dither'")
+}
+
+// validateColor checks a color mode and bilevel threshold (0 = default)
+// and that they can be combined with the encoder settings
+func validateColor(mode ColorMode, threshold int, enc *EncodeOptions) error {
+	if _, err := ParseColorMode(string(mode)); err != nil {
+		return err
+	}
+	if threshold < 0 || threshold > 255 {
+		return fmt.Errorf("threshold must be between 1 and 255")
+	}
+	if enc.Palette && reducedColor(mode) {
+		return fmt.Errorf("palette only applies to color output")
+	}
+	return nil
+}
+
+// reducedColor reports whether mode drops the colors of rendered pages
+func reducedColor(mode ColorMode) bool {
+	mode, _ = ParseColorMode(string(mode))
+	return mode != ColorModeColor && mode != ""
+}
+
+// applyColorMode converts a rendered page to the color mode of opts. In
+// color mode img itself is returned, otherwise a new 8-bit gray or 1-bit
+// paletted image that encoders store as such.
+func applyColorMode(img *image.RGBA, opts *RenderOptions) image.Image {
+	threshold := uint8(defaultThreshold)
+	if opts.Threshold > 0 {
+		threshold = uint8(opts.Threshold)
+	}
+
+	mode, _ := ParseColorMode(string(opts.ColorMode))
+	switch mode {
+	case ColorModeGray:
+		return imgenc.Grayscale(img)
+	case ColorModeBilevel:
+		return imgenc.Threshold(img, threshold)
+	case ColorModeDither:
+		return imgenc.Dither(img, threshold)
+	}
+	return img
+}
+
+// pageImage returns a rendered page in the color mode of opts, copied out
+// of WASM memory so it stays valid after the render is released
+func pageImage(img *image.RGBA, opts *RenderOptions) image.Image {
+	if !reducedColor(opts.ColorMode) {
+		return cloneImage(img)
+	}
+	return applyColorMode(img, opts)
+}
@@ -1,48 +1,130 @@
 package converter
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"image/png"
+	"image/color"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/klippa-app/go-pdfium"
 	"github.com/klippa-app/go-pdfium/requests"
-	"github.com/klippa-app/go-pdfium/webassembly"
+	"github.com/tu-usuario/pdf2img/pkg/imgenc"
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
 )
 
+// ErrPageTimeout is reported for pages whose render exceeds ConvertOptions.PageTimeout
+var ErrPageTimeout = errors.New("page render timed out")
+
 // Converter manages PDF to image conversion
 type Converter struct {
-	pool     pdfium.Pool
-	instance pdfium.Pdfium
+	pool     *instancePool
+	mu       sync.Mutex    // Guards instance, which is replaced when a call is abandoned
+	instance pdfium.Pdfium // Shared instance for document queries
+	poolSize int           // Instances available to Convert's render workers
 }
 
 // ConvertOptions specifies conversion parameters
 type ConvertOptions struct {
 	InputPath    string  // Path to PDF file
 	OutputDir    string  // Output directory
-	Format       string  // "png" or "jpg"
+	Format       string  // Any registered format: "png", "jpg", "webp", "tiff"... (default "png")
 	DPI          float64 // DPI for rendering (default 150)
-	StartPage    int     // Start page (1-indexed, 0 = all)
-	EndPage      int     // End page (1-indexed, 0 = all)
+	Width        int     // Render pages this many pixels wide (overrides DPI)
+	Height       int     // Render pages this many pixels tall (overrides DPI; with Width: fit inside the box)
+	MaxPixels    int     // Scale down pages whose width*height would exceed this (0 = no limit)
+	Pages        string  // Page selection, e.g. "1-3,7,10-", "last 5", "odd,!1" (see pagesel; default all)
+	StartPage    int     // Start page (1-indexed, 0 = first); ignored when Pages is set
+	EndPage      int     // End page (1-indexed, 0 = last); ignored when Pages is set
 	Prefix       string  // Prefix for output files
 	RetryFailed  bool    // Retry failed pages with reduced DPI (default false)
-	MaxPoolSize  int     // Max PDFium instances in pool (default 2, increase for large PDFs)
+	MaxPoolSize  int     // Max PDFium instances rendering in parallel (default: converter pool size)
 	RefreshEvery int     // Refresh PDFium instance every N pages (0 = disable, default 50)
+	Password     string  // Password of an encrypted PDF (user or owner password)
+
+	// NameTemplate names the output files relative to OutputDir, e.g.
+	// "{basename}_{page:05}_{dpi}dpi.{ext}" or "{basename}/{label}.{ext}".
+	// Placeholders: {basename}, {prefix}, {page}, {label}, {total}, {dpi},
+	// {width}, {height}, {hash} and {ext}; numbers take a zero-padded width
+	// ({page:05}). It must contain {page}, {label} or {hash}; a page whose
+	// name was already used by another page fails, and names that would
+	// leave OutputDir are rejected. {dpi} needs a fixed DPI (no Width,
+	// Height or MaxPixels). Default DefaultNameTemplate.
+	NameTemplate string
+
+	// Resume skips pages that an earlier run with the same input and
+	// options already rendered, as recorded in the manifest Convert keeps
+	// next to the images (see ManifestPath). Only missing, changed or
+	// failed pages are rendered again. Not available with MultiPage.
+	Resume bool
+
+	// MultiPage writes all pages into a single file named after the input
+	// (e.g. report.tiff) instead of one file per page. The format's encoder
+	// must implement MultiPageEncoder.
+	MultiPage bool
+
+	// Encoding tunes the encoder: JPEG quality, PNG compression, palette
+	Encoding EncodeOptions
+
+	// ColorMode writes pages in color (default), 8-bit gray or 1-bit black
+	// and white, split at Threshold (1-255, default 128) or dithered.
+	// PNG and TIFF files then store 8 or 1 bit per pixel.
+	ColorMode ColorMode
+	Threshold int
+
+	// Flags switches annotations, form fields, print mode and anti-aliasing.
+	// The zero value renders page content only.
+	Flags RenderFlags
+
+	// Background fills pages before they are drawn (nil = white, see
+	// ParseBackground). Transparent areas stay transparent in PNG, WebP
+	// and TIFF; JPEG gets the color made opaque, or white.
+	Background color.Color
+
+	// Crop writes only this rectangle of every page (nil = whole pages,
+	// see ParseRegion). Width, Height and MaxPixels then size the
+	// rectangle; pages it doesn't overlap fail.
+	Crop *Region
+
+	// PageBox selects the page boundary written (default PageBoxCrop, see
+	// ParsePageBox), e.g. PageBoxBleed or PageBoxTrim for print-ready
+	// PDFs. Crop is relative to the selected box.
+	PageBox PageBox
+
+	// PageTimeout bounds the render of a single page (0 = no limit). A worker
+	// whose render exceeds it moves on to a fresh instance from the pool.
+	PageTimeout time.Duration
+
+	// Progress, if set, receives an event for every page started, done,
+	// failed or retried and for every instance refresh
+	Progress ProgressFunc
 }
 
 // ConvertResult contains conversion results
 type ConvertResult struct {
-	TotalPages  int
-	Successful  int
-	Failed      int
-	OutputFiles []string
-	Errors      []string
+	TotalPages   int
+	Successful   int
+	Failed       int
+	OutputFiles  []string
+	Errors       []string
 	WarningPages []int // Pages with unreachable errors (may need manual inspection)
+
+	CancelledPages []int // Pages skipped because the context was cancelled
+	TimedOutPages  []int // Pages that exceeded PageTimeout (also counted as failed)
+	ResumedPages   []int // Pages reused from an earlier run (also counted as successful)
+
+	ManifestPath string // Sidecar manifest recording the status of every page
 }
 
 // New creates a new Converter instance using WebAssembly PDFium
@@ -57,14 +139,11 @@ func NewWithPoolSize(poolSize int) (*Converter, error) {
 	}
 
 	// Initialize PDFium pool (WebAssembly - pure Go)
-	// Larger pool helps prevent memory issues with large PDFs
-	pool, err := webassembly.Init(webassembly.Config{
-		MinIdle:  1,
-		MaxIdle:  poolSize,
-		MaxTotal: poolSize,
-	})
+	// The converter keeps one instance for document queries; the other
+	// poolSize instances are leased by Convert to render pages in parallel
+	pool, err := newInstancePool(poolSize + 1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize PDFium pool: %w", err)
+		return nil, err
 	}
 
 	// Get an instance from the pool
@@ -76,15 +155,19 @@ func NewWithPoolSize(poolSize int) (*Converter, error) {
 	return &Converter{
 		pool:     pool,
 		instance: instance,
+		poolSize: poolSize,
 	}, nil
 }
 
 // Close releases resources
 func (c *Converter) Close() error {
 	// Close the instance (returns it to the pool)
+	c.mu.Lock()
 	if c.instance != nil {
 		c.instance.Close()
+		c.instance = nil
 	}
+	c.mu.Unlock()
 	// Close the pool
 	if c.pool != nil {
 		c.pool.Close()
@@ -92,83 +175,69 @@ func (c *Converter) Close() error {
 	return nil
 }
 
-// refreshInstance returns the current instance to the pool and gets a fresh one
-// This helps prevent memory accumulation during processing of large PDFs
-func (c *Converter) refreshInstance() error {
-	if c.instance != nil {
-		c.instance.Close()
-	}
+// sharedInstance returns the converter's instance for document queries,
+// leasing a new one if the previous instance was abandoned
+func (c *Converter) sharedInstance() (pdfium.Pdfium, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Get a fresh instance from the pool
-	instance, err := c.pool.GetInstance(time.Second * 30)
-	if err != nil {
-		return fmt.Errorf("failed to get fresh PDFium instance: %w", err)
+	if c.instance == nil {
+		instance, err := c.pool.GetInstance(time.Second * 30)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PDFium instance: %w", err)
+		}
+		c.instance = instance
 	}
 
-	c.instance = instance
-	return nil
+	return c.instance, nil
 }
 
-// GetPDFInfo returns information about a PDF file
-func (c *Converter) GetPDFInfo(pdfPath string) (map[string]interface{}, error) {
-	if _, err := os.Stat(pdfPath); err != nil {
-		return nil, fmt.Errorf("PDF file not found: %w", err)
-	}
-
-	// Read PDF file into memory (WebAssembly requires bytes, not file paths)
-	pdfBytes, err := os.ReadFile(pdfPath)
+// runContext runs fn on the shared instance and gives up when ctx is done.
+// The abandoned instance is replaced so later calls don't queue behind it,
+// and closed once fn returns.
+func (c *Converter) runContext(ctx context.Context, fn func(instance pdfium.Pdfium) error) error {
+	instance, err := c.sharedInstance()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read PDF file: %w", err)
+		return err
 	}
 
-	// Open document
-	doc, err := c.instance.OpenDocument(&requests.OpenDocument{
-		File: &pdfBytes,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	if ctx.Done() == nil {
+		return fn(instance)
 	}
-	defer c.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
-		Document: doc.Document,
-	})
 
-	// Get page count
-	pageCountReq := &requests.FPDF_GetPageCount{
-		Document: doc.Document,
-	}
-	pageCountRes, err := c.instance.FPDF_GetPageCount(pageCountReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get page count: %w", err)
-	}
-
-	info := map[string]interface{}{
-		"file":       filepath.Base(pdfPath),
-		"pages":      pageCountRes.PageCount,
-		"file_size":  getFileSize(pdfPath),
-	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(instance)
+	}()
 
-	// Try to get first page dimensions
-	if pageCountRes.PageCount > 0 {
-		pageReq := &requests.FPDFPage_GetMediaBox{
-			Page: requests.Page{
-				ByIndex: &requests.PageByIndex{
-					Document: doc.Document,
-					Index:    0,
-				},
-			},
-		}
-		pageRes, err := c.instance.FPDFPage_GetMediaBox(pageReq)
-		if err == nil {
-			info["width"] = pageRes.Right
-			info["height"] = pageRes.Bottom
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		c.mu.Lock()
+		if c.instance == instance {
+			c.instance = nil
+			c.pool.abandon(instance, func() { <-done })
 		}
+		c.mu.Unlock()
+		return ctx.Err()
 	}
-
-	return info, nil
 }
 
 // Convert renders PDF pages to images
 func (c *Converter) Convert(opts *ConvertOptions) (*ConvertResult, error) {
+	return c.ConvertContext(context.Background(), opts)
+}
+
+// ConvertContext renders PDF pages to images and stops between pages when
+// ctx is done. Pages that were not rendered are listed in CancelledPages
+// and the partial result is returned together with the context error.
+//
+// The selected pages are split into contiguous blocks, one per render worker.
+// Each worker leases its own PDFium instance from the pool, so up to
+// MaxPoolSize pages are rendered concurrently. Results are reported in page
+// order regardless of which worker rendered them.
+func (c *Converter) ConvertContext(ctx context.Context, opts *ConvertOptions) (*ConvertResult, error) {
 	// Validate options
 	if err := validateOptions(opts); err != nil {
 		return nil, err
@@ -180,36 +249,30 @@ func (c *Converter) Convert(opts *ConvertOptions) (*ConvertResult, error) {
 		return nil, fmt.Errorf("failed to read PDF file: %w", err)
 	}
 
-	// Open document
-	doc, err := c.instance.OpenDocument(&requests.OpenDocument{
-		File: &pdfBytes,
-	})
+	pageCount, err := c.pageCount(ctx, pdfBytes, opts.Password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open PDF: %w", err)
+		return nil, err
 	}
-	defer c.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
-		Document: doc.Document,
-	})
 
-	// Get page count
-	pageCountReq := &requests.FPDF_GetPageCount{
-		Document: doc.Document,
+	// Resolve the page selection against the document
+	sel, err := pageSelection(opts.Pages, opts.StartPage, opts.EndPage)
+	if err != nil {
+		return nil, err
 	}
-	pageCountRes, err := c.instance.FPDF_GetPageCount(pageCountReq)
+	pages, err := sel.Pages(pageCount)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get page count: %w", err)
+		return nil, err
 	}
 
-	pageCount := pageCountRes.PageCount
-
-	// Determine page range
-	startPage, endPage := determinePageRange(opts.StartPage, opts.EndPage, pageCount)
-
 	result := &ConvertResult{
-		TotalPages:  pageCount,
-		OutputFiles: []string{},
-		Errors:      []string{},
-		WarningPages: []int{},
+		TotalPages:     pageCount,
+		OutputFiles:    []string{},
+		Errors:         []string{},
+		WarningPages:   []int{},
+		CancelledPages: []int{},
+		TimedOutPages:  []int{},
+		ResumedPages:   []int{},
+		ManifestPath:   ManifestPath(opts),
 	}
 
 	// Set DPI
@@ -224,155 +287,473 @@ func (c *Converter) Convert(opts *ConvertOptions) (*ConvertResult, error) {
 		refreshEvery = 50 // Default: refresh every 50 pages
 	}
 
-	// Set pool size for converter
-	poolSize := opts.MaxPoolSize
-	if poolSize <= 0 {
-		poolSize = 2
+	names, err := parseNameTemplate(opts.NameTemplate, opts.Prefix)
+	if err != nil {
+		return nil, err
 	}
 
-	// Track failed pages for retry
-	failedPages := make(map[int]string)
-	pagesProcessed := 0
-
-	_ = poolSize      // Keep the parameter for future use
-
-	// Process pages in chunks to prevent WASM state accumulation
-	// After each chunk, close document and refresh the entire WASM instance
-	chunkSize := refreshEvery
-	if chunkSize <= 0 {
-		chunkSize = 50 // Default chunk size
+	// Keep the pages of an earlier run that are still on disk unchanged in
+	// the manifest, and with Resume don't render them again
+	inputSum := sha256.Sum256(pdfBytes)
+	manifest := Manifest{
+		Version:     manifestVersion,
+		Input:       opts.InputPath,
+		InputSHA256: hex.EncodeToString(inputSum[:]),
+		PageCount:   pageCount,
+		Options:     manifestOptions(opts, dpi),
+	}
+	var completed map[int]ManifestPage
+	previous, err := ReadManifest(result.ManifestPath)
+	switch {
+	case err == nil:
+		if !opts.Resume {
+			// Pages rendered by this run need not be checked
+			rendered := make(map[int]bool, len(pages))
+			for _, pageNum := range pages {
+				rendered[pageNum] = true
+			}
+			previous.Pages = slices.DeleteFunc(previous.Pages, func(page ManifestPage) bool {
+				return rendered[page.Page]
+			})
+		}
+		completed = previous.completedPages(manifest.InputSHA256, manifest.Options, opts.OutputDir)
+	case opts.Resume && !errors.Is(err, fs.ErrNotExist):
+		return nil, err
 	}
 
-	currentPage := startPage
-	for currentPage <= endPage {
-		chunkEnd := currentPage + chunkSize - 1
-		if chunkEnd > endPage {
-			chunkEnd = endPage
+	outcomes := make([]pageOutcome, len(pages))
+	var todo []int // Indexes into pages of the pages to render
+	for i, pageNum := range pages {
+		if page, ok := completed[pageNum]; ok && opts.Resume {
+			outcomes[i] = pageOutcome{outputPath: filepath.Join(opts.OutputDir, page.File), resumed: true}
+			continue
 		}
+		todo = append(todo, i)
+	}
+	renderPages := make([]int, len(todo))
+	for i, index := range todo {
+		renderPages[i] = pages[index]
+	}
+
+	job := &convertJob{
+		pool:      c.pool,
+		opts:      opts,
+		pdfBytes:  pdfBytes,
+		names:     names,
+		pageCount: pageCount,
+		render: RenderOptions{
+			DPI:          dpi,
+			Width:        opts.Width,
+			Height:       opts.Height,
+			MaxPixels:    opts.MaxPixels,
+			PageTimeout:  opts.PageTimeout,
+			RefreshEvery: refreshEvery,
+			Encoding:     opts.Encoding,
+			Password:     opts.Password,
+			ColorMode:    opts.ColorMode,
+			Threshold:    opts.Threshold,
+			Flags:        opts.Flags,
+			Background:   opts.Background,
+			Crop:         opts.Crop,
+			PageBox:      opts.PageBox,
+		},
+		progress: newProgressReporter(opts.Progress, len(renderPages)),
+		manifest: newManifestWriter(result.ManifestPath, manifest, completed),
+		claimed:  map[string]nameClaim{},
+	}
+	for i, pageNum := range pages {
+		if outcomes[i].resumed {
+			page := completed[pageNum]
+			job.claimed[filepath.Clean(page.File)] = nameClaim{page: pageNum, sha256: page.SHA256}
+		}
+	}
 
-		// Render pages in this chunk
-		for pageNum := currentPage; pageNum <= chunkEnd; pageNum++ {
-		// Render page to image
-		pageRender, err := c.instance.RenderPageInDPI(&requests.RenderPageInDPI{
-			DPI: int(dpi),
-			Page: requests.Page{
-				ByIndex: &requests.PageByIndex{
-					Document: doc.Document,
-					Index:    pageNum - 1,
-				},
-			},
-		})
+	var documentPath string
+	if opts.MultiPage {
+		enc, _ := lookupEncoder(opts.Format)
+		documentPath = multiPagePath(opts)
+		if job.document, err = enc.(MultiPageEncoder).NewPageWriter(documentPath, &opts.Encoding); err != nil {
+			return nil, err
+		}
+	}
 
-		if err != nil {
+	// Render disjoint page blocks concurrently, one worker per block
+	rendered := make([]pageOutcome, len(renderPages))
+	var blockErrors [][]string
+	if len(renderPages) > 0 {
+		blocks := splitPages(len(renderPages), c.workerCount(opts.MaxPoolSize, len(renderPages)))
+		blockErrors = make([][]string, len(blocks))
+
+		var wg sync.WaitGroup
+		for i, block := range blocks {
+			wg.Add(1)
+			go func(i int, block [2]int) {
+				defer wg.Done()
+				blockErrors[i] = job.renderBlock(ctx, renderPages[block[0]:block[1]], rendered[block[0]:block[1]])
+			}(i, block)
+		}
+		wg.Wait()
+	}
+
+	// Retry failed pages with reduced DPI (or target size) if requested
+	if opts.RetryFailed && (dpi > 72 || job.render.sized()) && ctx.Err() == nil {
+		retry := job.render
+		retry.DPI = dpi * 0.75 // Reduce DPI by 25%
+		retry.Width = retry.Width * 3 / 4
+		retry.Height = retry.Height * 3 / 4
+		retry.MaxPixels = retry.MaxPixels * 9 / 16
+		job.retryFailed(ctx, &retry, renderPages, rendered)
+	}
+	for i, index := range todo {
+		outcomes[index] = rendered[i]
+	}
+
+	// Merge outcomes in page order
+	for i, pageNum := range pages {
+		outcome := outcomes[i]
+		switch {
+		case outcome.outputPath != "":
+			result.Successful++
+			if job.document == nil {
+				result.OutputFiles = append(result.OutputFiles, outcome.outputPath)
+			}
+			if outcome.resumed {
+				result.ResumedPages = append(result.ResumedPages, pageNum)
+			}
+		case outcome.cancelled:
+			result.CancelledPages = append(result.CancelledPages, pageNum)
+		case outcome.err != "":
 			result.Failed++
-			failedPages[pageNum] = err.Error()
-			result.Errors = append(result.Errors, fmt.Sprintf("Page %d: %v", pageNum, err))
-
-			// Mark pages with WASM errors for potential retry
-			if strings.Contains(err.Error(), "unreachable") || strings.Contains(err.Error(), "wasm") {
+			result.Errors = append(result.Errors, outcome.err)
+			if outcome.warning {
 				result.WarningPages = append(result.WarningPages, pageNum)
 			}
-			continue
+			if outcome.timedOut {
+				result.TimedOutPages = append(result.TimedOutPages, pageNum)
+			}
 		}
+	}
+	for _, errs := range blockErrors {
+		result.Errors = append(result.Errors, errs...)
+	}
 
-		// Clean up render resources
-		if pageRender != nil {
-			defer pageRender.Cleanup()
+	// The manifest is best effort: the images themselves are complete
+	if err := job.manifest.close(); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Error writing manifest: %v", err))
+	}
+
+	// Assemble the multi-page file from whatever pages were rendered
+	if job.document != nil {
+		if err := job.document.Close(); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Error writing %s: %v", documentPath, err))
+			return result, fmt.Errorf("failed to write %s: %w", documentPath, err)
+		}
+		if result.Successful > 0 {
+			result.OutputFiles = append(result.OutputFiles, documentPath)
 		}
+	}
 
-		// Get the image from result
-		if pageRender == nil || pageRender.Result.Image == nil {
-			result.Failed++
-			result.Errors = append(result.Errors, fmt.Sprintf("Page %d: no image generated", pageNum))
-			continue
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("conversion cancelled: %w", err)
+	}
+
+	return result, nil
+}
+
+// convertJob holds the state shared by the render workers of one Convert call
+type convertJob struct {
+	pool     *instancePool
+	opts     *ConvertOptions
+	pdfBytes []byte
+	render   RenderOptions
+	progress *progressReporter
+	document PageWriter // Set when all pages go into one file
+
+	names     *nameTemplate   // Output file names
+	pageCount int             // Pages in the document, for {total}
+	manifest  *manifestWriter // Per-page status for Resume
+
+	namesMu sync.Mutex
+	claimed map[string]nameClaim // Output names given to pages so far
+}
+
+// nameClaim is the page an output name was given to
+type nameClaim struct {
+	page   int
+	sha256 string
+}
+
+// pageOutcome records what happened to a single page during Convert
+type pageOutcome struct {
+	outputPath string // Saved image (or the multi-page file), empty if the page was not rendered
+	err        string // Error as reported in ConvertResult.Errors
+	warning    bool   // Page failed with a WASM/unreachable error
+	timedOut   bool   // Page render exceeded PageTimeout
+	cancelled  bool   // Page was skipped because the context was cancelled
+	resumed    bool   // Page was rendered by an earlier run
+	sha256     string // Hash of the saved image
+	size       int64  // Size of the saved image
+}
+
+// renderBlock renders pages with a dedicated worker, refreshing its
+// instance every refreshEvery pages. Per-page results are written to the
+// matching entries of outcomes; errors that stop the block are returned.
+func (j *convertJob) renderBlock(ctx context.Context, pages []int, outcomes []pageOutcome) []string {
+	if ctx.Err() != nil {
+		markCancelled(outcomes)
+		return nil
+	}
+
+	w, err := newRenderWorker(j.pool, j.pdfBytes, j.render.Password)
+	if err != nil {
+		return []string{fmt.Sprintf("Error starting worker for pages %d-%d: %v", pages[0], pages[len(pages)-1], err)}
+	}
+	defer w.close()
+
+	// Process pages in chunks to prevent WASM state accumulation
+	// After each chunk, close document and refresh the entire WASM instance
+	for chunkStart := 0; chunkStart < len(pages); chunkStart += j.render.RefreshEvery {
+		chunkEnd := min(chunkStart+j.render.RefreshEvery, len(pages))
+
+		for i := chunkStart; i < chunkEnd; i++ {
+			if ctx.Err() != nil {
+				markCancelled(outcomes[i:])
+				return nil
+			}
+
+			outcomes[i] = j.renderAndSave(ctx, w, pages[i], &j.render)
+
+			// A timed out instance was abandoned, continue on a fresh one
+			if outcomes[i].timedOut && i < len(pages)-1 {
+				if err := w.open(); err != nil {
+					return []string{fmt.Sprintf("Error replacing instance after page %d: %v", pages[i], err)}
+				}
+				j.progress.emit(ProgressEvent{Type: InstanceRefreshed})
+			}
+		}
+
+		// After processing chunk, refresh WASM instance to reset state
+		if chunkEnd < len(pages) && ctx.Err() == nil {
+			if err := w.refresh(); err != nil {
+				return []string{fmt.Sprintf("Error refreshing instance after page %d: %v", pages[chunkEnd-1], err)}
+			}
+			j.progress.emit(ProgressEvent{Type: InstanceRefreshed})
 		}
+	}
 
-		// Save image
-		outputPath := filepath.Join(
-			opts.OutputDir,
-			fmt.Sprintf("%s%04d.%s", opts.Prefix, pageNum, opts.Format),
-		)
+	return nil
+}
 
-		if err := saveImage(pageRender.Result.Image, outputPath, opts.Format); err != nil {
-			result.Failed++
-			result.Errors = append(result.Errors, fmt.Sprintf("Page %d save: %v", pageNum, err))
+// markCancelled flags every page in outcomes as skipped by cancellation
+func markCancelled(outcomes []pageOutcome) {
+	for i := range outcomes {
+		outcomes[i] = pageOutcome{cancelled: true}
+	}
+}
+
+// retryFailed re-renders failed pages at a reduced DPI on a fresh worker
+func (j *convertJob) retryFailed(ctx context.Context, render *RenderOptions, pages []int, outcomes []pageOutcome) {
+	var w *renderWorker
+	for i := range outcomes {
+		if outcomes[i].err == "" {
 			continue
 		}
+		if ctx.Err() != nil {
+			return
+		}
+		if w == nil || w.instance == nil {
+			var err error
+			if w, err = newRenderWorker(j.pool, j.pdfBytes, j.render.Password); err != nil {
+				return
+			}
+			defer w.close()
+		}
 
-		result.Successful++
-		result.OutputFiles = append(result.OutputFiles, outputPath)
-		pagesProcessed++
+		j.progress.emit(ProgressEvent{Type: PageRetry, Page: pages[i]})
+		if outcome := j.renderAndSave(ctx, w, pages[i], render); outcome.outputPath != "" {
+			outcomes[i] = outcome
 		}
+	}
+}
 
-		// After processing chunk, close document and refresh WASM instance to reset state
-		if chunkEnd < endPage {
-			// Close current document
-			c.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
-				Document: doc.Document,
-			})
+// renderAndSave renders one page with the given worker and writes it to
+// disk, reporting progress along the way
+func (j *convertJob) renderAndSave(ctx context.Context, w *renderWorker, pageNum int, render *RenderOptions) pageOutcome {
+	j.progress.emit(ProgressEvent{Type: PageStarted, Page: pageNum})
+	started := time.Now()
+
+	outcome := j.renderPage(ctx, w, pageNum, render)
+	switch {
+	case outcome.outputPath != "":
+		ev := ProgressEvent{Type: PageDone, Page: pageNum, Path: outcome.outputPath, Duration: time.Since(started)}
+		// A multi-page file is only written at the end
+		if j.document == nil {
+			ev.Size = outcome.size
+		}
+		j.progress.emit(ev)
+		j.manifest.record(ManifestPage{
+			Page:   pageNum,
+			Status: PageStatusDone,
+			File:   j.relativePath(outcome.outputPath),
+			SHA256: outcome.sha256,
+			Size:   outcome.size,
+		})
+	case outcome.err != "":
+		j.progress.emit(ProgressEvent{Type: PageFailed, Page: pageNum, Error: outcome.err, Duration: time.Since(started)})
+		j.manifest.record(ManifestPage{Page: pageNum, Status: PageStatusFailed, Error: outcome.err})
+	}
 
-			// Refresh the entire WASM instance to clear accumulated state
-			if err := c.refreshInstance(); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Error refreshing instance after page %d: %v", chunkEnd, err))
-				break // Stop processing if we can't refresh
-			}
+	return outcome
+}
 
-			// Reopen document from bytes in the fresh instance
-			newDoc, err := c.instance.OpenDocument(&requests.OpenDocument{
-				File: &pdfBytes,
-			})
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Error reopening document after page %d: %v", chunkEnd, err))
-				break // Stop processing if we can't reopen
-			}
-			doc = newDoc
+// relativePath returns path relative to the output directory
+func (j *convertJob) relativePath(path string) string {
+	if rel, err := filepath.Rel(j.opts.OutputDir, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// renderPage renders one page with the given worker and writes it to disk
+func (j *convertJob) renderPage(ctx context.Context, w *renderWorker, pageNum int, render *RenderOptions) pageOutcome {
+	opts := j.opts
+	img, release, err := w.renderImage(ctx, pageNum, render)
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return pageOutcome{cancelled: true}
+	}
+	if errors.Is(err, ErrPageTimeout) {
+		return pageOutcome{
+			err:      fmt.Sprintf("Page %d: %v after %s", pageNum, err, opts.PageTimeout),
+			timedOut: true,
+		}
+	}
+	if err != nil {
+		return pageOutcome{
+			err: fmt.Sprintf("Page %d: %v", pageNum, err),
+			// Mark pages with WASM errors for potential retry
+			warning: strings.Contains(err.Error(), "unreachable") || strings.Contains(err.Error(), "wasm"),
 		}
+	}
 
-		currentPage = chunkEnd + 1
-	}
-
-	// Retry failed pages with reduced DPI if requested
-	if opts.RetryFailed && len(failedPages) > 0 && dpi > 72 {
-		retryDPI := dpi * 0.75 // Reduce DPI by 25%
-		for pageNum, _ := range failedPages {
-			// Try rendering with reduced DPI
-			pageRender, err := c.instance.RenderPageInDPI(&requests.RenderPageInDPI{
-				DPI: int(retryDPI),
-				Page: requests.Page{
-					ByIndex: &requests.PageByIndex{
-						Document: doc.Document,
-						Index:    pageNum - 1,
-					},
-				},
-			})
+	// Clean up render resources
+	defer release()
+	page := applyColorMode(img, render)
 
-			if err == nil && pageRender != nil && pageRender.Result.Image != nil {
-				outputPath := filepath.Join(
-					opts.OutputDir,
-					fmt.Sprintf("%s%04d.%s", opts.Prefix, pageNum, opts.Format),
-				)
-
-				if err := saveImage(pageRender.Result.Image, outputPath, opts.Format); err == nil {
-					result.Failed--
-					result.Successful++
-					result.OutputFiles = append(result.OutputFiles, outputPath)
-					// Remove from errors list
-					newErrors := []string{}
-					for _, e := range result.Errors {
-						if !strings.Contains(e, fmt.Sprintf("Page %d", pageNum)) {
-							newErrors = append(newErrors, e)
-						}
-					}
-					result.Errors = newErrors
-				}
+	if j.document != nil {
+		if err := j.document.AddPage(pageNum, page); err != nil {
+			return pageOutcome{err: fmt.Sprintf("Page %d encode: %v", pageNum, err)}
+		}
+		return pageOutcome{outputPath: multiPagePath(opts)}
+	}
 
-				// Clean up resources
-				pageRender.Cleanup()
-			}
+	// Save image
+	outcome, err := j.savePage(w, pageNum, page, render)
+	if err != nil {
+		return pageOutcome{err: fmt.Sprintf("Page %d save: %v", pageNum, err)}
+	}
+
+	return outcome
+}
+
+// savePage names a rendered page after the job's template and writes it
+// into the output directory
+func (j *convertJob) savePage(w *renderWorker, pageNum int, img image.Image, render *RenderOptions) (pageOutcome, error) {
+	opts := j.opts
+	values := &nameValues{
+		basename: strings.TrimSuffix(filepath.Base(opts.InputPath), filepath.Ext(opts.InputPath)),
+		ext:      opts.Format,
+		page:     pageNum,
+		total:    j.pageCount,
+		width:    img.Bounds().Dx(),
+		height:   img.Bounds().Dy(),
+		dpi:      render.DPI,
+	}
+	if j.names.uses("label") {
+		values.label = w.pageLabel(pageNum)
+	}
+
+	// Encode first: the hash names the file ({hash}) and goes into the manifest
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, opts.Format, &render.Encoding); err != nil {
+		return pageOutcome{}, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	values.hash = hex.EncodeToString(sum[:])
+
+	name, err := j.names.expand(values)
+	if err != nil {
+		return pageOutcome{}, err
+	}
+	if err := j.claimName(name, pageNum, values.hash); err != nil {
+		return pageOutcome{}, err
+	}
+	outputPath := filepath.Join(opts.OutputDir, name)
+	if dir := filepath.Dir(name); dir != "." {
+		if err := os.MkdirAll(filepath.Join(opts.OutputDir, dir), 0755); err != nil {
+			return pageOutcome{}, fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
-	return result, nil
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return pageOutcome{}, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return pageOutcome{outputPath: outputPath, sha256: values.hash, size: int64(buf.Len())}, nil
+}
+
+// claimName gives an output name to a page. Templates without {page} can
+// repeat a name (two pages with the same {label}); the later page fails
+// instead of overwriting the earlier one, unless both images are identical.
+func (j *convertJob) claimName(name string, pageNum int, sha256 string) error {
+	j.namesMu.Lock()
+	defer j.namesMu.Unlock()
+	name = filepath.Clean(name)
+	if claim, ok := j.claimed[name]; ok && claim.page != pageNum && claim.sha256 != sha256 {
+		return fmt.Errorf("file name %q is already used by page %d; add {page} to the name template", name, claim.page)
+	}
+	j.claimed[name] = nameClaim{page: pageNum, sha256: sha256}
+	return nil
+}
+
+// pageCount opens the document in the shared instance and returns its page count
+func (c *Converter) pageCount(ctx context.Context, pdfBytes []byte, pass string) (int, error) {
+	var pageCount int
+	err := c.runContext(ctx, func(instance pdfium.Pdfium) error {
+		doc, err := openDocument(instance, &pdfBytes, pass)
+		if err != nil {
+			return err
+		}
+		defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: doc.Document,
+		})
+
+		pageCountRes, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: doc.Document,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get page count: %w", err)
+		}
+
+		pageCount = pageCountRes.PageCount
+		return nil
+	})
+
+	return pageCount, err
+}
+
+// workerCount returns how many render workers to use for the given number of
+// pages. requested is ConvertOptions.MaxPoolSize and is capped by the pool size.
+func (c *Converter) workerCount(requested, pages int) int {
+	workers := requested
+	if workers <= 0 || workers > c.poolSize {
+		workers = c.poolSize
+	}
+	if workers > pages {
+		workers = pages
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
 }
 
 // Helper functions
@@ -394,15 +775,65 @@ func validateOptions(opts *ConvertOptions) error {
 		opts.Format = "png"
 	}
 
-	opts.Format = strings.ToLower(opts.Format)
-	if opts.Format != "png" && opts.Format != "jpg" && opts.Format != "jpeg" {
-		return fmt.Errorf("format must be 'png' or 'jpg'")
+	format, err := normalizeFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	opts.Format = format
+
+	if opts.Width < 0 || opts.Height < 0 || opts.MaxPixels < 0 {
+		return fmt.Errorf("width, height and max pixels cannot be negative")
+	}
+
+	if err := opts.Encoding.validate(); err != nil {
+		return err
+	}
+	if err := validateColor(opts.ColorMode, opts.Threshold, &opts.Encoding); err != nil {
+		return err
+	}
+	opts.ColorMode, _ = ParseColorMode(string(opts.ColorMode))
+	opts.Background = formatBackground(format, opts.Background)
+	box, err := ParsePageBox(string(opts.PageBox))
+	if err != nil {
+		return err
+	}
+	opts.PageBox = box
+	if opts.Crop != nil {
+		if err := opts.Crop.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Page numbers are checked against the document once it is loaded
+	if _, err := pageSelection(opts.Pages, opts.StartPage, opts.EndPage); err != nil {
+		return err
+	}
+
+	if opts.MultiPage {
+		enc, _ := lookupEncoder(format)
+		if _, ok := enc.(MultiPageEncoder); !ok {
+			return fmt.Errorf("format %s does not support multi-page output", format)
+		}
+		if opts.Resume {
+			return fmt.Errorf("resume is not supported with multi-page output")
+		}
 	}
 
 	if opts.Prefix == "" {
 		opts.Prefix = "page_"
 	}
 
+	names, err := parseNameTemplate(opts.NameTemplate, opts.Prefix)
+	if err != nil {
+		return err
+	}
+	if !opts.MultiPage && !names.uses("page") && !names.uses("label") && !names.uses("hash") {
+		return fmt.Errorf("name template must contain {page}, {label} or {hash} to keep file names unique")
+	}
+	if names.uses("dpi") && (opts.Width > 0 || opts.Height > 0 || opts.MaxPixels > 0) {
+		return fmt.Errorf("name template can't use {dpi} with width, height or max pixels, which don't render at a fixed DPI")
+	}
+
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
@@ -415,62 +846,76 @@ func validateOptions(opts *ConvertOptions) error {
 	return nil
 }
 
-func determinePageRange(startPage, endPage, totalPages int) (int, int) {
-	if startPage == 0 {
-		startPage = 1
+// pageSelection returns the pages requested either as a selection
+// expression or as the older StartPage/EndPage pair
+func pageSelection(pages string, startPage, endPage int) (pagesel.Selection, error) {
+	if pages == "" {
+		return pagesel.Range(startPage, endPage), nil
 	}
-	if endPage == 0 {
-		endPage = totalPages
+	if startPage != 0 || endPage != 0 {
+		return pagesel.Selection{}, fmt.Errorf("use either a page selection or start/end pages, not both")
 	}
+	return pagesel.Parse(pages)
+}
 
-	if startPage < 1 {
-		startPage = 1
+// splitPages divides total selected pages into n contiguous blocks whose
+// sizes differ by at most one page. Blocks are [from, to) index ranges.
+func splitPages(total, n int) [][2]int {
+	if n > total {
+		n = total
 	}
-	if endPage > totalPages {
-		endPage = totalPages
+	if n < 1 {
+		n = 1
 	}
-	if startPage > endPage {
-		startPage = endPage
+
+	blocks := make([][2]int, 0, n)
+	index := 0
+	for i := 0; i < n; i++ {
+		size := total / n
+		if i < total%n {
+			size++
+		}
+		blocks = append(blocks, [2]int{index, index + size})
+		index += size
 	}
 
-	return startPage, endPage
+	return blocks
 }
 
-func saveImage(img image.Image, path string, format string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
-	}
-	defer file.Close()
-
-	switch format {
-	case "png":
-		if err := png.Encode(file, img); err != nil {
-			return fmt.Errorf("failed to encode PNG: %w", err)
-		}
-	case "jpg", "jpeg":
-		if err := jpeg.Encode(file, img, &jpeg.Options{Quality: 90}); err != nil {
-			return fmt.Errorf("failed to encode JPEG: %w", err)
-		}
+// normalizeFormat lower-cases an output format and checks that it is registered
+func normalizeFormat(format string) (string, error) {
+	format = strings.ToLower(format)
+	if _, ok := lookupEncoder(format); !ok {
+		return "", fmt.Errorf("format must be one of: %s", strings.Join(Formats(), ", "))
 	}
+	return format, nil
+}
 
-	return nil
+// multiPagePath returns the single output file used when opts.MultiPage is set
+func multiPagePath(opts *ConvertOptions) string {
+	name := strings.TrimSuffix(filepath.Base(opts.InputPath), filepath.Ext(opts.InputPath))
+	return filepath.Join(opts.OutputDir, name+"."+opts.Format)
 }
 
-func getFileSize(path string) string {
-	info, err := os.Stat(path)
-	if err != nil {
-		return "unknown"
+// encodeImage writes img to out with the encoder registered for format
+func encodeImage(out io.Writer, img image.Image, format string, opts *EncodeOptions) error {
+	enc, ok := lookupEncoder(format)
+	if !ok {
+		return fmt.Errorf("unsupported format %q", format)
 	}
 
-	size := info.Size()
-	units := []string{"B", "KB", "MB", "GB"}
-	divisor := 1.0
-
-	for i := 0; i < len(units) && size > 1024; i++ {
-		size = size / 1024
-		divisor = divisor * 1024
+	var err error
+	if ce, ok := enc.(ConfigurableEncoder); ok {
+		err = ce.EncodeWith(out, img, opts)
+	} else {
+		if opts.Palette {
+			img = imgenc.Quantize(img, 256)
+		}
+		err = enc.Encode(out, img)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", strings.ToUpper(format), err)
 	}
 
-	return fmt.Sprintf("%.2f %s", float64(info.Size())/divisor, units[int(divisor/1024)])
+	return nil
 }
@@ -1,9 +1,30 @@
 package converter
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/responses"
+	"github.com/tu-usuario/pdf2img/internal/testpdf"
+	"golang.org/x/image/tiff"
 )
 
 // TestValidateOptions tests the options validation
@@ -41,6 +62,53 @@ func TestValidateOptions(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "multi-page format without page writer",
+			opts: &ConvertOptions{
+				InputPath: "test.pdf",
+				OutputDir: "/tmp",
+				Format:    "png",
+				MultiPage: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "name template without page",
+			opts: &ConvertOptions{
+				InputPath:    "test.pdf",
+				OutputDir:    "/tmp",
+				NameTemplate: "{basename}.{ext}",
+			},
+			wantErr: true,
+		},
+		{
+			name: "dpi in name template with width",
+			opts: &ConvertOptions{
+				InputPath:    "test.pdf",
+				OutputDir:    "/tmp",
+				Width:        800,
+				NameTemplate: "{page}_{dpi}dpi.{ext}",
+			},
+			wantErr: true,
+		},
+		{
+			name: "prefix escaping output directory",
+			opts: &ConvertOptions{
+				InputPath: "test.pdf",
+				OutputDir: "/tmp",
+				Prefix:    "../page_",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid page selection",
+			opts: &ConvertOptions{
+				InputPath: "test.pdf",
+				OutputDir: "/tmp",
+				Pages:     "1-x",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -53,69 +121,1854 @@ func TestValidateOptions(t *testing.T) {
 	}
 }
 
-// TestDeterminePageRange tests page range determination
-func TestDeterminePageRange(t *testing.T) {
+// TestPageSelection tests page selection from Pages or StartPage/EndPage
+func TestPageSelection(t *testing.T) {
 	tests := []struct {
 		name      string
+		pages     string
 		startPage int
 		endPage   int
 		totalPage int
-		want      [2]int
+		want      []int
+		wantErr   bool
 	}{
 		{
 			name:      "all pages",
-			startPage: 0,
-			endPage:   0,
-			totalPage: 10,
-			want:      [2]int{1, 10},
+			totalPage: 5,
+			want:      []int{1, 2, 3, 4, 5},
 		},
 		{
 			name:      "specific range",
 			startPage: 2,
 			endPage:   5,
 			totalPage: 10,
-			want:      [2]int{2, 5},
+			want:      []int{2, 3, 4, 5},
 		},
 		{
 			name:      "end page beyond total",
 			startPage: 5,
 			endPage:   20,
 			totalPage: 10,
-			want:      [2]int{5, 10},
+			wantErr:   true,
 		},
 		{
 			name:      "start page greater than end",
 			startPage: 10,
 			endPage:   5,
 			totalPage: 15,
-			want:      [2]int{5, 5},
+			wantErr:   true,
+		},
+		{
+			name:      "selection expression",
+			pages:     "1-2,last 2,!5",
+			totalPage: 6,
+			want:      []int{1, 2, 6},
+		},
+		{
+			name:      "selection and range together",
+			pages:     "odd",
+			startPage: 2,
+			totalPage: 6,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := pageSelection(tt.pages, tt.startPage, tt.endPage)
+			var got []int
+			if err == nil {
+				got, err = sel.Pages(tt.totalPage)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pageSelection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pageSelection() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSplitPages tests distribution of pages across render workers
+func TestSplitPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		workers int
+		want    [][2]int
+	}{
+		{
+			name:    "single worker",
+			total:   10,
+			workers: 1,
+			want:    [][2]int{{0, 10}},
+		},
+		{
+			name:    "even split",
+			total:   10,
+			workers: 2,
+			want:    [][2]int{{0, 5}, {5, 10}},
+		},
+		{
+			name:    "uneven split",
+			total:   7,
+			workers: 3,
+			want:    [][2]int{{0, 3}, {3, 5}, {5, 7}},
+		},
+		{
+			name:    "more workers than pages",
+			total:   2,
+			workers: 8,
+			want:    [][2]int{{0, 1}, {1, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitPages(tt.total, tt.workers)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitPages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNameTemplate tests output file name templates
+func TestNameTemplate(t *testing.T) {
+	values := &nameValues{
+		basename: "report",
+		ext:      "png",
+		label:    "iv",
+		hash:     "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		page:     7,
+		total:    23,
+		width:    1240,
+		height:   1754,
+		dpi:      150,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		values   *nameValues
+		want     string
+		wantErr  bool
+	}{
+		{name: "default", template: "", want: "page_0007.png"},
+		{name: "default past 9999 pages", template: "", values: &nameValues{ext: "png", page: 7, total: 12000}, want: "page_00007.png"},
+		{name: "padded page and dpi", template: "{basename}_{page:05}_{dpi}dpi.{ext}", want: "report_00007_150dpi.png"},
+		{name: "all fields", template: "{label}-{total}-{width}x{height}-{hash:8}.{ext}", want: "iv-23-1240x1754-01234567.png"},
+		{name: "default hash length", template: "{hash}.{ext}", want: "0123456789abcdef.png"},
+		{name: "subdirectory", template: "{basename}/{page:3}.{ext}", want: filepath.Join("report", "007.png")},
+		{name: "literal braces", template: "{{{page:1}}}.{ext}", want: "{7}.png"},
+		{name: "missing label", template: "{label}.{ext}", values: &nameValues{ext: "jpg", page: 3, total: 5}, want: "3.jpg"},
+		{name: "label with slashes", template: "{label}.{ext}", values: &nameValues{ext: "png", label: "../a/b", page: 1, total: 1}, want: ".._a_b.png"},
+		{name: "dot label", template: "{label}", values: &nameValues{label: "..", page: 1, total: 1}, want: "__"},
+		{name: "parent directory", template: "../{page}.{ext}", wantErr: true},
+		{name: "absolute path", template: "/tmp/{page}.{ext}", wantErr: true},
+		{name: "unknown placeholder", template: "{pagenum}.{ext}", wantErr: true},
+		{name: "width on text", template: "{basename:5}_{page}", wantErr: true},
+		{name: "bad width", template: "{page:x}", wantErr: true},
+		{name: "unclosed brace", template: "{page", wantErr: true},
+		{name: "unmatched brace", template: "page}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseNameTemplate(tt.template, "page_")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNameTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			v := tt.values
+			if v == nil {
+				v = values
+			}
+			got, err := tmpl.expand(v)
+			if err != nil {
+				t.Fatalf("expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClaimName tests that a page can't take a name another page saved to
+func TestClaimName(t *testing.T) {
+	j := &convertJob{claimed: map[string]nameClaim{}}
+	tests := []struct {
+		name    string
+		file    string
+		page    int
+		sha256  string
+		wantErr bool
+	}{
+		{name: "new name", file: "iv.png", page: 4, sha256: "a"},
+		{name: "same page again", file: "iv.png", page: 4, sha256: "b"},
+		{name: "other page", file: "iv.png", page: 9, sha256: "c", wantErr: true},
+		{name: "other page, same image", file: "./iv.png", page: 9, sha256: "b"},
+		{name: "other name", file: "v.png", page: 9, sha256: "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := j.claimName(tt.file, tt.page, tt.sha256)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("claimName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestManifestResume tests which pages of a previous run are reused
+func TestManifestResume(t *testing.T) {
+	dir := t.TempDir()
+	options := ManifestOptions{Format: "png", DPI: 150, Prefix: "page_", NameTemplate: DefaultNameTemplate}
+
+	files := map[string]string{"page_0001.png": "one", "page_0002.png": "two", "page_0003.png": "three"}
+	var pages []ManifestPage
+	for i, name := range []string{"page_0001.png", "page_0002.png", "page_0003.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(files[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum, size, err := fileSHA256(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, ManifestPage{Page: i + 1, Status: PageStatusDone, File: name, SHA256: sum, Size: size})
+	}
+	pages = append(pages,
+		ManifestPage{Page: 4, Status: PageStatusFailed, Error: "Page 4: unreachable"},
+		ManifestPage{Page: 5, Status: PageStatusDone, File: "page_0005.png", SHA256: "missing"},
+		ManifestPage{Page: 6, Status: PageStatusDone, File: "../page_0006.png"},
+	)
+
+	// Page 2 changed on disk since the manifest was written
+	if err := os.WriteFile(filepath.Join(dir, "page_0002.png"), []byte("TWO"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "doc.pdf2img.json")
+	w := newManifestWriter(path, Manifest{Version: manifestVersion, InputSHA256: "abc", PageCount: 6, Options: options}, nil)
+	for i := len(pages) - 1; i >= 0; i-- {
+		w.record(pages[i])
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	m, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if !reflect.DeepEqual(m.Pages, pages) {
+		t.Errorf("pages = %+v, want %+v", m.Pages, pages)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		options ManifestOptions
+		want    []int
+	}{
+		{name: "same run", input: "abc", options: options, want: []int{1, 3}},
+		{name: "different input", input: "def", options: options},
+		{name: "different options", input: "abc", options: ManifestOptions{Format: "png", DPI: 300, Prefix: "page_", NameTemplate: DefaultNameTemplate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for page := range m.completedPages(tt.input, tt.options, dir) {
+				got = append(got, page)
+			}
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("completedPages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWorkerCount tests that parallelism is capped by pool size and page count
+func TestWorkerCount(t *testing.T) {
+	c := &Converter{poolSize: 4}
+
+	tests := []struct {
+		requested int
+		pages     int
+		want      int
+	}{
+		{requested: 0, pages: 100, want: 4},
+		{requested: 2, pages: 100, want: 2},
+		{requested: 16, pages: 100, want: 4},
+		{requested: 4, pages: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := c.workerCount(tt.requested, tt.pages); got != tt.want {
+			t.Errorf("workerCount(%d, %d) = %d, want %d", tt.requested, tt.pages, got, tt.want)
+		}
+	}
+}
+
+// TestPageSizeInPixels tests target-size and pixel-cap computations
+func TestPageSizeInPixels(t *testing.T) {
+	const a4W, a4H = 595.0, 842.0
+
+	tests := []struct {
+		name       string
+		w, h       float64
+		opts       RenderOptions
+		wantWidth  int
+		wantHeight int
+		wantOK     bool
+	}{
+		{name: "dpi only", w: a4W, h: a4H, opts: RenderOptions{DPI: 150}},
+		{name: "cap not reached", w: a4W, h: a4H, opts: RenderOptions{DPI: 150, MaxPixels: 10_000_000}},
+		{name: "fixed width", w: a4W, h: a4H, opts: RenderOptions{Width: 1000}, wantWidth: 1000, wantHeight: 1415, wantOK: true},
+		{name: "fixed height", w: a4W, h: a4H, opts: RenderOptions{Height: 1000}, wantWidth: 707, wantHeight: 1000, wantOK: true},
+		{name: "fit portrait in box", w: a4W, h: a4H, opts: RenderOptions{Width: 1000, Height: 1000}, wantWidth: 707, wantHeight: 1000, wantOK: true},
+		{name: "fit landscape in box", w: a4H, h: a4W, opts: RenderOptions{Width: 1000, Height: 1000}, wantWidth: 1000, wantHeight: 707, wantOK: true},
+		{name: "A0 drawing in same box", w: 2384, h: 3370, opts: RenderOptions{Width: 1000, Height: 1000}, wantWidth: 707, wantHeight: 1000, wantOK: true},
+		{name: "cap at dpi", w: a4W, h: a4H, opts: RenderOptions{DPI: 300, MaxPixels: 1_000_000}, wantWidth: 841, wantHeight: 1189, wantOK: true},
+		{name: "cap with width", w: a4W, h: a4H, opts: RenderOptions{Width: 4000, MaxPixels: 1_000_000}, wantWidth: 841, wantHeight: 1189, wantOK: true},
+		{name: "empty page", w: 0, h: a4H, opts: RenderOptions{Width: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, ok := pageSizeInPixels(tt.w, tt.h, &tt.opts)
+			if ok != tt.wantOK || width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("pageSizeInPixels() = %d, %d, %v, want %d, %d, %v",
+					width, height, ok, tt.wantWidth, tt.wantHeight, tt.wantOK)
+			}
+			if ok && tt.opts.MaxPixels > 0 && width*height > tt.opts.MaxPixels {
+				t.Errorf("%dx%d exceeds MaxPixels %d", width, height, tt.opts.MaxPixels)
+			}
+		})
+	}
+}
+
+// TestNormalizeFormat tests format lookup in the encoder registry
+func TestNormalizeFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "png", want: "png"},
+		{format: "JPG", want: "jpg"},
+		{format: "jpeg", want: "jpeg"},
+		{format: "WebP", want: "webp"},
+		{format: "tiff", want: "tiff"},
+		{format: "tif", want: "tif"},
+		{format: "bmp", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeFormat(tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeFormat(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+// TestEncodeOptionsValidate tests encoder option ranges
+func TestEncodeOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    EncodeOptions
+		wantErr bool
+	}{
+		{name: "defaults", opts: EncodeOptions{}},
+		{name: "thumbnail", opts: EncodeOptions{Quality: 60, PNGCompression: "best", Palette: true}},
+		{name: "quality too high", opts: EncodeOptions{Quality: 101}, wantErr: true},
+		{name: "negative quality", opts: EncodeOptions{Quality: -1}, wantErr: true},
+		{name: "unknown compression", opts: EncodeOptions{PNGCompression: "max"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestEncodeImagePalette tests that Palette produces an 8-bit paletted PNG
+func TestEncodeImagePalette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+
+	for _, palette := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := encodeImage(&buf, img, "png", &EncodeOptions{Palette: palette}); err != nil {
+			t.Fatalf("encodeImage() error = %v", err)
+		}
+		decoded, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("png.Decode() error = %v", err)
+		}
+		if _, paletted := decoded.(*image.Paletted); paletted != palette {
+			t.Errorf("Palette %v: decoded %T", palette, decoded)
+		}
+	}
+}
+
+// TestValidateColor tests color mode, threshold and palette checks
+func TestValidateColor(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      ColorMode
+		threshold int
+		palette   bool
+		wantErr   bool
+	}{
+		{name: "defaults"},
+		{name: "gray", mode: "gray"},
+		{name: "grayscale alias", mode: "Grayscale"},
+		{name: "bilevel with threshold", mode: ColorModeBilevel, threshold: 200},
+		{name: "dither", mode: ColorModeDither},
+		{name: "color palette", mode: ColorModeColor, palette: true},
+		{name: "unknown mode", mode: "sepia", wantErr: true},
+		{name: "threshold too high", mode: ColorModeBilevel, threshold: 256, wantErr: true},
+		{name: "palette with gray", mode: ColorModeGray, palette: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateColor(tt.mode, tt.threshold, &EncodeOptions{Palette: tt.palette})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateColor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestApplyColorMode tests that reduced color modes are written as 8-bit
+// gray and 1-bit PNG files
+func TestApplyColorMode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i / 4)
+		if i%4 == 3 {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	tests := []struct {
+		mode      ColorMode
+		wantModel color.Model
+		wantDepth byte // Bit depth in the PNG header
+	}{
+		{mode: ColorModeColor, wantModel: color.RGBAModel, wantDepth: 8},
+		{mode: ColorModeGray, wantModel: color.GrayModel, wantDepth: 8},
+		{mode: ColorModeBilevel, wantDepth: 1},
+		{mode: ColorModeDither, wantDepth: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			page := applyColorMode(img, &RenderOptions{ColorMode: tt.mode})
+			if tt.wantModel != nil && page.ColorModel() != tt.wantModel {
+				t.Errorf("color model = %T", page.ColorModel())
+			}
+
+			var buf bytes.Buffer
+			if err := encodeImage(&buf, page, "png", &EncodeOptions{}); err != nil {
+				t.Fatalf("encodeImage() error = %v", err)
+			}
+			// IHDR follows the 8-byte signature, length and type: width,
+			// height, then the bit depth
+			if depth := buf.Bytes()[24]; depth != tt.wantDepth {
+				t.Errorf("PNG bit depth = %d, want %d", depth, tt.wantDepth)
+			}
+		})
+	}
+}
+
+// TestParseRenderFlags tests parsing render flag lists and formatting them
+func TestParseRenderFlags(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    RenderFlags
+		wantStr string
+		wantErr bool
+	}{
+		{list: ""},
+		{list: "annotations", want: RenderFlags{Annotations: true}, wantStr: "annotations"},
+		{list: " Forms , no-smooth-text,", want: RenderFlags{Forms: true, NoSmoothText: true}, wantStr: "forms,no_smooth_text"},
+		{list: "no_smooth_path,print,lcd_text", want: RenderFlags{Print: true, LCDText: true, NoSmoothPath: true}, wantStr: "print,lcd_text,no_smooth_path"},
+		{list: "annotations,reverse_byte_order", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseRenderFlags(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRenderFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRenderFlags() = %+v, want %+v", got, tt.want)
+			}
+			if s := got.String(); s != tt.wantStr {
+				t.Errorf("String() = %q, want %q", s, tt.wantStr)
+			}
+		})
+	}
+}
+
+// TestRenderFlags tests mapping render options to PDFium flags
+func TestRenderFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RenderOptions
+		want enums.FPDF_RENDER_FLAG
+	}{
+		{name: "none"},
+		{name: "forms only", opts: RenderOptions{Flags: RenderFlags{Forms: true}}},
+		{
+			name: "annotations and print",
+			opts: RenderOptions{Flags: RenderFlags{Annotations: true, Print: true}},
+			want: enums.FPDF_RENDER_FLAG_ANNOT | enums.FPDF_RENDER_FLAG_PRINTING,
+		},
+		{
+			name: "no smoothing",
+			opts: RenderOptions{Flags: RenderFlags{NoSmoothText: true, NoSmoothImage: true, NoSmoothPath: true}},
+			want: enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHTEXT | enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHIMAGE | enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHPATH,
+		},
+		{
+			name: "gray with lcd text",
+			opts: RenderOptions{ColorMode: ColorModeGray, Flags: RenderFlags{LCDText: true}},
+			want: enums.FPDF_RENDER_FLAG_LCD_TEXT | enums.FPDF_RENDER_FLAG_GRAYSCALE,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			start, end := determinePageRange(tt.startPage, tt.endPage, tt.totalPage)
-			if start != tt.want[0] || end != tt.want[1] {
-				t.Errorf("determinePageRange() = [%d, %d], want [%d, %d]", start, end, tt.want[0], tt.want[1])
+			if got := renderFlags(&tt.opts); got != tt.want {
+				t.Errorf("renderFlags() = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseBackground tests parsing background colors and formatting them
+// for the manifest
+func TestParseBackground(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    color.Color
+		wantStr string
+		wantErr bool
+	}{
+		{s: ""},
+		{s: "transparent", want: color.NRGBA{}, wantStr: "transparent"},
+		{s: "#F5F5F5", want: color.NRGBA{R: 0xf5, G: 0xf5, B: 0xf5, A: 0xff}, wantStr: "#f5f5f5"},
+		{s: "#0f8", want: color.NRGBA{G: 0xff, B: 0x88, A: 0xff}, wantStr: "#00ff88"},
+		{s: "#ff000080", want: color.NRGBA{R: 0xff, A: 0x80}, wantStr: "#ff000080"},
+		{s: "f5f5f5", wantErr: true},
+		{s: "#f5f5f", wantErr: true},
+		{s: "#gggggg", wantErr: true},
+		{s: "white", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseBackground(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBackground() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBackground() = %v, want %v", got, tt.want)
+			}
+			if s := backgroundString(got); s != tt.wantStr {
+				t.Errorf("backgroundString() = %q, want %q", s, tt.wantStr)
 			}
 		})
 	}
 }
 
-// TestFileSize tests file size formatting
-func TestGetFileSize(t *testing.T) {
-	// Create a temporary test file
-	tmpDir := t.TempDir()
-	testFile := filepath.Join(tmpDir, "test.txt")
+// TestFormatBackground tests that JPEG output gets an opaque background
+func TestFormatBackground(t *testing.T) {
+	translucent := color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x80}
+	tests := []struct {
+		format string
+		bg     color.Color
+		want   color.Color
+	}{
+		{format: "png", bg: nil, want: nil},
+		{format: "png", bg: color.NRGBA{}, want: color.NRGBA{}},
+		{format: "webp", bg: translucent, want: translucent},
+		{format: "jpg", bg: nil, want: nil},
+		{format: "jpg", bg: color.NRGBA{}, want: nil},
+		{format: "jpeg", bg: translucent, want: color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}},
+	}
+
+	for _, tt := range tests {
+		if got := formatBackground(tt.format, tt.bg); got != tt.want {
+			t.Errorf("formatBackground(%q, %v) = %v, want %v", tt.format, tt.bg, got, tt.want)
+		}
+	}
+}
+
+// TestBGRAToRGBA tests copying PDFium bitmaps with straight alpha
+func TestBGRAToRGBA(t *testing.T) {
+	// 3x1 pixels: opaque red, half transparent blue, transparent; 4 bytes
+	// of row padding
+	bgra := []byte{0, 0, 255, 255, 255, 0, 0, 128, 9, 9, 9, 0, 7, 7, 7, 7}
+	img := bgraToRGBA(bgra, 3, 1, 16)
+	want := []byte{255, 0, 0, 255, 0, 0, 128, 128, 0, 0, 0, 0}
+	if !bytes.Equal(img.Pix, want) {
+		t.Errorf("pixels = %v, want %v", img.Pix, want)
+	}
+}
+
+// TestFlattenOnWhite tests that transparent pages are written to JPEG on
+// white instead of black
+func TestFlattenOnWhite(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	if got := flattenOnWhite(img).At(0, 0); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("flattened pixel = %v, want white", got)
+	}
+	opaque := image.NewGray(image.Rect(0, 0, 16, 16))
+	if got := flattenOnWhite(opaque); got != image.Image(opaque) {
+		t.Error("opaque image was copied")
+	}
 
-	// Create file with 1024 bytes
-	err := os.WriteFile(testFile, make([]byte, 1024), 0644)
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, "jpg", &EncodeOptions{}); err != nil {
+		t.Fatalf("encodeImage() error = %v", err)
+	}
+	decoded, err := jpeg.Decode(&buf)
 	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+		t.Fatalf("jpeg.Decode() error = %v", err)
 	}
+	if r, _, _, _ := decoded.At(8, 8).RGBA(); r>>8 < 250 {
+		t.Errorf("JPEG pixel red = %d, want white", r>>8)
+	}
+}
 
-	size := getFileSize(testFile)
-	if size == "unknown" {
-		t.Errorf("getFileSize() returned 'unknown'")
+// TestParseRegion tests parsing crop regions in points and fractions
+func TestParseRegion(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    *Region
+		wantErr bool
+	}{
+		{s: ""},
+		{s: "72, 144, 200.5, 100", want: &Region{X: 72, Y: 144, Width: 200.5, Height: 100}},
+		{s: "0,0.5,1,0.5", want: &Region{Y: 0.5, Width: 1, Height: 0.5, Fraction: true}},
+		{s: "0,0,1,2", want: &Region{Width: 1, Height: 2}},
+		{s: "1,2,3", wantErr: true},
+		{s: "a,b,c,d", wantErr: true},
+		{s: "-1,0,10,10", wantErr: true},
+		{s: "0,0,0,10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseRegion(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRegion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRegion() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBitmapLayout tests where cropped pages land in the bitmap
+func TestBitmapLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		box     Rect // Page box in displayed points (default: the page)
+		opts    RenderOptions
+		want    bitmapRender
+		wantErr bool
+	}{
+		{
+			name: "whole page",
+			opts: RenderOptions{DPI: 144},
+			want: bitmapRender{width: 1224, height: 1584, sizeX: 1224, sizeY: 1584},
+		},
+		{
+			name: "points",
+			opts: RenderOptions{DPI: 144, Crop: &Region{X: 72, Y: 36, Width: 144, Height: 72}},
+			want: bitmapRender{width: 288, height: 144, startX: -144, startY: -72, sizeX: 1224, sizeY: 1584},
+		},
+		{
+			name: "bottom right quarter",
+			opts: RenderOptions{DPI: 72, Crop: &Region{X: 0.5, Y: 0.5, Width: 0.5, Height: 0.5, Fraction: true}},
+			want: bitmapRender{width: 306, height: 396, startX: -306, startY: -396, sizeX: 612, sizeY: 792},
+		},
+		{
+			name: "clipped to the page",
+			opts: RenderOptions{DPI: 72, Crop: &Region{X: 600, Y: 700, Width: 100, Height: 100}},
+			want: bitmapRender{width: 12, height: 92, startX: -600, startY: -700, sizeX: 612, sizeY: 792},
+		},
+		{
+			name: "width sizes the region",
+			opts: RenderOptions{DPI: 72, Width: 1000, Crop: &Region{Width: 306, Height: 396}},
+			want: bitmapRender{width: 1000, height: 1294, sizeX: 2000, sizeY: 2588},
+		},
+		{
+			name:    "outside the page",
+			opts:    RenderOptions{DPI: 72, Crop: &Region{X: 612, Width: 10, Height: 10}},
+			wantErr: true,
+		},
+		{
+			name: "bleed around the page",
+			box:  Rect{X0: -9, Y0: -9, X1: 621, Y1: 801},
+			opts: RenderOptions{DPI: 144},
+			want: bitmapRender{width: 1260, height: 1620, startX: 18, startY: 18, sizeX: 1224, sizeY: 1584},
+		},
+		{
+			name: "region of the trim box",
+			box:  Rect{X0: 36, Y0: 36, X1: 576, Y1: 756},
+			opts: RenderOptions{DPI: 72, Crop: &Region{X: 0.5, Y: 0, Width: 0.5, Height: 0.5, Fraction: true}},
+			want: bitmapRender{width: 270, height: 360, startX: -306, startY: -36, sizeX: 612, sizeY: 792},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := tt.box
+			if box == (Rect{}) {
+				box = Rect{X1: 612, Y1: 792}
+			}
+			got, err := bitmapLayout(612, 792, box, &tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bitmapLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("bitmapLayout() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParsePageBox tests page box names
+func TestParsePageBox(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    PageBox
+		wantErr bool
+	}{
+		{in: "", want: PageBoxCrop},
+		{in: "media", want: PageBoxMedia},
+		{in: "Bleed", want: PageBoxBleed},
+		{in: "TrimBox", want: PageBoxTrim},
+		{in: " art ", want: PageBoxArt},
+		{in: "box", wantErr: true},
+		{in: "paper", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePageBox(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePageBox(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePageBox(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRectToPoints tests placing page boxes on the displayed page, which
+// starts at the top left of the rotated crop box
+func TestRectToPoints(t *testing.T) {
+	crop := Rect{X0: 20, Y0: 20, X1: 380, Y1: 180}  // 360 x 160 points
+	bleed := Rect{X0: 10, Y0: 15, X1: 390, Y1: 185} // 10 and 5 points around it
+
+	tests := []struct {
+		name     string
+		rotation int
+		want     Rect
+	}{
+		{name: "upright", rotation: 0, want: Rect{X0: -10, Y0: -5, X1: 370, Y1: 165}},
+		{name: "90", rotation: 1, want: Rect{X0: -5, Y0: -10, X1: 165, Y1: 370}},
+		{name: "180", rotation: 2, want: Rect{X0: -10, Y0: -5, X1: 370, Y1: 165}},
+		{name: "270", rotation: 3, want: Rect{X0: -5, Y0: -10, X1: 165, Y1: 370}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &pageTransform{box: crop, rotation: tt.rotation, width: 360, height: 160}
+			if got := tr.rectToPoints(bleed); got != tt.want {
+				t.Errorf("rectToPoints() = %+v, want %+v", got, tt.want)
+			}
+			if got := tr.rectToPoints(crop); got.X0 != 0 || got.Y0 != 0 {
+				t.Errorf("rectToPoints(crop box) = %+v, want it at 0, 0", got)
+			}
+		})
+	}
+}
+
+// TestPageBoxSet tests selecting a boundary of a page
+func TestPageBoxSet(t *testing.T) {
+	s := &pageBoxSet{
+		media: Rect{X1: 300, Y1: 300},
+		crop:  Rect{X0: 20, Y0: 20, X1: 280, Y1: 280},
+		bleed: Rect{X0: 10, Y0: 10, X1: 290, Y1: 290},
+		trim:  Rect{X0: 20, Y0: 20, X1: 280, Y1: 280},
+		art:   Rect{X0: 50, Y0: 50, X1: 100, Y1: 100},
+	}
+	tests := map[PageBox]Rect{
+		"":           s.crop,
+		PageBoxCrop:  s.crop,
+		PageBoxMedia: s.media,
+		PageBoxBleed: s.bleed,
+		PageBoxTrim:  s.trim,
+		PageBoxArt:   s.art,
+		"BleedBox":   s.bleed,
+	}
+	for box, want := range tests {
+		if got := s.get(box); got != want {
+			t.Errorf("get(%q) = %+v, want %+v", box, got, want)
+		}
+	}
+	if customBox("") || customBox(PageBoxCrop) || !customBox(PageBoxBleed) {
+		t.Errorf("customBox() reports the crop box as custom or the bleed box as not")
+	}
+}
+
+func TestTIFFPageWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.tiff")
+	w, err := tiffEncoder{}.NewPageWriter(path, &EncodeOptions{})
+	if err != nil {
+		t.Fatalf("NewPageWriter() error = %v", err)
+	}
+
+	for _, pageNum := range []int{3, 1, 2} {
+		img := image.NewGray(image.Rect(0, 0, 10*pageNum, 10))
+		if err := w.AddPage(pageNum, img); err != nil {
+			t.Fatalf("AddPage(%d) error = %v", pageNum, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// The first page decoded must be page 1
+	cfg, err := tiff.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("DecodeConfig() error = %v", err)
+	}
+	if cfg.Width != 10 {
+		t.Errorf("first page width = %d, want 10", cfg.Width)
+	}
+
+	// Only the output file is left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("output directory has %d entries, want 1", len(entries))
+	}
+}
+
+// TestCleanText tests the normalization of text returned by PDFium
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "Hello world", want: "Hello world"},
+		{name: "crlf", in: "one\r\ntwo\r\n", want: "one\ntwo\n"},
+		{name: "lone cr", in: "one\rtwo", want: "one\ntwo"},
+		{name: "markers", in: "hyphen\x02ated\ufffe text\x00", want: "hyphenated text"},
+		{name: "tabs kept", in: "a\tb", want: "a\tb"},
+		{name: "unicode", in: "Señor © 2016", want: "Señor © 2016"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanText(tt.in); got != tt.want {
+				t.Errorf("cleanText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTruncateText tests per-page truncation by characters
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            string
+		maxChars      int
+		want          string
+		wantTruncated bool
+	}{
+		{name: "no limit", in: "abcdef", maxChars: 0, want: "abcdef"},
+		{name: "shorter", in: "abc", maxChars: 5, want: "abc"},
+		{name: "exact", in: "abcde", maxChars: 5, want: "abcde"},
+		{name: "longer", in: "abcdef", maxChars: 4, want: "abcd", wantTruncated: true},
+		{name: "multibyte", in: "ñañaña", maxChars: 3, want: "ñañ", wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateText(tt.in, tt.maxChars)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncateText(%q, %d) = %q, %v, want %q, %v", tt.in, tt.maxChars, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+// TestPageTransform tests the mapping from PDF user space to image pixels
+// for every page rotation and for renders of part of the page or of a
+// larger page box
+func TestPageTransform(t *testing.T) {
+	box := Rect{X0: 10, Y0: 20, X1: 210, Y1: 120} // 200 x 100 points
+
+	tests := []struct {
+		name     string
+		rotation int
+		area     *Rect // Rendered area at 4 pixels per point (nil = whole page at 2)
+		x, y     float64
+		wantX    float64
+		wantY    float64
+	}{
+		{name: "upright top left", rotation: 0, x: 10, y: 120, wantX: 0, wantY: 0},
+		{name: "upright bottom right", rotation: 0, x: 210, y: 20, wantX: 400, wantY: 200},
+		{name: "upright inside", rotation: 0, x: 60, y: 70, wantX: 100, wantY: 100},
+		{name: "90 bottom left", rotation: 1, x: 10, y: 20, wantX: 0, wantY: 0},
+		{name: "90 top right", rotation: 1, x: 210, y: 120, wantX: 200, wantY: 400},
+		{name: "180 bottom left", rotation: 2, x: 10, y: 20, wantX: 400, wantY: 0},
+		{name: "180 top right", rotation: 2, x: 210, y: 120, wantX: 0, wantY: 200},
+		{name: "270 bottom left", rotation: 3, x: 10, y: 20, wantX: 200, wantY: 400},
+		{name: "270 top right", rotation: 3, x: 210, y: 120, wantX: 0, wantY: 0},
+		{name: "crop", area: &Rect{X0: 50, Y0: 25, X1: 150, Y1: 75}, x: 60, y: 70, wantX: 0, wantY: 100},
+		{name: "larger box", area: &Rect{X0: -10, Y0: -10, X1: 210, Y1: 110}, x: 10, y: 120, wantX: 40, wantY: 40},
+		{name: "90 crop", rotation: 1, area: &Rect{X0: 50, Y0: 100, X1: 100, Y1: 200}, x: 110, y: 70, wantX: 0, wantY: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &pageTransform{box: box, rotation: tt.rotation, width: 200, height: 100, area: Rect{X1: 200, Y1: 100}, pixelWidth: 400, pixelHeight: 200}
+			if tt.rotation%2 == 1 {
+				tr.width, tr.height, tr.area, tr.pixelWidth, tr.pixelHeight = 100, 200, Rect{X1: 100, Y1: 200}, 200, 400
+			}
+			if a := tt.area; a != nil {
+				tr.area, tr.pixelWidth, tr.pixelHeight = *a, int(4*(a.X1-a.X0)), int(4*(a.Y1-a.Y0))
+			}
+			x, y := tr.pixel(tt.x, tt.y)
+			if x != tt.wantX || y != tt.wantY {
+				t.Errorf("pixel(%v, %v) = %v, %v, want %v, %v", tt.x, tt.y, x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+// TestRenderSize tests that layout pixel sizes match the rendered images
+func TestRenderSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		widthPt    float64
+		heightPt   float64
+		opts       RenderOptions
+		wantWidth  int
+		wantHeight int
+	}{
+		// 792 * (150/72) is a hair above 1650 in floating point, PDFium rounds it up
+		{name: "dpi", widthPt: 612, heightPt: 792, opts: RenderOptions{DPI: 150}, wantWidth: 1275, wantHeight: 1651},
+		{name: "fractional dpi", widthPt: 612, heightPt: 792, opts: RenderOptions{DPI: 97.5}, wantWidth: 825, wantHeight: 1067},
+		{name: "width", widthPt: 450, heightPt: 648, opts: RenderOptions{Width: 1000}, wantWidth: 1000, wantHeight: 1440},
+		{name: "max pixels refit", widthPt: 450, heightPt: 500, opts: RenderOptions{DPI: 150, MaxPixels: 300000}, wantWidth: 519, wantHeight: 576},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := renderSize(tt.widthPt, tt.heightPt, &tt.opts)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("renderSize() = %dx%d, want %dx%d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+// TestGroupText tests grouping characters into words, lines and blocks
+func TestGroupText(t *testing.T) {
+	// char places a 5pt wide character of a 10pt font with its baseline at y
+	char := func(text string, x, y float64) TextChar {
+		return TextChar{Text: text, FontName: "Helvetica", FontSize: 10, Box: Box{Points: Rect{X0: x, Y0: y, X1: x + 5, Y1: y + 8}}}
+	}
+	word := func(text string, x, y float64) []TextChar {
+		var chars []TextChar
+		for i, r := range text {
+			chars = append(chars, char(string(r), x+float64(i)*5, y))
+		}
+		return chars
+	}
+	newline := TextChar{Text: "\n"}
+
+	var chars []TextChar
+	chars = append(chars, word("Hello", 10, 700)...)
+	chars = append(chars, char(" ", 35, 700))
+	chars = append(chars, word("world", 40, 700)...)
+	chars = append(chars, word("12", 400, 700)...) // Far right: its own line and block
+	chars = append(chars, TextChar{Text: "\r"}, newline)
+	chars = append(chars, word("second", 10, 688)...) // Next line of the first block
+	chars = append(chars, newline)
+	chars = append(chars, word("13", 400, 688)...) // Continues the right-hand block
+	chars = append(chars, newline)
+	chars = append(chars, word("far", 10, 600)...) // Paragraph below
+
+	blocks := groupBlocks(groupLines(chars))
+
+	var texts []string
+	for _, block := range blocks {
+		texts = append(texts, block.Text)
+	}
+	want := []string{"Hello world\nsecond", "12\n13", "far"}
+	if !reflect.DeepEqual(texts, want) {
+		t.Fatalf("blocks = %q, want %q", texts, want)
+	}
+
+	first := blocks[0]
+	if got := first.Points; got != (Rect{X0: 10, Y0: 688, X1: 65, Y1: 708}) {
+		t.Errorf("first block box = %+v", got)
+	}
+	if len(first.Lines) != 2 || len(first.Lines[0].Words) != 2 {
+		t.Fatalf("first block lines = %+v", first.Lines)
+	}
+	if w := first.Lines[0].Words[1]; w.Text != "world" || w.FontName != "Helvetica" || w.FontSize != 10 || len(w.Chars) != 5 {
+		t.Errorf("second word = %+v", w)
+	}
+
+	layout := &PageLayout{Blocks: blocks}
+	trimLayout(layout, 1)
+	if len(layout.Blocks[0].Lines) != 2 || layout.Blocks[0].Lines[0].Words != nil {
+		t.Errorf("trimLayout(lines) kept words: %+v", layout.Blocks[0].Lines[0])
+	}
+}
+
+// TestLayoutDepth tests layout level names
+func TestLayoutDepth(t *testing.T) {
+	for level, want := range map[string]int{"blocks": 0, "lines": 1, "": 2, "words": 2, "Chars": 3} {
+		if got, err := layoutDepth(level); err != nil || got != want {
+			t.Errorf("layoutDepth(%q) = %d, %v, want %d", level, got, err, want)
+		}
+	}
+	if _, err := layoutDepth("paragraphs"); err == nil {
+		t.Error("layoutDepth(\"paragraphs\") should fail")
+	}
+}
+
+// TestCompileQuery tests plain text and regular expression queries
+func TestCompileQuery(t *testing.T) {
+	tests := []struct {
+		query         string
+		regex         bool
+		caseSensitive bool
+		text          string
+		want          []string
+		wantErr       bool
+	}{
+		{query: "lease term", text: "The Lease Term ends", want: []string{"Lease Term"}},
+		{query: "  lease\nterm ", text: "lease term", want: []string{"lease term"}},
+		{query: "Lease", caseSensitive: true, text: "lease Lease", want: []string{"Lease"}},
+		{query: "a.b", text: "a.b axb", want: []string{"a.b"}},
+		{query: `\d{4}`, regex: true, text: "in 1978 and 2015", want: []string{"1978", "2015"}},
+		{query: "(", regex: true, wantErr: true},
+		{query: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		re, err := compileQuery(tt.query, tt.regex, tt.caseSensitive)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("compileQuery(%q) expected error", tt.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("compileQuery(%q) unexpected error: %v", tt.query, err)
+			continue
+		}
+		if got := re.FindAllString(tt.text, -1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("compileQuery(%q) matches %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestSearchHit tests matching across a line break, snippets and hit boxes
+func TestSearchHit(t *testing.T) {
+	// char places a 5pt wide character of a 10pt font with its baseline at y
+	char := func(text string, x, y float64) TextChar {
+		return TextChar{Text: text, FontSize: 10, Box: Box{Points: Rect{X0: x, Y0: y, X1: x + 5, Y1: y + 8}}}
+	}
+	var chars []TextChar
+	for i, r := range "see the lease" {
+		chars = append(chars, char(string(r), 10+float64(i)*5, 700))
+	}
+	chars = append(chars, TextChar{Text: "\r"}, TextChar{Text: "\n"})
+	for i, r := range "term ends" {
+		chars = append(chars, char(string(r), 10+float64(i)*5, 688))
+	}
+
+	text := newSearchText(chars)
+	if want := "see the lease term ends"; text.text != want {
+		t.Fatalf("search text = %q, want %q", text.text, want)
+	}
+
+	re, _ := compileQuery("LEASE TERM", false, false)
+	m := re.FindStringIndex(text.text)
+	hit := text.hit(chars, m[0], m[1], 4)
+	if hit.Text != "lease term" {
+		t.Errorf("hit text = %q", hit.Text)
+	}
+	if want := "…the lease term end…"; hit.Snippet != want {
+		t.Errorf("snippet = %q, want %q", hit.Snippet, want)
+	}
+	want := []Rect{{X0: 50, Y0: 700, X1: 75, Y1: 708}, {X0: 10, Y0: 688, X1: 30, Y1: 696}}
+	if len(hit.Boxes) != len(want) {
+		t.Fatalf("got %d boxes, want %d", len(hit.Boxes), len(want))
+	}
+	for i, box := range hit.Boxes {
+		if box.Points != want[i] {
+			t.Errorf("box %d = %+v, want %+v", i, box.Points, want[i])
+		}
+	}
+}
+
+// TestImagePlacement tests image placement through nested form matrices
+func TestImagePlacement(t *testing.T) {
+	tests := []struct {
+		name  string
+		image matrix
+		forms []matrix // Innermost first
+		want  Rect
+	}{
+		{
+			name:  "page level",
+			image: matrix{a: 200, d: 100, e: 50, f: 600},
+			want:  Rect{X0: 50, Y0: 600, X1: 250, Y1: 700},
+		},
+		{
+			name:  "rotated 90 degrees",
+			image: matrix{b: 200, c: -100, e: 300, f: 100},
+			want:  Rect{X0: 200, Y0: 100, X1: 300, Y1: 300},
+		},
+		{
+			name:  "inside scaled and moved forms",
+			image: matrix{a: 10, d: 10},
+			forms: []matrix{{a: 2, d: 2, e: 5, f: 5}, {a: 1, d: 1, e: 100, f: 200}},
+			want:  Rect{X0: 105, Y0: 205, X1: 125, Y1: 225},
+		},
+	}
+
+	for _, tt := range tests {
+		m := tt.image
+		for _, form := range tt.forms {
+			m = m.then(form)
+		}
+		if got := roundRect(m.unitSquare()); got != tt.want {
+			t.Errorf("%s: placement = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestBitmapToImage tests conversion of PDFium bitmap buffers
+func TestBitmapToImage(t *testing.T) {
+	// 2x1 pixels with 2 bytes of row padding
+	bgr := []byte{0, 0, 255, 255, 0, 0, 9, 9}
+	img, err := bitmapToImage(bgr, 2, 1, 8, enums.FPDF_BITMAP_FORMAT_BGR)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := img.At(0, 0).(color.NRGBA); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("pixel 0 = %v, want red", got)
+	}
+	if got := img.At(1, 0).(color.NRGBA); got != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("pixel 1 = %v, want blue", got)
+	}
+
+	gray, err := bitmapToImage([]byte{10, 20, 0, 0, 30, 40, 0, 0}, 2, 2, 4, enums.FPDF_BITMAP_FORMAT_GRAY)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := gray.(*image.Gray).Pix; !bytes.Equal(got, []byte{10, 20, 30, 40}) {
+		t.Errorf("gray pixels = %v", got)
+	}
+
+	if _, err := bitmapToImage(bgr, 2, 2, 8, enums.FPDF_BITMAP_FORMAT_BGR); err == nil {
+		t.Error("expected error for a buffer shorter than the bitmap")
+	}
+	if _, err := bitmapToImage(bgr, 2, 1, 8, enums.FPDF_BITMAP_FORMAT_UNKNOWN); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
+// TestNativeFormat tests which images are written without decoding
+func TestNativeFormat(t *testing.T) {
+	tests := []struct {
+		filters []string
+		want    string
+	}{
+		{[]string{"DCTDecode"}, "jpg"},
+		{[]string{"JPXDecode"}, "jp2"},
+		{[]string{"FlateDecode"}, ""},
+		{[]string{"FlateDecode", "DCTDecode"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := nativeFormat(tt.filters); got != tt.want {
+			t.Errorf("nativeFormat(%v) = %q, want %q", tt.filters, got, tt.want)
+		}
+	}
+}
+
+// TestFormatSize tests file size formatting
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.00 KB"},
+		{1536, "1.50 KB"},
+		{5 * 1024 * 1024, "5.00 MB"},
+		{3 << 30, "3.00 GB"},
+		{2 << 40, "2.00 TB"},
+		{2048 << 40, "2048.00 TB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.size); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+// TestIsLinearized tests detection of the linearization dictionary
+func TestIsLinearized(t *testing.T) {
+	linearized := "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<</Linearized 1/L 5120/O 3/E 2048/N 1/T 4096/H [512 128]>>\nendobj\n"
+	late := "%PDF-1.7\n" + strings.Repeat(" ", linearizedWindow) + "<</Linearized 1>>"
+
+	tests := []struct {
+		name string
+		pdf  string
+		want bool
+	}{
+		{"linearized", linearized, true},
+		{"plain", "%PDF-1.4\n1 0 obj\n<</Type/Catalog/Pages 2 0 R>>\nendobj\n", false},
+		{"beyond the header", late, false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLinearized([]byte(tt.pdf)); got != tt.want {
+				t.Errorf("isLinearized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPermissionNames tests naming the user permissions
+func TestPermissionNames(t *testing.T) {
+	perms := &responses.FPDF_GetDocUserPermissions{
+		PrintDocument:                       true,
+		CopyOrExtractText:                   true,
+		FillInExistingInteractiveFormFields: true,
+	}
+	want := []string{"print", "copy", "fill_forms"}
+	if got := permissionNames(perms); !slices.Equal(got, want) {
+		t.Errorf("permissionNames() = %v, want %v", got, want)
+	}
+
+	if got := permissionNames(&responses.FPDF_GetDocUserPermissions{}); got == nil || len(got) != 0 {
+		t.Errorf("permissionNames() with no permissions = %#v, want an empty list", got)
+	}
+}
+
+// TestSetEndPages tests the page ranges of outline sections
+func TestSetEndPages(t *testing.T) {
+	outline := []OutlineItem{
+		{Title: "Preface", Level: 1, Page: 2},
+		{Title: "Part 1", Level: 1, Page: 5, Children: []OutlineItem{
+			{Title: "1.1", Level: 2, Page: 5},
+			{Title: "1.2", Level: 2, Page: 5},
+			{Title: "Website", Level: 2, URI: "https://example.com"},
+			{Title: "1.3", Level: 2, Page: 9},
+		}},
+		{Title: "Part 2", Level: 1, Page: 12},
+	}
+	setEndPages(outline, 20)
+
+	tests := []struct {
+		item *OutlineItem
+		want int
+	}{
+		{&outline[0], 4},
+		{&outline[1], 11},
+		{&outline[1].Children[0], 8}, // Sections on the same page end with the next one
+		{&outline[1].Children[1], 8},
+		{&outline[1].Children[2], 0},
+		{&outline[1].Children[3], 11},
+		{&outline[2], 20},
+	}
+	for _, tt := range tests {
+		if tt.item.EndPage != tt.want {
+			t.Errorf("%s: EndPage = %d, want %d", tt.item.Title, tt.item.EndPage, tt.want)
+		}
+	}
+}
+
+// TestSetEndPagesNested tests sections whose children or next entry
+// share a page with them
+func TestSetEndPagesNested(t *testing.T) {
+	outline := []OutlineItem{
+		{Title: "1", Level: 1, Page: 5, Children: []OutlineItem{
+			{Title: "1.1", Level: 2, Page: 6, Children: []OutlineItem{
+				{Title: "Windows", Level: 3, Page: 8},
+				{Title: "OSX", Level: 3, Page: 9},
+			}},
+			{Title: "1.2", Level: 2, Page: 9, midPage: true, Children: []OutlineItem{
+				{Title: "Windows", Level: 3, Page: 10, midPage: true},
+				{Title: "OSX", Level: 3, Page: 12},
+			}},
+			{Title: "1.3", Level: 2, Page: 12, midPage: true},
+			{Title: "1.4", Level: 2, Page: 14, midPage: true},
+		}},
+		{Title: "2", Level: 1, Page: 16},
+	}
+	setEndPages(outline, 20)
+
+	tests := []struct {
+		item *OutlineItem
+		want int
+	}{
+		{&outline[0], 15},
+		{&outline[0].Children[0], 9},              // Up to its last child
+		{&outline[0].Children[0].Children[0], 8},  // The next entry starts at the top of page 9
+		{&outline[0].Children[0].Children[1], 9},  // 1.2 starts further down the same page
+		{&outline[0].Children[1], 12},             // 1.3 starts mid-page
+		{&outline[0].Children[1].Children[0], 11}, // OSX starts at the top
+		{&outline[0].Children[1].Children[1], 12},
+		{&outline[0].Children[2], 14},
+		{&outline[0].Children[3], 15},
+		{&outline[1], 20},
+	}
+	for _, tt := range tests {
+		if tt.item.EndPage != tt.want {
+			t.Errorf("%s p. %d: EndPage = %d, want %d", tt.item.Title, tt.item.Page, tt.item.EndPage, tt.want)
+		}
+	}
+}
+
+// TestSetDestPages tests taking pages from the matching bookmarks
+func TestSetDestPages(t *testing.T) {
+	outline := []OutlineItem{
+		{Title: "Action", Page: 3},
+		{Title: "Dest", Children: []OutlineItem{{Title: "Child"}}},
+		{Title: "Unresolved"},
+	}
+	bookmarks := []responses.GetBookmarksBookmark{
+		{DestInfo: &responses.DestInfo{PageIndex: 7}},
+		{DestInfo: &responses.DestInfo{PageIndex: 1}, Children: []responses.GetBookmarksBookmark{
+			{DestInfo: &responses.DestInfo{PageIndex: 4}},
+		}},
+		{DestInfo: &responses.DestInfo{PageIndex: -1}},
+	}
+	setDestPages(outline, bookmarks, nil)
+
+	got := []int{outline[0].Page, outline[1].Page, outline[1].Children[0].Page, outline[2].Page}
+	if want := []int{3, 2, 5, 0}; !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+// TestAnnotationFilter tests selecting annotation types
+func TestAnnotationFilter(t *testing.T) {
+	all, err := annotationFilter(nil)
+	if err != nil {
+		t.Fatalf("annotationFilter(nil) error = %v", err)
+	}
+	if !all("Highlight") || all("Popup") {
+		t.Errorf("default filter: Highlight = %v, Popup = %v, want true, false", all("Highlight"), all("Popup"))
+	}
+
+	some, err := annotationFilter([]string{"text", "POPUP"})
+	if err != nil {
+		t.Fatalf("annotationFilter() error = %v", err)
+	}
+	if !some("Text") || !some("Popup") || some("Link") {
+		t.Errorf("filter for text and popup: Text = %v, Popup = %v, Link = %v", some("Text"), some("Popup"), some("Link"))
+	}
+
+	if _, err := annotationFilter([]string{"note"}); err == nil {
+		t.Errorf("annotationFilter() with an unknown type: expected an error")
+	}
+}
+
+// TestHexColor tests formatting annotation colors
+func TestHexColor(t *testing.T) {
+	tests := []struct {
+		components []float64
+		want       string
+	}{
+		{[]float64{1, 1, 0}, "#ffff00"},
+		{[]float64{0.5}, "#808080"},
+		{[]float64{0, 1, 1, 0}, "#ff0000"},
+		{[]float64{0, 0, 0, 1}, "#000000"},
+		{[]float64{1.5, -1, 0}, "#ff0000"},
+		{nil, ""},
+		{[]float64{1, 0}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := hexColor(tt.components); got != tt.want {
+			t.Errorf("hexColor(%v) = %q, want %q", tt.components, got, tt.want)
+		}
+	}
+}
+
+// testPDF builds a PDF with one page per width, 200 points tall. Page n is
+// painted a different shade of gray, so every page renders differently.
+func testPDF(widths ...int) []byte {
+	kids := make([]string, len(widths))
+	for i := range widths {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+2*i)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(widths)),
+	}
+	for i, width := range widths {
+		content := fmt.Sprintf("%.2f g 0 0 %d 200 re f", float64(i+1)/float64(len(widths)+1), width)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d 200] /Contents %d 0 R >>", width, 4+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	return testpdf.Build(objects, "")
+}
+
+// writeTestPDF writes testPDF(widths...) to a temporary file
+func writeTestPDF(t *testing.T, widths ...int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, testPDF(widths...), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// TestConvertParallel tests that rendering with several workers, refreshing
+// their instances every few pages, gives the same files, in the same page
+// order, as a single worker
+func TestConvertParallel(t *testing.T) {
+	c, err := NewWithPoolSize(3)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+
+	// The crop region misses the narrow even pages, which fail
+	input := writeTestPDF(t, 400, 200, 400, 200, 400, 200, 400, 400)
+	convert := func(workers int) (*ConvertResult, string) {
+		dir := t.TempDir()
+		result, err := c.Convert(&ConvertOptions{
+			InputPath:    input,
+			OutputDir:    dir,
+			DPI:          72,
+			MaxPoolSize:  workers,
+			RefreshEvery: 2,
+			Crop:         &Region{X: 250, Width: 100, Height: 100},
+		})
+		if err != nil {
+			t.Fatalf("Convert() with %d workers error = %v", workers, err)
+		}
+		return result, dir
+	}
+
+	single, singleDir := convert(1)
+	parallel, parallelDir := convert(3)
+
+	wantFiles := []string{"page_0001.png", "page_0003.png", "page_0005.png", "page_0007.png", "page_0008.png"}
+	for _, r := range []struct {
+		result *ConvertResult
+		dir    string
+	}{{single, singleDir}, {parallel, parallelDir}} {
+		var files []string
+		for _, path := range r.result.OutputFiles {
+			files = append(files, filepath.Base(path))
+		}
+		if !slices.Equal(files, wantFiles) {
+			t.Errorf("OutputFiles = %v, want %v", files, wantFiles)
+		}
+		if r.result.Successful != 5 || r.result.Failed != 3 {
+			t.Errorf("Successful, Failed = %d, %d, want 5, 3", r.result.Successful, r.result.Failed)
+		}
+	}
+
+	if !slices.Equal(single.Errors, parallel.Errors) {
+		t.Errorf("Errors with 3 workers = %v, want %v", parallel.Errors, single.Errors)
+	}
+	for i, pageNum := range []int{2, 4, 6} {
+		if i >= len(parallel.Errors) || !strings.HasPrefix(parallel.Errors[i], fmt.Sprintf("Page %d:", pageNum)) {
+			t.Errorf("Errors = %v, want pages 2, 4 and 6 in order", parallel.Errors)
+			break
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, name := range wantFiles {
+		a, errA := os.ReadFile(filepath.Join(singleDir, name))
+		b, errB := os.ReadFile(filepath.Join(parallelDir, name))
+		if errA != nil || errB != nil {
+			t.Fatalf("ReadFile(%s) errors = %v, %v", name, errA, errB)
+		}
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s differs between 1 and 3 workers", name)
+		}
+		if seen[string(a)] {
+			t.Errorf("%s has the same image as another page", name)
+		}
+		seen[string(a)] = true
+	}
+}
+
+// TestConvertCancelled tests stopping a conversion through its context,
+// before it starts and after the first page
+func TestConvertCancelled(t *testing.T) {
+	c, err := NewWithPoolSize(2)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	input := writeTestPDF(t, 200, 200, 200, 200)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := c.ConvertContext(cancelled, &ConvertOptions{InputPath: input, OutputDir: t.TempDir(), DPI: 72})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ConvertContext() with a cancelled context error = %v, want context.Canceled", err)
+	}
+	if result != nil && (result.Successful != 0 || len(result.CancelledPages) != 4) {
+		t.Errorf("Successful = %d, CancelledPages = %v, want 0, all pages", result.Successful, result.CancelledPages)
+	}
+
+	// One worker renders the pages in order, so cancelling after the first
+	// leaves the others
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result, err = c.ConvertContext(ctx, &ConvertOptions{
+		InputPath:   input,
+		OutputDir:   t.TempDir(),
+		DPI:         72,
+		MaxPoolSize: 1,
+		Progress: func(ev ProgressEvent) {
+			if ev.Type == PageDone {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ConvertContext() cancelled after a page error = %v, want context.Canceled", err)
+	}
+	if result == nil || result.Successful != 1 || !slices.Equal(result.CancelledPages, []int{2, 3, 4}) {
+		t.Fatalf("result = %+v, want page 1 done and pages 2-4 cancelled", result)
+	}
+
+	assertConverterWorks(t, c, input)
+}
+
+// TestConvertPageTimeout tests that pages exceeding PageTimeout are
+// reported, and that their abandoned instances are replaced
+func TestConvertPageTimeout(t *testing.T) {
+	c, err := NewWithPoolSize(2)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	input := writeTestPDF(t, 200, 200, 200, 200)
+
+	result, err := c.Convert(&ConvertOptions{InputPath: input, OutputDir: t.TempDir(), DPI: 72, PageTimeout: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if result.Failed != 4 || !slices.Equal(result.TimedOutPages, []int{1, 2, 3, 4}) {
+		t.Errorf("Failed = %d, TimedOutPages = %v, want 4, all pages", result.Failed, result.TimedOutPages)
+	}
+	for _, msg := range result.Errors {
+		if !strings.Contains(msg, ErrPageTimeout.Error()) {
+			t.Errorf("error %q does not report the timeout", msg)
+		}
+	}
+
+	assertConverterWorks(t, c, input)
+}
+
+// TestConvertHungPages tests that renders that never return don't use up
+// the pool: there are more of them than the pool has instances
+func TestConvertHungPages(t *testing.T) {
+	hang := make(chan struct{})
+	renderPageFunc = func(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT, pageNum int, opts *RenderOptions) renderResult {
+		if pageNum <= 3 && opts.PageTimeout > 0 {
+			<-hang
+			return renderResult{err: errors.New("released")}
+		}
+		return renderPage(instance, doc, pageNum, opts)
+	}
+	defer func() { renderPageFunc = renderPage }()
+	defer close(hang)
+
+	c, err := NewWithPoolSize(1)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	input := writeTestPDF(t, 200, 200, 200, 200, 200)
+
+	result, err := c.Convert(&ConvertOptions{InputPath: input, OutputDir: t.TempDir(), DPI: 72, PageTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if result.Successful != 2 || !slices.Equal(result.TimedOutPages, []int{1, 2, 3}) {
+		t.Errorf("Successful = %d, TimedOutPages = %v, want 2, pages 1-3; errors: %v", result.Successful, result.TimedOutPages, result.Errors)
+	}
+
+	assertConverterWorks(t, c, input)
+}
+
+// assertConverterWorks converts and renders input, failing t if c no
+// longer can
+func assertConverterWorks(t *testing.T, c *Converter, input string) {
+	t.Helper()
+	result, err := c.Convert(&ConvertOptions{InputPath: input, OutputDir: t.TempDir(), DPI: 72})
+	if err != nil || result.Successful != result.TotalPages {
+		t.Fatalf("Convert() afterwards: result = %+v, error = %v", result, err)
+	}
+	pdf, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if _, err := c.RenderPage(context.Background(), pdf, 1, &RenderOptions{DPI: 72}); err != nil {
+		t.Fatalf("RenderPage() afterwards error = %v", err)
+	}
+}
+
+// TestRenderPage tests rendering and encoding single pages in memory
+func TestRenderPage(t *testing.T) {
+	c, err := NewWithPoolSize(1)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	pdf := testPDF(100, 200, 300)
+	ctx := context.Background()
+
+	img, err := c.RenderPage(ctx, pdf, 2, &RenderOptions{DPI: 72})
+	if err != nil {
+		t.Fatalf("RenderPage() error = %v", err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(200, 200) {
+		t.Errorf("RenderPage() size = %v, want 200x200", got)
+	}
+
+	for _, pageNum := range []int{0, -1, 4} {
+		if _, err := c.RenderPage(ctx, pdf, pageNum, nil); err == nil {
+			t.Errorf("RenderPage(%d) expected an error", pageNum)
+		}
+		if err := c.EncodePage(ctx, io.Discard, pdf, pageNum, "png", nil); err == nil {
+			t.Errorf("EncodePage(%d) expected an error", pageNum)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := c.EncodePage(ctx, &buf, pdf, 3, "jpg", &RenderOptions{DPI: 36}); err != nil {
+		t.Fatalf("EncodePage() error = %v", err)
+	}
+	encoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("EncodePage() wrote an invalid JPEG: %v", err)
+	}
+	if got := encoded.Bounds().Size(); got != image.Pt(150, 100) {
+		t.Errorf("EncodePage() size = %v, want 150x100", got)
+	}
+	if err := c.EncodePage(ctx, io.Discard, pdf, 1, "bmp", nil); err == nil {
+		t.Errorf("EncodePage() with an unknown format expected an error")
+	}
+}
+
+// TestRenderPages tests the order of RenderPages, its errors, and that
+// stopping early hands the instance back to the pool
+func TestRenderPages(t *testing.T) {
+	c, err := NewWithPoolSize(1)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	pdf := testPDF(100, 200, 300, 400, 500)
+	ctx := context.Background()
+
+	var pages, widths []int
+	for page, err := range c.RenderPages(ctx, pdf, "odd", &RenderOptions{DPI: 72, RefreshEvery: 2}) {
+		if err != nil {
+			t.Fatalf("RenderPages() page %d error = %v", page.Page, err)
+		}
+		pages = append(pages, page.Page)
+		widths = append(widths, page.Image.Bounds().Dx())
+	}
+	if !slices.Equal(pages, []int{1, 3, 5}) || !slices.Equal(widths, []int{100, 300, 500}) {
+		t.Errorf("RenderPages() pages = %v, widths = %v, want 1, 3, 5 at 100, 300, 500", pages, widths)
+	}
+
+	var errs int
+	for _, err := range c.RenderPages(ctx, pdf, "7", nil) {
+		if err == nil {
+			t.Errorf("RenderPages() of a page beyond the document yielded a page")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("RenderPages() of a page beyond the document yielded %d errors, want 1", errs)
+	}
+
+	pages = nil
+	for page, err := range c.RenderPages(ctx, pdf, "", &RenderOptions{DPI: 36}) {
+		if err != nil {
+			t.Fatalf("RenderPages() page %d error = %v", page.Page, err)
+		}
+		pages = append(pages, page.Page)
+		if len(pages) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(pages, []int{1, 2}) {
+		t.Errorf("RenderPages() before break = %v, want 1, 2", pages)
+	}
+
+	// The pool has a single render instance: it is only available again if
+	// the iterator closed its document and returned the instance
+	instance, err := c.pool.GetInstance(5 * time.Second)
+	if err != nil {
+		t.Fatalf("instance not returned to the pool after break: %v", err)
+	}
+	instance.Close()
+
+	if _, err := c.RenderPage(ctx, pdf, 4, nil); err != nil {
+		t.Errorf("RenderPage() after break error = %v", err)
+	}
+}
+
+// TestRenderRegion tests rendering a region in memory and encoding it
+func TestRenderRegion(t *testing.T) {
+	c, err := NewWithPoolSize(1)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	pdf := testPDF(400)
+	ctx := context.Background()
+
+	img, err := c.RenderRegion(ctx, pdf, 1, Region{X: 100, Y: 50, Width: 200, Height: 100}, &RenderOptions{DPI: 144})
+	if err != nil {
+		t.Fatalf("RenderRegion() error = %v", err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(400, 200) {
+		t.Errorf("RenderRegion() size = %v, want 400x200", got)
+	}
+	if _, err := c.RenderRegion(ctx, pdf, 1, Region{X: 500, Y: 0, Width: 10, Height: 10}, nil); err == nil {
+		t.Errorf("RenderRegion() outside the page expected an error")
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, img, "PNG", nil); err != nil {
+		t.Fatalf("EncodeImage() error = %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("EncodeImage() wrote an invalid PNG: %v", err)
+	}
+	if got := decoded.Bounds().Size(); got != image.Pt(400, 200) {
+		t.Errorf("EncodeImage() size = %v, want 400x200", got)
+	}
+	if err := EncodeImage(io.Discard, img, "bmp", nil); err == nil {
+		t.Errorf("EncodeImage() with an unknown format expected an error")
+	}
+	if err := EncodeImage(io.Discard, img, "jpg", &EncodeOptions{Quality: 101}); err == nil {
+		t.Errorf("EncodeImage() with an invalid quality expected an error")
+	}
+}
+
+// TestConvertManifest tests that every conversion keeps the manifest, with
+// the permissions of the images, so that a later run can resume it
+func TestConvertManifest(t *testing.T) {
+	c, err := NewWithPoolSize(1)
+	if err != nil {
+		t.Fatalf("NewWithPoolSize() error = %v", err)
+	}
+	defer c.Close()
+	input := writeTestPDF(t, 100, 200, 300)
+	dir := t.TempDir()
+
+	// Runs without Resume keep the pages of earlier runs in the manifest
+	for _, pages := range []string{"1-2", "3"} {
+		if _, err := c.Convert(&ConvertOptions{InputPath: input, OutputDir: dir, DPI: 36, Pages: pages}); err != nil {
+			t.Fatalf("Convert(%q) error = %v", pages, err)
+		}
+	}
+	opts := &ConvertOptions{InputPath: input, OutputDir: dir, DPI: 36}
+	m, err := ReadManifest(ManifestPath(opts))
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	var done []int
+	for _, page := range m.Pages {
+		if page.Status == PageStatusDone {
+			done = append(done, page.Page)
+		}
+	}
+	if !slices.Equal(done, []int{1, 2, 3}) {
+		t.Errorf("manifest pages done = %v, want 1, 2, 3", done)
+	}
+
+	manifest, err := os.Stat(ManifestPath(opts))
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	page, err := os.Stat(filepath.Join(dir, "page_0001.png"))
+	if err != nil {
+		t.Fatalf("image not written: %v", err)
+	}
+	if manifest.Mode().Perm() != page.Mode().Perm() {
+		t.Errorf("manifest mode = %v, want %v like the images", manifest.Mode().Perm(), page.Mode().Perm())
+	}
+
+	opts.Resume = true
+	result, err := c.Convert(opts)
+	if err != nil {
+		t.Fatalf("Convert() with Resume error = %v", err)
+	}
+	if !slices.Equal(result.ResumedPages, []int{1, 2, 3}) {
+		t.Errorf("Convert() with Resume ResumedPages = %v, want 1, 2, 3", result.ResumedPages)
 	}
 }
@@ -0,0 +1,287 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tu-usuario/pdf2img/pkg/imgenc"
+)
+
+// Encoder writes a rendered page in one output format
+type Encoder interface {
+	Encode(w io.Writer, img image.Image) error
+}
+
+// EncoderFunc adapts an ordinary function to the Encoder interface
+type EncoderFunc func(w io.Writer, img image.Image) error
+
+// Encode calls f(w, img)
+func (f EncoderFunc) Encode(w io.Writer, img image.Image) error {
+	return f(w, img)
+}
+
+// ConfigurableEncoder is an Encoder that honours EncodeOptions. Plain
+// encoders get EncodeOptions.Palette applied before Encode is called.
+type ConfigurableEncoder interface {
+	Encoder
+	EncodeWith(w io.Writer, img image.Image, opts *EncodeOptions) error
+}
+
+// MultiPageEncoder is an Encoder that can also store several pages in a
+// single file (ConvertOptions.MultiPage)
+type MultiPageEncoder interface {
+	Encoder
+	NewPageWriter(path string, opts *EncodeOptions) (PageWriter, error)
+}
+
+// EncodeOptions tunes the output encoders. Zero values keep each format's
+// defaults.
+type EncodeOptions struct {
+	Quality        int    // JPEG quality 1-100 (default 90)
+	PNGCompression string // PNG compression: "default", "none", "fast" or "best"
+	Palette        bool   // Reduce to an 8-bit palette of up to 256 colors (not for JPEG)
+}
+
+// validate checks the option values without applying defaults
+func (o *EncodeOptions) validate() error {
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	if _, err := pngCompressionLevel(o.PNGCompression); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pngCompressionLevel maps a PNGCompression name to the encoder level
+func pngCompressionLevel(name string) (png.CompressionLevel, error) {
+	switch strings.ToLower(name) {
+	case "", "default":
+		return png.DefaultCompression, nil
+	case "none":
+		return png.NoCompression, nil
+	case "fast":
+		return png.BestSpeed, nil
+	case "best":
+		return png.BestCompression, nil
+	}
+	return 0, fmt.Errorf("png compression must be 'default', 'none', 'fast' or 'best'")
+}
+
+// PageWriter collects the pages of a multi-page output file
+type PageWriter interface {
+	// AddPage stores a rendered page. It may be called concurrently and
+	// in any page order.
+	AddPage(pageNum int, img image.Image) error
+	// Close writes the collected pages to the file in page order
+	Close() error
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		"png":  pngEncoder{},
+		"jpg":  jpegEncoder{},
+		"jpeg": jpegEncoder{},
+		"webp": EncoderFunc(imgenc.EncodeWebP),
+		"tiff": tiffEncoder{},
+		"tif":  tiffEncoder{},
+	}
+)
+
+// RegisterEncoder makes an output format available to Convert and
+// EncodePage. The format name is also used as the file extension.
+// Registering an existing format replaces its encoder.
+func RegisterEncoder(format string, enc Encoder) {
+	if enc == nil {
+		panic("converter: RegisterEncoder with nil encoder")
+	}
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[strings.ToLower(format)] = enc
+}
+
+// Formats returns the registered output formats in alphabetical order
+func Formats() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	formats := make([]string, 0, len(encoders))
+	for format := range encoders {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// EncodeImage writes an image returned by RenderPage or RenderRegion to out
+// in the given format. opts may be nil.
+func EncodeImage(out io.Writer, img image.Image, format string, opts *EncodeOptions) error {
+	format, err := normalizeFormat(format)
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &EncodeOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	return encodeImage(out, img, format, opts)
+}
+
+// lookupEncoder returns the encoder for a normalized format
+func lookupEncoder(format string) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	enc, ok := encoders[format]
+	return enc, ok
+}
+
+// pngEncoder writes PNG files, 8-bit paletted when EncodeOptions.Palette is set
+type pngEncoder struct{}
+
+func (e pngEncoder) Encode(w io.Writer, img image.Image) error {
+	return e.EncodeWith(w, img, &EncodeOptions{})
+}
+
+func (pngEncoder) EncodeWith(w io.Writer, img image.Image, opts *EncodeOptions) error {
+	level, err := pngCompressionLevel(opts.PNGCompression)
+	if err != nil {
+		return err
+	}
+	if opts.Palette {
+		img = imgenc.Quantize(img, 256)
+	}
+	enc := &png.Encoder{CompressionLevel: level}
+	return enc.Encode(w, img)
+}
+
+// jpegEncoder writes baseline JPEG files. The standard library encoder
+// always uses 4:2:0 chroma subsampling and cannot write progressive files.
+// JPEG has no alpha channel, so transparent pixels are put on white.
+type jpegEncoder struct{}
+
+func (e jpegEncoder) Encode(w io.Writer, img image.Image) error {
+	return e.EncodeWith(w, img, &EncodeOptions{})
+}
+
+func (jpegEncoder) EncodeWith(w io.Writer, img image.Image, opts *EncodeOptions) error {
+	quality := opts.Quality
+	if quality == 0 {
+		quality = 90
+	}
+	return jpeg.Encode(w, flattenOnWhite(img), &jpeg.Options{Quality: quality})
+}
+
+// tiffEncoder writes TIFF files, one page each or all pages in one file
+type tiffEncoder struct{}
+
+func (e tiffEncoder) Encode(w io.Writer, img image.Image) error {
+	return e.EncodeWith(w, img, &EncodeOptions{})
+}
+
+func (tiffEncoder) EncodeWith(w io.Writer, img image.Image, opts *EncodeOptions) error {
+	if opts.Palette {
+		img = imgenc.Quantize(img, 256)
+	}
+	return imgenc.EncodeTIFF(w, img)
+}
+
+func (tiffEncoder) NewPageWriter(path string, opts *EncodeOptions) (PageWriter, error) {
+	spill, err := os.CreateTemp(filepath.Dir(path), ".pdf2img-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	return &tiffPageWriter{path: path, palette: opts.Palette, spill: spill, pages: map[int]spilledPage{}}, nil
+}
+
+// tiffPageWriter compresses pages as they arrive and parks their data in a
+// temporary file, so memory use does not grow with the page count. Close
+// assembles the final multi-page TIFF in page order.
+type tiffPageWriter struct {
+	path    string
+	palette bool
+	mu      sync.Mutex
+	spill   *os.File
+	size    int64
+	pages   map[int]spilledPage
+}
+
+// spilledPage is a compressed page whose strip data lives in the spill file
+type spilledPage struct {
+	page   *imgenc.TIFFPage
+	offset int64
+	length int
+}
+
+func (t *tiffPageWriter) AddPage(pageNum int, img image.Image) error {
+	if t.palette {
+		img = imgenc.Quantize(img, 256)
+	}
+	page, err := imgenc.NewTIFFPage(img)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.spill.WriteAt(page.Data, t.size); err != nil {
+		return fmt.Errorf("failed to buffer page: %w", err)
+	}
+	t.pages[pageNum] = spilledPage{page: page, offset: t.size, length: len(page.Data)}
+	t.size += int64(len(page.Data))
+	page.Data = nil
+	return nil
+}
+
+func (t *tiffPageWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer os.Remove(t.spill.Name())
+	defer t.spill.Close()
+
+	if len(t.pages) == 0 {
+		return nil
+	}
+
+	pageNums := make([]int, 0, len(t.pages))
+	for pageNum := range t.pages {
+		pageNums = append(pageNums, pageNum)
+	}
+	sort.Ints(pageNums)
+
+	file, err := os.Create(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	tw, err := imgenc.NewTIFFWriter(file, len(pageNums))
+	if err != nil {
+		return err
+	}
+	for _, pageNum := range pageNums {
+		spilled := t.pages[pageNum]
+		spilled.page.Data = make([]byte, spilled.length)
+		if _, err := t.spill.ReadAt(spilled.page.Data, spilled.offset); err != nil {
+			return fmt.Errorf("failed to read buffered page %d: %w", pageNum, err)
+		}
+		err := tw.WritePage(spilled.page)
+		spilled.page.Data = nil
+		if err != nil {
+			return fmt.Errorf("failed to write page %d: %w", pageNum, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
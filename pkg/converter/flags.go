@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium/enums"
+)
+
+// RenderFlags switches optional PDFium rendering features. The zero value
+// renders page content only, without annotations or form fields.
+//
+// PDFium's reverse byte order flag is not offered: go-pdfium always sets
+// it so that bitmaps come out in the RGBA order of Go images.
+type RenderFlags struct {
+	Annotations   bool // Draw annotations: notes, highlights, stamps, links...
+	Forms         bool // Draw form fields with their current values
+	Print         bool // Render for printing: print-only annotations, no screen-only ones
+	LCDText       bool // Optimize text for LCD screens (subpixel anti-aliasing)
+	NoSmoothText  bool // Disable anti-aliasing of text
+	NoSmoothImage bool // Disable anti-aliasing of images
+	NoSmoothPath  bool // Disable anti-aliasing of paths
+}
+
+// renderFlagNames are the names ParseRenderFlags accepts, in String order
+var renderFlagNames = []string{"annotations", "forms", "print", "lcd_text", "no_smooth_text", "no_smooth_image", "no_smooth_path"}
+
+// fields returns pointers to the flags in renderFlagNames order
+func (f *RenderFlags) fields() []*bool {
+	return []*bool{&f.Annotations, &f.Forms, &f.Print, &f.LCDText, &f.NoSmoothText, &f.NoSmoothImage, &f.NoSmoothPath}
+}
+
+// ParseRenderFlags parses a comma-separated list of flag names, e.g.
+// "annotations,forms,no_smooth_text". Dashes may be used for underscores.
+func ParseRenderFlags(list string) (RenderFlags, error) {
+	var flags RenderFlags
+	fields := flags.fields()
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+		if name == "" {
+			continue
+		}
+		i := slices.Index(renderFlagNames, name)
+		if i < 0 {
+			return RenderFlags{}, fmt.Errorf("unknown render flag %q (expected %s)", name, strings.Join(renderFlagNames, ", "))
+		}
+		*fields[i] = true
+	}
+	return flags, nil
+}
+
+// String returns the set flags as a comma-separated list that
+// ParseRenderFlags accepts, "" when none is set
+func (f RenderFlags) String() string {
+	var names []string
+	for i, set := range f.fields() {
+		if *set {
+			names = append(names, renderFlagNames[i])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// renderFlags returns the PDFium render flags for opts. Reduced color
+// modes let PDFium render in grayscale, which spares a conversion pass.
+func renderFlags(opts *RenderOptions) enums.FPDF_RENDER_FLAG {
+	var flags enums.FPDF_RENDER_FLAG
+	f := opts.Flags
+	if f.Annotations {
+		flags |= enums.FPDF_RENDER_FLAG_ANNOT
+	}
+	if f.Print {
+		flags |= enums.FPDF_RENDER_FLAG_PRINTING
+	}
+	if f.LCDText {
+		flags |= enums.FPDF_RENDER_FLAG_LCD_TEXT
+	}
+	if f.NoSmoothText {
+		flags |= enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHTEXT
+	}
+	if f.NoSmoothImage {
+		flags |= enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHIMAGE
+	}
+	if f.NoSmoothPath {
+		flags |= enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHPATH
+	}
+	if reducedColor(opts.ColorMode) {
+		flags |= enums.FPDF_RENDER_FLAG_GRAYSCALE
+	}
+	return flags
+}
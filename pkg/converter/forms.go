@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"context"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/tu-usuario/pdf2img/pkg/forms"
+)
+
+// FlattenForm turns the form fields and other annotations of an in-memory
+// PDF into page content and returns the flattened PDF. Use the forms
+// package to list and fill the fields.
+func (c *Converter) FlattenForm(ctx context.Context, pdf []byte, pass string) ([]byte, error) {
+	var out []byte
+	err := c.runContext(ctx, func(instance pdfium.Pdfium) error {
+		doc, err := openDocument(instance, &pdf, pass)
+		if err != nil {
+			return err
+		}
+		defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: doc.Document,
+		})
+
+		out, err = forms.Flatten(instance, doc.Document)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
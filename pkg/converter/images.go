@@ -0,0 +1,401 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+)
+
+// ImageOptions controls ExtractImages
+type ImageOptions struct {
+	OutputDir string // Write the images into this directory ("" = only list them)
+	MinSize   int    // Skip images narrower or shorter than this many pixels, e.g. rules and icons
+	Password  string // Password of an encrypted PDF (user or owner password)
+
+	// Format re-encodes every image in a registered format ("png", "tiff"...).
+	// By default JPEG and JPEG 2000 images are written byte for byte as they
+	// are stored in the PDF and all other images as PNG.
+	Format string
+}
+
+// ImagesResult lists the images of the selected pages
+type ImagesResult struct {
+	TotalPages int             `json:"total_pages"` // Pages in the document
+	Images     []EmbeddedImage `json:"images"`      // In page order, then in drawing order
+}
+
+// EmbeddedImage is an image object drawn on a page
+type EmbeddedImage struct {
+	Page         int      `json:"page"`  // Page number (1-indexed)
+	Index        int      `json:"index"` // Position among the images of the page (1-indexed)
+	Width        int      `json:"width"` // Size of the image itself in pixels
+	Height       int      `json:"height"`
+	BitsPerPixel int      `json:"bits_per_pixel"`
+	ColorSpace   string   `json:"color_space"`       // PDF color space, e.g. DeviceRGB or ICCBased
+	Filters      []string `json:"filters,omitempty"` // Compression filters, e.g. DCTDecode for JPEG
+	Placement    Rect     `json:"placement"`         // Where the image is drawn, in PDF user space
+	Format       string   `json:"format,omitempty"`  // Format of the written file: jpg, jp2, png...
+	File         string   `json:"file,omitempty"`
+	Error        string   `json:"error,omitempty"` // The image could not be written
+}
+
+// maxFormDepth bounds how deep ExtractImages looks into nested form XObjects
+const maxFormDepth = 16
+
+// colorSpaceNames maps PDFium color spaces to their PDF names
+var colorSpaceNames = map[enums.FPDF_COLORSPACE]string{
+	enums.FPDF_COLORSPACE_DEVICEGRAY: "DeviceGray",
+	enums.FPDF_COLORSPACE_DEVICERGB:  "DeviceRGB",
+	enums.FPDF_COLORSPACE_DEVICECMYK: "DeviceCMYK",
+	enums.FPDF_COLORSPACE_CALGRAY:    "CalGray",
+	enums.FPDF_COLORSPACE_CALRGB:     "CalRGB",
+	enums.FPDF_COLORSPACE_LAB:        "Lab",
+	enums.FPDF_COLORSPACE_ICCBASED:   "ICCBased",
+	enums.FPDF_COLORSPACE_SEPARATION: "Separation",
+	enums.FPDF_COLORSPACE_DEVICEN:    "DeviceN",
+	enums.FPDF_COLORSPACE_INDEXED:    "Indexed",
+	enums.FPDF_COLORSPACE_PATTERN:    "Pattern",
+}
+
+// ExtractImages lists the raster images drawn on the selected pages (see
+// pagesel, "" = all pages) of an in-memory PDF, including images inside
+// form XObjects, and writes them to opts.OutputDir when set. An image that
+// cannot be written is reported with its Error and extraction continues.
+func (c *Converter) ExtractImages(ctx context.Context, pdf []byte, pages string, opts *ImageOptions) (*ImagesResult, error) {
+	if opts == nil {
+		opts = &ImageOptions{}
+	}
+	if opts.MinSize < 0 {
+		return nil, fmt.Errorf("min size cannot be negative")
+	}
+	if opts.Format != "" {
+		format, err := normalizeFormat(opts.Format)
+		if err != nil {
+			return nil, err
+		}
+		normalized := *opts
+		normalized.Format = format
+		opts = &normalized
+	}
+	if opts.OutputDir != "" {
+		if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	sel, err := pagesel.Parse(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newRenderWorker(c.pool, pdf, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	pageCount, err := w.pageCount()
+	if err != nil {
+		return nil, err
+	}
+	pageNums, err := sel.Pages(pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImagesResult{TotalPages: pageCount, Images: []EmbeddedImage{}}
+	for _, pageNum := range pageNums {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		images, err := w.pageImages(pageNum, pageCount, opts)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		result.Images = append(result.Images, images...)
+	}
+
+	return result, nil
+}
+
+// pageImages lists, and writes when opts.OutputDir is set, the images of
+// pageNum (1-indexed)
+func (w *renderWorker) pageImages(pageNum, pageCount int, opts *ImageOptions) ([]EmbeddedImage, error) {
+	page := w.page(pageNum)
+	count, err := w.instance.FPDFPage_CountObjects(&requests.FPDFPage_CountObjects{Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count page objects: %w", err)
+	}
+
+	var images []EmbeddedImage
+	var visit func(obj references.FPDF_PAGEOBJECT, parent matrix, depth int) error
+	visit = func(obj references.FPDF_PAGEOBJECT, parent matrix, depth int) error {
+		typ, err := w.instance.FPDFPageObj_GetType(&requests.FPDFPageObj_GetType{PageObject: obj})
+		if err != nil {
+			return fmt.Errorf("failed to get page object type: %w", err)
+		}
+		if typ.Type != enums.FPDF_PAGEOBJ_IMAGE && typ.Type != enums.FPDF_PAGEOBJ_FORM {
+			return nil
+		}
+
+		m, err := w.instance.FPDFPageObj_GetMatrix(&requests.FPDFPageObj_GetMatrix{PageObject: obj})
+		if err != nil {
+			return fmt.Errorf("failed to get object matrix: %w", err)
+		}
+		ctm := newMatrix(m.Matrix.A, m.Matrix.B, m.Matrix.C, m.Matrix.D, m.Matrix.E, m.Matrix.F).then(parent)
+
+		if typ.Type == enums.FPDF_PAGEOBJ_FORM {
+			if depth >= maxFormDepth {
+				return nil
+			}
+			children, err := w.instance.FPDFFormObj_CountObjects(&requests.FPDFFormObj_CountObjects{PageObject: obj})
+			if err != nil {
+				return fmt.Errorf("failed to count form objects: %w", err)
+			}
+			for i := 0; i < children.Count; i++ {
+				child, err := w.instance.FPDFFormObj_GetObject(&requests.FPDFFormObj_GetObject{PageObject: obj, Index: uint64(i)})
+				if err != nil {
+					return fmt.Errorf("failed to get form object: %w", err)
+				}
+				if err := visit(child.PageObject, ctm, depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		img, err := w.imageInfo(page, obj)
+		if err != nil {
+			return err
+		}
+		if img.Width < opts.MinSize || img.Height < opts.MinSize {
+			return nil
+		}
+		img.Page = pageNum
+		img.Index = len(images) + 1
+		// Images are drawn into the unit square of their matrix
+		img.Placement = roundRect(ctm.unitSquare())
+		if opts.OutputDir != "" {
+			if err := w.saveImage(obj, &img, pageCount, opts); err != nil {
+				img.Error = err.Error()
+			}
+		}
+		images = append(images, img)
+		return nil
+	}
+
+	for i := 0; i < count.Count; i++ {
+		obj, err := w.instance.FPDFPage_GetObject(&requests.FPDFPage_GetObject{Page: page, Index: i})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get page object: %w", err)
+		}
+		if err := visit(obj.PageObject, identityMatrix, 0); err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
+
+// imageInfo returns the size, color space and filters of an image object
+func (w *renderWorker) imageInfo(page requests.Page, obj references.FPDF_PAGEOBJECT) (EmbeddedImage, error) {
+	meta, err := w.instance.FPDFImageObj_GetImageMetadata(&requests.FPDFImageObj_GetImageMetadata{ImageObject: obj, Page: page})
+	if err != nil {
+		return EmbeddedImage{}, fmt.Errorf("failed to get image metadata: %w", err)
+	}
+	md := meta.ImageMetadata
+	img := EmbeddedImage{
+		Width:        int(md.Width),
+		Height:       int(md.Height),
+		BitsPerPixel: int(md.BitsPerPixel),
+		ColorSpace:   colorSpaceNames[md.Colorspace],
+	}
+	if img.ColorSpace == "" {
+		img.ColorSpace = "Unknown"
+	}
+
+	filters, err := w.instance.FPDFImageObj_GetImageFilterCount(&requests.FPDFImageObj_GetImageFilterCount{ImageObject: obj})
+	if err != nil {
+		return EmbeddedImage{}, fmt.Errorf("failed to get image filters: %w", err)
+	}
+	for i := 0; i < filters.Count; i++ {
+		filter, err := w.instance.FPDFImageObj_GetImageFilter(&requests.FPDFImageObj_GetImageFilter{ImageObject: obj, Index: i})
+		if err != nil {
+			return EmbeddedImage{}, fmt.Errorf("failed to get image filters: %w", err)
+		}
+		img.Filters = append(img.Filters, filter.ImageFilter)
+	}
+	return img, nil
+}
+
+// nativeFormat returns the file format an image stored with filters can be
+// written in without decoding it, or "" if it has to be decoded
+func nativeFormat(filters []string) string {
+	if len(filters) != 1 {
+		return ""
+	}
+	switch filters[0] {
+	case "DCTDecode":
+		return "jpg"
+	case "JPXDecode":
+		return "jp2"
+	}
+	return ""
+}
+
+// saveImage writes an image object into opts.OutputDir, filling in
+// img.Format and img.File
+func (w *renderWorker) saveImage(obj references.FPDF_PAGEOBJECT, img *EmbeddedImage, pageCount int, opts *ImageOptions) error {
+	var data []byte
+	format := opts.Format
+	if format == "" {
+		format = nativeFormat(img.Filters)
+	}
+
+	if format != "" && format == nativeFormat(img.Filters) {
+		raw, err := w.instance.FPDFImageObj_GetImageDataRaw(&requests.FPDFImageObj_GetImageDataRaw{ImageObject: obj})
+		if err != nil {
+			return fmt.Errorf("failed to get image data: %w", err)
+		}
+		data = raw.Data
+	} else {
+		if format == "" {
+			format = "png"
+		}
+		decoded, err := w.imageBitmap(obj)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := encodeImage(&buf, decoded, format, &EncodeOptions{}); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	digits := max(4, len(strconv.Itoa(pageCount)))
+	name := fmt.Sprintf("page_%0*d_img_%02d.%s", digits, img.Page, img.Index, format)
+	path := filepath.Join(opts.OutputDir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	img.Format, img.File = format, path
+	return nil
+}
+
+// imageBitmap decodes an image object into a Go image. Masks and the
+// transform matrix are not applied: this is the image as stored.
+func (w *renderWorker) imageBitmap(obj references.FPDF_PAGEOBJECT) (image.Image, error) {
+	res, err := w.instance.FPDFImageObj_GetBitmap(&requests.FPDFImageObj_GetBitmap{ImageObject: obj})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bitmap := res.Bitmap
+	defer w.instance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{Bitmap: bitmap})
+
+	width, err := w.instance.FPDFBitmap_GetWidth(&requests.FPDFBitmap_GetWidth{Bitmap: bitmap})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	height, err := w.instance.FPDFBitmap_GetHeight(&requests.FPDFBitmap_GetHeight{Bitmap: bitmap})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	stride, err := w.instance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{Bitmap: bitmap})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	format, err := w.instance.FPDFBitmap_GetFormat(&requests.FPDFBitmap_GetFormat{Bitmap: bitmap})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	buffer, err := w.instance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{Bitmap: bitmap})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return bitmapToImage(buffer.Buffer, width.Width, height.Height, stride.Stride, format.Format)
+}
+
+// bitmapToImage copies a PDFium bitmap buffer into a Go image
+func bitmapToImage(buf []byte, width, height, stride int, format enums.FPDF_BITMAP_FORMAT) (image.Image, error) {
+	if width <= 0 || height <= 0 || len(buf) < stride*height {
+		return nil, fmt.Errorf("invalid %dx%d bitmap", width, height)
+	}
+	rect := image.Rect(0, 0, width, height)
+
+	switch format {
+	case enums.FPDF_BITMAP_FORMAT_GRAY:
+		img := image.NewGray(rect)
+		for y := 0; y < height; y++ {
+			copy(img.Pix[y*img.Stride:y*img.Stride+width], buf[y*stride:])
+		}
+		return img, nil
+	case enums.FPDF_BITMAP_FORMAT_BGR, enums.FPDF_BITMAP_FORMAT_BGRX, enums.FPDF_BITMAP_FORMAT_BGRA:
+		bpp := 4
+		if format == enums.FPDF_BITMAP_FORMAT_BGR {
+			bpp = 3
+		}
+		img := image.NewNRGBA(rect)
+		for y := 0; y < height; y++ {
+			src := buf[y*stride:]
+			dst := img.Pix[y*img.Stride:]
+			for x := 0; x < width; x++ {
+				s, d := src[x*bpp:], dst[x*4:]
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 0xff
+				if format == enums.FPDF_BITMAP_FORMAT_BGRA {
+					d[3] = s[3]
+				}
+			}
+		}
+		return img, nil
+	}
+	return nil, fmt.Errorf("unsupported bitmap format %d", format)
+}
+
+// matrix is a PDF transformation matrix [a b c d e f] mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f)
+type matrix struct {
+	a, b, c, d, e, f float64
+}
+
+var identityMatrix = matrix{a: 1, d: 1}
+
+func newMatrix(a, b, c, d, e, f float32) matrix {
+	return matrix{float64(a), float64(b), float64(c), float64(d), float64(e), float64(f)}
+}
+
+// then returns the matrix that applies m and then p
+func (m matrix) then(p matrix) matrix {
+	return matrix{
+		a: m.a*p.a + m.b*p.c,
+		b: m.a*p.b + m.b*p.d,
+		c: m.c*p.a + m.d*p.c,
+		d: m.c*p.b + m.d*p.d,
+		e: m.e*p.a + m.f*p.c + p.e,
+		f: m.e*p.b + m.f*p.d + p.f,
+	}
+}
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m.a*x + m.c*y + m.e, m.b*x + m.d*y + m.f
+}
+
+// unitSquare returns the bounds of the unit square mapped by m
+func (m matrix) unitSquare() Rect {
+	r := Rect{X0: math.Inf(1), Y0: math.Inf(1), X1: math.Inf(-1), Y1: math.Inf(-1)}
+	for _, p := range [][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		x, y := m.apply(p[0], p[1])
+		r = unionRect(r, Rect{X0: x, Y0: y, X1: x, Y1: y})
+	}
+	return r
+}
@@ -0,0 +1,264 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/tu-usuario/pdf2img/pkg/password"
+)
+
+// PDFInfo describes a PDF document
+type PDFInfo struct {
+	File       string     `json:"file"`       // Base name of the file
+	Size       int64      `json:"size"`       // File size in bytes
+	Version    string     `json:"version"`    // Version in the file header, e.g. "1.7"
+	PageCount  int        `json:"page_count"` // Number of pages
+	Encryption Encryption `json:"encryption"`
+	Tagged     bool       `json:"tagged"`     // Has a logical structure tree (tagged PDF)
+	Linearized bool       `json:"linearized"` // Optimized for fast web view
+	Pages      []PageInfo `json:"pages"`
+
+	Outline []OutlineItem `json:"outline,omitempty"` // Bookmarks, see Outline
+}
+
+// Encryption describes how a PDF is protected
+type Encryption struct {
+	Encrypted bool `json:"encrypted"`
+	Revision  int  `json:"revision,omitempty"` // Standard security handler revision: 2-4 RC4 or AES-128, 5-6 AES-256
+
+	// Permissions lists what the document allows to users who only know the
+	// user password: print, modify, copy, annotate, fill_forms,
+	// extract_accessibility, assemble and print_high_quality
+	Permissions []string `json:"permissions"`
+}
+
+// PageInfo describes one page. Boxes are in PDF user space.
+type PageInfo struct {
+	Page     int     `json:"page"`      // Page number (1-indexed)
+	Width    float64 `json:"width"`     // Displayed width in points (crop box, rotated)
+	Height   float64 `json:"height"`    // Displayed height in points
+	MediaBox Rect    `json:"media_box"` // Physical page
+	CropBox  Rect    `json:"crop_box"`  // Visible area; the media box if the page sets none
+	BleedBox Rect    `json:"bleed_box"` // Trimmed page plus bleed; the crop box if the page sets none
+	TrimBox  Rect    `json:"trim_box"`  // Finished page; the crop box if the page sets none
+	ArtBox   Rect    `json:"art_box"`   // Meaningful content; the crop box if the page sets none
+	Rotation int     `json:"rotation"`  // Degrees clockwise
+	UserUnit float64 `json:"user_unit"` // Size of a user space unit in points (PDF 1.6+, default 1)
+}
+
+// linearizedWindow is how far into the file the linearization dictionary,
+// the first object of a linearized PDF, must start
+const linearizedWindow = 1024
+
+// GetPDFInfo returns information about a PDF file
+func (c *Converter) GetPDFInfo(pdfPath string) (*PDFInfo, error) {
+	return c.GetPDFInfoContext(context.Background(), pdfPath)
+}
+
+// GetPDFInfoContext is like GetPDFInfo but gives up when ctx is done
+func (c *Converter) GetPDFInfoContext(ctx context.Context, pdfPath string) (*PDFInfo, error) {
+	return c.GetPDFInfoWithPassword(ctx, pdfPath, "")
+}
+
+// GetPDFInfoWithPassword is like GetPDFInfoContext for encrypted PDFs
+func (c *Converter) GetPDFInfoWithPassword(ctx context.Context, pdfPath, pass string) (*PDFInfo, error) {
+	if _, err := os.Stat(pdfPath); err != nil {
+		return nil, fmt.Errorf("PDF file not found: %w", err)
+	}
+
+	// Read PDF file into memory (WebAssembly requires bytes, not file paths)
+	pdfBytes, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	info := &PDFInfo{
+		File:       filepath.Base(pdfPath),
+		Size:       int64(len(pdfBytes)),
+		Linearized: isLinearized(pdfBytes),
+	}
+	err = c.runContext(ctx, func(instance pdfium.Pdfium) error {
+		doc, err := openDocument(instance, &pdfBytes, pass)
+		if err != nil {
+			return err
+		}
+		defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: doc.Document,
+		})
+
+		return documentInfo(ctx, instance, doc, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// PDFium does not expose UserUnit
+	units := userUnits(pdfBytes, pass)
+	for i := range info.Pages {
+		info.Pages[i].UserUnit = 1
+		if unit, ok := units[i+1]; ok {
+			info.Pages[i].UserUnit = unit
+		}
+	}
+
+	return info, nil
+}
+
+// documentInfo fills in the document and page properties PDFium knows about
+func documentInfo(ctx context.Context, instance pdfium.Pdfium, doc *responses.OpenDocument, info *PDFInfo) error {
+	pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{Document: doc.Document})
+	if err != nil {
+		return fmt.Errorf("failed to get page count: %w", err)
+	}
+	info.PageCount = pageCount.PageCount
+
+	version, err := instance.FPDF_GetFileVersion(&requests.FPDF_GetFileVersion{Document: doc.Document})
+	if err != nil {
+		return fmt.Errorf("failed to get PDF version: %w", err)
+	}
+	info.Version = fmt.Sprintf("%d.%d", version.FileVersion/10, version.FileVersion%10)
+
+	revision, err := instance.FPDF_GetSecurityHandlerRevision(&requests.FPDF_GetSecurityHandlerRevision{Document: doc.Document})
+	if err != nil {
+		return fmt.Errorf("failed to get encryption: %w", err)
+	}
+	// The user permissions stay the same whichever password opened the
+	// document, and allow everything when it is not encrypted
+	perms, err := instance.FPDF_GetDocUserPermissions(&requests.FPDF_GetDocUserPermissions{Document: doc.Document})
+	if err != nil {
+		return fmt.Errorf("failed to get permissions: %w", err)
+	}
+	info.Encryption = Encryption{
+		Encrypted:   revision.SecurityHandlerRevision >= 0,
+		Revision:    max(revision.SecurityHandlerRevision, 0),
+		Permissions: permissionNames(perms),
+	}
+
+	tagged, err := instance.FPDFCatalog_IsTagged(&requests.FPDFCatalog_IsTagged{Document: doc.Document})
+	if err != nil {
+		return fmt.Errorf("failed to get tagged status: %w", err)
+	}
+	info.Tagged = tagged.IsTagged
+
+	if info.Outline, err = documentOutline(instance, doc.Document); err != nil {
+		return err
+	}
+
+	info.Pages = make([]PageInfo, 0, info.PageCount)
+	for i := 0; i < info.PageCount; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page := requests.Page{ByIndex: &requests.PageByIndex{Document: doc.Document, Index: i}}
+		boxes, err := pageBoxes(instance, page)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+		rotation, err := instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{Page: page})
+		if err != nil {
+			return fmt.Errorf("page %d: failed to get rotation: %w", i+1, err)
+		}
+		size, err := instance.GetPageSize(&requests.GetPageSize{Page: page})
+		if err != nil {
+			return fmt.Errorf("page %d: failed to get page size: %w", i+1, err)
+		}
+		info.Pages = append(info.Pages, PageInfo{
+			Page:     i + 1,
+			Width:    roundPoints(size.Width),
+			Height:   roundPoints(size.Height),
+			MediaBox: roundRect(boxes.media),
+			CropBox:  roundRect(boxes.crop),
+			BleedBox: roundRect(boxes.bleed),
+			TrimBox:  roundRect(boxes.trim),
+			ArtBox:   roundRect(boxes.art),
+			Rotation: int(rotation.PageRotation) % 4 * 90,
+		})
+	}
+	return nil
+}
+
+// permissionNames lists the operations the user permissions allow
+func permissionNames(p *responses.FPDF_GetDocUserPermissions) []string {
+	perms := []struct {
+		allowed bool
+		name    string
+	}{
+		{p.PrintDocument, "print"},
+		{p.ModifyContents, "modify"},
+		{p.CopyOrExtractText, "copy"},
+		{p.AddOrModifyTextAnnotations, "annotate"},
+		{p.FillInExistingInteractiveFormFields, "fill_forms"},
+		{p.ExtractTextAndGraphics, "extract_accessibility"},
+		{p.AssembleDocument, "assemble"},
+		{p.PrintDocumentAsFaithfulDigitalCopy, "print_high_quality"},
+	}
+	names := []string{}
+	for _, perm := range perms {
+		if perm.allowed {
+			names = append(names, perm.name)
+		}
+	}
+	return names
+}
+
+// isLinearized reports whether a PDF starts with a linearization dictionary
+func isLinearized(pdf []byte) bool {
+	head := pdf[:min(len(pdf), linearizedWindow)]
+	return bytes.Contains(head, []byte("/Linearized"))
+}
+
+// userUnits returns the UserUnit of the pages that set one, keyed by page
+// number. It is best effort: a PDF pdfcpu cannot read has no user units.
+func userUnits(pdf []byte, pass string) map[int]float64 {
+	conf := password.Configuration(pass)
+	conf.ValidationMode = model.ValidationRelaxed
+
+	units := map[int]float64{}
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil || ctx.EnsurePageCount() != nil {
+		return units
+	}
+	for pageNum := 1; pageNum <= ctx.PageCount; pageNum++ {
+		d, _, _, err := ctx.PageDict(pageNum, false)
+		if err != nil || d == nil {
+			continue
+		}
+		obj, ok := d.Find("UserUnit")
+		if !ok {
+			continue
+		}
+		if unit, err := ctx.DereferenceNumber(obj); err == nil && unit > 0 {
+			units[pageNum] = unit
+		}
+	}
+	return units
+}
+
+// roundPoints rounds a length to 1/100 point
+func roundPoints(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// FormatSize formats a byte count for humans, e.g. "2.50 MB"
+func FormatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[unit])
+}
@@ -0,0 +1,574 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+)
+
+// Text layout levels, from the coarsest to the finest
+const (
+	LayoutBlocks = "blocks"
+	LayoutLines  = "lines"
+	LayoutWords  = "words"
+	LayoutChars  = "chars"
+)
+
+// LayoutOptions controls positioned text extraction. The pixel settings
+// match ConvertOptions: with the same DPI, Width, Height, MaxPixels,
+// PageBox and Crop the pixel coordinates line up with the images Convert
+// writes.
+type LayoutOptions struct {
+	Level     string  // Finest level to include: blocks, lines, words (default) or chars
+	DPI       float64 // DPI of the images the pixel coordinates refer to (default 150)
+	Width     int     // Images rendered this many pixels wide
+	Height    int     // Images rendered this many pixels tall
+	MaxPixels int     // Images scaled down to at most this many pixels
+	PageBox   PageBox // Page boundary the images show (default PageBoxCrop)
+	Crop      *Region // Images show only this rectangle of the page box
+	Password  string  // Password of an encrypted PDF (user or owner password)
+}
+
+// PageLayout is the positioned text of one page
+type PageLayout struct {
+	Page        int         `json:"page"`         // Page number (1-indexed)
+	Width       float64     `json:"width"`        // Displayed page width in points
+	Height      float64     `json:"height"`       // Displayed page height in points
+	Rotation    int         `json:"rotation"`     // Page rotation in degrees clockwise
+	PixelWidth  int         `json:"pixel_width"`  // Width of the rendered image
+	PixelHeight int         `json:"pixel_height"` // Height of the rendered image
+	Blocks      []TextBlock `json:"blocks"`
+}
+
+// Rect is a rectangle given by two corners
+type Rect struct {
+	X0 float64 `json:"x0"`
+	Y0 float64 `json:"y0"`
+	X1 float64 `json:"x1"`
+	Y1 float64 `json:"y1"`
+}
+
+// Box locates text both in the PDF and in the rendered image. Points are in
+// PDF user space (x0,y0 = left,bottom; x1,y1 = right,top; y grows upwards),
+// ready to be written back to the PDF, e.g. as redactions. Pixels are image
+// coordinates (x0,y0 = top left corner; y grows downwards) and account for
+// the page rotation, crop box and rendered area; text outside a cropped
+// image lies outside 0..PixelWidth and 0..PixelHeight.
+type Box struct {
+	Points Rect `json:"points"`
+	Pixels Rect `json:"pixels"`
+}
+
+// TextBlock is a group of lines that are stacked closely, e.g. a paragraph
+type TextBlock struct {
+	Box
+	Text  string     `json:"text"` // Lines separated by "\n"
+	Lines []TextLine `json:"lines,omitempty"`
+}
+
+// TextLine is a run of words on the same baseline
+type TextLine struct {
+	Box
+	Text  string     `json:"text"` // Words separated by single spaces
+	Words []TextWord `json:"words,omitempty"`
+}
+
+// TextWord is a run of characters without whitespace
+type TextWord struct {
+	Box
+	Text     string     `json:"text"`
+	FontName string     `json:"font_name,omitempty"`
+	FontSize float64    `json:"font_size,omitempty"` // In points
+	Chars    []TextChar `json:"chars,omitempty"`
+}
+
+// TextChar is a single character
+type TextChar struct {
+	Box
+	Text     string  `json:"text"`
+	FontName string  `json:"font_name,omitempty"`
+	FontSize float64 `json:"font_size,omitempty"` // In points
+	Angle    float64 `json:"angle,omitempty"`     // Text direction in radians, counterclockwise
+}
+
+// lineGapFactor splits a line where the gap between two words exceeds this
+// many times the font size, e.g. between columns or in a table
+const lineGapFactor = 3
+
+// ExtractTextLayout returns the text of the selected pages (see pagesel,
+// "" = all pages) with the position of every block, line, word and
+// character down to opts.Level
+func (c *Converter) ExtractTextLayout(ctx context.Context, pdf []byte, pages string, opts *LayoutOptions) ([]PageLayout, error) {
+	if opts == nil {
+		opts = &LayoutOptions{}
+	}
+	level, err := layoutDepth(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	render := (&RenderOptions{
+		DPI:       opts.DPI,
+		Width:     opts.Width,
+		Height:    opts.Height,
+		MaxPixels: opts.MaxPixels,
+		PageBox:   opts.PageBox,
+		Crop:      opts.Crop,
+	}).withDefaults()
+	if err := render.validate(); err != nil {
+		return nil, err
+	}
+
+	sel, err := pagesel.Parse(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newRenderWorker(c.pool, pdf, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	pageCount, err := w.pageCount()
+	if err != nil {
+		return nil, err
+	}
+	pageNums, err := sel.Pages(pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	layouts := make([]PageLayout, 0, len(pageNums))
+	for _, pageNum := range pageNums {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		layout, err := w.pageLayout(pageNum, render, level)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		layouts = append(layouts, *layout)
+	}
+
+	return layouts, nil
+}
+
+// layoutDepth returns the depth of a layout level: 0 for blocks to 3 for chars
+func layoutDepth(level string) (int, error) {
+	switch strings.ToLower(level) {
+	case LayoutBlocks:
+		return 0, nil
+	case LayoutLines:
+		return 1, nil
+	case LayoutWords, "":
+		return 2, nil
+	case LayoutChars:
+		return 3, nil
+	}
+	return 0, fmt.Errorf("unknown layout level %q (use blocks, lines, words or chars)", level)
+}
+
+// pageLayout extracts the positioned text of pageNum (1-indexed)
+func (w *renderWorker) pageLayout(pageNum int, render *RenderOptions, depth int) (*PageLayout, error) {
+	t, chars, err := w.pageChars(pageNum, render)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &PageLayout{
+		Page:        pageNum,
+		Width:       t.width,
+		Height:      t.height,
+		Rotation:    t.rotation * 90,
+		PixelWidth:  t.pixelWidth,
+		PixelHeight: t.pixelHeight,
+		Blocks:      groupBlocks(groupLines(chars)),
+	}
+	trimLayout(layout, depth)
+	return layout, nil
+}
+
+// pageChars returns the characters of pageNum (1-indexed) in PDFium's
+// reading order, positioned for images rendered as described by render
+func (w *renderWorker) pageChars(pageNum int, render *RenderOptions) (*pageTransform, []TextChar, error) {
+	t, err := w.pageTransform(pageNum, render)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := w.instance.GetPageTextStructured(&requests.GetPageTextStructured{
+		Page:                   w.page(pageNum),
+		Mode:                   requests.GetPageTextStructuredModeChars,
+		CollectFontInformation: true,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get text: %w", err)
+	}
+
+	chars := make([]TextChar, 0, len(res.Chars))
+	for _, c := range res.Chars {
+		chars = append(chars, t.char(c))
+	}
+	return t, chars, nil
+}
+
+// page returns the request reference to pageNum (1-indexed)
+func (w *renderWorker) page(pageNum int) requests.Page {
+	return requests.Page{
+		ByIndex: &requests.PageByIndex{
+			Document: w.doc,
+			Index:    pageNum - 1,
+		},
+	}
+}
+
+// pageTransform maps PDF user space to the pixels of a rendered page
+type pageTransform struct {
+	box           Rect    // Visible page area (crop box within the media box) in user space
+	rotation      int     // Quarter turns clockwise
+	width, height float64 // Displayed page size in points
+	area          Rect    // Rendered area in displayed points (see renderArea)
+	pixelWidth    int
+	pixelHeight   int
+}
+
+// pageTransform returns the transform of pageNum for images rendered as
+// described by render, using the same pixel size renderPage does
+func (w *renderWorker) pageTransform(pageNum int, render *RenderOptions) (*pageTransform, error) {
+	page := w.page(pageNum)
+
+	boxes, err := pageBoxes(w.instance, page)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, err := w.instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rotation: %w", err)
+	}
+	size, err := w.instance.GetPageSize(&requests.GetPageSize{Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page size: %w", err)
+	}
+
+	t := &pageTransform{
+		box:      boxes.crop,
+		rotation: int(rotation.PageRotation) % 4,
+		width:    size.Width,
+		height:   size.Height,
+	}
+	box := Rect{X1: size.Width, Y1: size.Height}
+	if customBox(render.PageBox) {
+		box = t.rectToPoints(boxes.get(render.PageBox))
+	}
+	if t.area, err = renderArea(box, render.Crop); err != nil {
+		return nil, err
+	}
+	t.pixelWidth, t.pixelHeight = renderSize(t.area.X1-t.area.X0, t.area.Y1-t.area.Y0, render)
+	return t, nil
+}
+
+// renderSize returns the pixel size renderPage produces for a page of
+// widthPt x heightPt points
+func renderSize(widthPt, heightPt float64, opts *RenderOptions) (int, int) {
+	if opts.sized() {
+		if width, height, ok := pageSizeInPixels(widthPt, heightPt, opts); ok {
+			return fitInPixels(widthPt, heightPt, width, height)
+		}
+	}
+	// PDFium's RenderPageInDPI takes a whole DPI and rounds the size up
+	scale := float64(int(opts.DPI)) / 72
+	return int(math.Ceil(widthPt * scale)), int(math.Ceil(heightPt * scale))
+}
+
+// fitInPixels returns the size PDFium's RenderPageInPixels renders a page of
+// widthPt x heightPt points at when asked for width x height pixels: the
+// largest size with the page's aspect ratio that fits, rounded up
+func fitInPixels(widthPt, heightPt float64, width, height int) (int, int) {
+	targetWidth, targetHeight := float64(width), float64(height)
+	if ratio := heightPt / widthPt; targetWidth*ratio < targetHeight {
+		targetHeight = targetWidth * ratio
+	} else if ratio := widthPt / heightPt; targetHeight*ratio < targetWidth {
+		targetWidth = targetHeight * ratio
+	}
+	return int(math.Ceil(targetWidth)), int(math.Ceil(targetHeight))
+}
+
+// pixel maps a point in user space to image coordinates
+func (t *pageTransform) pixel(x, y float64) (float64, float64) {
+	px, py := t.point(x, y)
+	a := t.area
+	return (px - a.X0) * float64(t.pixelWidth) / (a.X1 - a.X0), (py - a.Y0) * float64(t.pixelHeight) / (a.Y1 - a.Y0)
+}
+
+// point maps a point in user space to displayed points, measured from the
+// top left corner of the rotated crop box
+func (t *pageTransform) point(x, y float64) (float64, float64) {
+	b := t.box
+	switch t.rotation {
+	case 1:
+		return y - b.Y0, x - b.X0
+	case 2:
+		return b.X1 - x, y - b.Y0
+	case 3:
+		return b.Y1 - y, b.X1 - x
+	default:
+		return x - b.X0, b.Y1 - y
+	}
+}
+
+// rectToPixels maps a user space rectangle to image coordinates
+func (t *pageTransform) rectToPixels(r Rect) Rect {
+	x0, y0 := t.pixel(r.X0, r.Y0)
+	x1, y1 := t.pixel(r.X1, r.Y1)
+	return Rect{
+		X0: math.Min(x0, x1),
+		Y0: math.Min(y0, y1),
+		X1: math.Max(x0, x1),
+		Y1: math.Max(y0, y1),
+	}
+}
+
+// rectToPoints maps a user space rectangle to displayed points
+func (t *pageTransform) rectToPoints(r Rect) Rect {
+	x0, y0 := t.point(r.X0, r.Y0)
+	x1, y1 := t.point(r.X1, r.Y1)
+	return Rect{
+		X0: math.Min(x0, x1),
+		Y0: math.Min(y0, y1),
+		X1: math.Max(x0, x1),
+		Y1: math.Max(y0, y1),
+	}
+}
+
+// boxOf returns the Box of a user space rectangle
+func (t *pageTransform) boxOf(r Rect) Box {
+	return Box{Points: roundRect(r), Pixels: roundRect(t.rectToPixels(r))}
+}
+
+// char converts a character reported by PDFium
+func (t *pageTransform) char(c *responses.GetPageTextStructuredChar) TextChar {
+	p := c.PointPosition
+	char := TextChar{
+		Box:   t.boxOf(Rect{X0: p.Left, Y0: p.Bottom, X1: p.Right, Y1: p.Top}),
+		Text:  c.Text,
+		Angle: c.Angle,
+	}
+	if f := c.FontInformation; f != nil && f.Name != "" {
+		char.FontName = f.Name
+		char.FontSize = math.Round(f.Size*100) / 100
+	}
+	return char
+}
+
+// groupLines splits the characters, in PDFium's reading order, into words
+// and lines. Lines end at the line breaks PDFium generates and at gaps
+// wider than lineGapFactor times the font size.
+func groupLines(chars []TextChar) []TextLine {
+	var lines []TextLine
+	var words []TextWord
+	var word []TextChar
+
+	endWord := func() {
+		if len(word) > 0 {
+			words = append(words, newWord(word))
+			word = nil
+		}
+	}
+	endLine := func() {
+		endWord()
+		if len(words) > 0 {
+			lines = append(lines, newLine(words))
+			words = nil
+		}
+	}
+
+	for _, c := range chars {
+		r := []rune(c.Text)
+		switch {
+		case c.Text == "":
+			continue
+		case len(r) == 1 && (r[0] == '\r' || r[0] == '\n'):
+			endLine()
+		case len(r) == 1 && unicode.IsSpace(r[0]):
+			endWord()
+		default:
+			if len(word) > 0 && wideGap(word[len(word)-1].Points, c) {
+				endLine()
+			} else if len(word) == 0 && len(words) > 0 && wideGap(words[len(words)-1].Points, c) {
+				endLine()
+			}
+			word = append(word, c)
+		}
+	}
+	endLine()
+
+	return lines
+}
+
+// wideGap reports whether c starts far enough right of the text before it
+// to belong to a separate line
+func wideGap(prev Rect, c TextChar) bool {
+	if c.Angle != 0 || c.FontSize <= 0 {
+		return false
+	}
+	return c.Points.X0-prev.X1 > lineGapFactor*c.FontSize
+}
+
+// groupBlocks stacks each line onto the most recent block whose last line
+// it continues (see sameBlock), so that interleaved columns such as a table
+// of contents and its page numbers form separate blocks
+func groupBlocks(lines []TextLine) []TextBlock {
+	var groups [][]TextLine
+	for _, line := range lines {
+		i := len(groups) - 1
+		for ; i >= 0; i-- {
+			if sameBlock(groups[i][len(groups[i])-1], line) {
+				break
+			}
+		}
+		if i < 0 {
+			groups = append(groups, nil)
+			i = len(groups) - 1
+		}
+		groups[i] = append(groups[i], line)
+	}
+
+	blocks := make([]TextBlock, len(groups))
+	for i, group := range groups {
+		blocks[i] = newBlock(group)
+	}
+	return blocks
+}
+
+// sameBlock reports whether line continues the block that ends with prev:
+// both are horizontal, they overlap horizontally and line starts below prev
+// within the height of a line
+func sameBlock(prev, line TextLine) bool {
+	p, l := prev.Points, line.Points
+	if !upright(prev) || !upright(line) {
+		return false
+	}
+	height := math.Max(p.Y1-p.Y0, l.Y1-l.Y0)
+	gap := p.Y0 - l.Y1
+	overlaps := l.X0 < p.X1 && l.X1 > p.X0
+	return overlaps && gap > -height/2 && gap < height
+}
+
+// upright reports whether all words of line run left to right
+func upright(line TextLine) bool {
+	for _, word := range line.Words {
+		for _, c := range word.Chars {
+			if c.Angle != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newWord(chars []TextChar) TextWord {
+	var text strings.Builder
+	boxes := make([]Box, len(chars))
+	word := TextWord{Chars: chars}
+	for i, c := range chars {
+		text.WriteString(c.Text)
+		boxes[i] = c.Box
+		if word.FontName == "" {
+			word.FontName, word.FontSize = c.FontName, c.FontSize
+		}
+	}
+	word.Text = text.String()
+	word.Box = unionBoxes(boxes)
+	return word
+}
+
+func newLine(words []TextWord) TextLine {
+	texts := make([]string, len(words))
+	boxes := make([]Box, len(words))
+	for i, word := range words {
+		texts[i] = word.Text
+		boxes[i] = word.Box
+	}
+	return TextLine{Box: unionBoxes(boxes), Text: strings.Join(texts, " "), Words: words}
+}
+
+func newBlock(lines []TextLine) TextBlock {
+	texts := make([]string, len(lines))
+	boxes := make([]Box, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+		boxes[i] = line.Box
+	}
+	return TextBlock{Box: unionBoxes(boxes), Text: strings.Join(texts, "\n"), Lines: lines}
+}
+
+// trimLayout drops the levels finer than depth (0 = blocks only)
+func trimLayout(layout *PageLayout, depth int) {
+	for b := range layout.Blocks {
+		block := &layout.Blocks[b]
+		if depth < 1 {
+			block.Lines = nil
+			continue
+		}
+		for l := range block.Lines {
+			line := &block.Lines[l]
+			if depth < 2 {
+				line.Words = nil
+				continue
+			}
+			if depth < 3 {
+				for w := range line.Words {
+					line.Words[w].Chars = nil
+				}
+			}
+		}
+	}
+}
+
+// unionBoxes returns the smallest box containing all boxes
+func unionBoxes(boxes []Box) Box {
+	if len(boxes) == 0 {
+		return Box{}
+	}
+	u := boxes[0]
+	for _, b := range boxes[1:] {
+		u.Points = unionRect(u.Points, b.Points)
+		u.Pixels = unionRect(u.Pixels, b.Pixels)
+	}
+	return u
+}
+
+func unionRect(a, b Rect) Rect {
+	return Rect{
+		X0: math.Min(a.X0, b.X0),
+		Y0: math.Min(a.Y0, b.Y0),
+		X1: math.Max(a.X1, b.X1),
+		Y1: math.Max(a.Y1, b.Y1),
+	}
+}
+
+func intersectRect(a, b Rect) Rect {
+	r := Rect{
+		X0: math.Max(a.X0, b.X0),
+		Y0: math.Max(a.Y0, b.Y0),
+		X1: math.Min(a.X1, b.X1),
+		Y1: math.Min(a.Y1, b.Y1),
+	}
+	if r.X0 >= r.X1 || r.Y0 >= r.Y1 {
+		return a
+	}
+	return r
+}
+
+// roundRect rounds coordinates to 1/100 point or pixel
+func roundRect(r Rect) Rect {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return Rect{X0: round(r.X0), Y0: round(r.Y0), X1: round(r.X1), Y1: round(r.Y1)}
+}
@@ -0,0 +1,246 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifestVersion is bumped when the manifest layout changes incompatibly
+const manifestVersion = 1
+
+// manifestFlushInterval bounds how often the manifest is rewritten while
+// pages are being rendered
+const manifestFlushInterval = time.Second
+
+// Page statuses recorded in the manifest
+const (
+	PageStatusDone   = "done"
+	PageStatusFailed = "failed"
+)
+
+// Manifest is the sidecar file Convert writes next to the images. It lets a
+// later run with ConvertOptions.Resume skip the pages that are already done.
+type Manifest struct {
+	Version     int             `json:"version"`
+	Input       string          `json:"input"`
+	InputSHA256 string          `json:"input_sha256"`
+	PageCount   int             `json:"page_count"`
+	Options     ManifestOptions `json:"options"`
+	Pages       []ManifestPage  `json:"pages"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ManifestOptions are the settings that affect the rendered files. Pages
+// from a run with different options are rendered again.
+type ManifestOptions struct {
+	Format         string  `json:"format"`
+	DPI            float64 `json:"dpi"`
+	Width          int     `json:"width,omitempty"`
+	Height         int     `json:"height,omitempty"`
+	MaxPixels      int     `json:"max_pixels,omitempty"`
+	Prefix         string  `json:"prefix"`
+	NameTemplate   string  `json:"name_template"`
+	MultiPage      bool    `json:"multi_page,omitempty"`
+	Quality        int     `json:"quality,omitempty"`
+	PNGCompression string  `json:"png_compression,omitempty"`
+	Palette        bool    `json:"palette,omitempty"`
+	ColorMode      string  `json:"color_mode,omitempty"`
+	Threshold      int     `json:"threshold,omitempty"`
+	RenderFlags    string  `json:"render_flags,omitempty"`
+	Background     string  `json:"background,omitempty"`
+	Crop           string  `json:"crop,omitempty"`
+	PageBox        string  `json:"page_box,omitempty"`
+}
+
+// ManifestPage records the outcome of one page
+type ManifestPage struct {
+	Page   int    `json:"page"`
+	Status string `json:"status"`
+	File   string `json:"file,omitempty"` // Relative to the output directory
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ManifestPath returns the manifest file Convert writes for opts
+func ManifestPath(opts *ConvertOptions) string {
+	name := strings.TrimSuffix(filepath.Base(opts.InputPath), filepath.Ext(opts.InputPath))
+	return filepath.Join(opts.OutputDir, name+".pdf2img.json")
+}
+
+// ReadManifest loads a manifest written by Convert
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("manifest %s has unsupported version %d", path, m.Version)
+	}
+	return &m, nil
+}
+
+// manifestOptions returns the options recorded in the manifest for opts
+func manifestOptions(opts *ConvertOptions, dpi float64) ManifestOptions {
+	nameTemplate := opts.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+	// Color output is recorded as before color modes existed
+	colorMode := string(opts.ColorMode)
+	if opts.ColorMode == ColorModeColor {
+		colorMode = ""
+	}
+	var crop string
+	if opts.Crop != nil {
+		crop = opts.Crop.String()
+	}
+	// Crop box output is recorded as before page boxes could be chosen
+	pageBox := string(opts.PageBox)
+	if opts.PageBox == PageBoxCrop {
+		pageBox = ""
+	}
+	return ManifestOptions{
+		Format:         opts.Format,
+		DPI:            dpi,
+		Width:          opts.Width,
+		Height:         opts.Height,
+		MaxPixels:      opts.MaxPixels,
+		Prefix:         opts.Prefix,
+		NameTemplate:   nameTemplate,
+		MultiPage:      opts.MultiPage,
+		Quality:        opts.Encoding.Quality,
+		PNGCompression: opts.Encoding.PNGCompression,
+		Palette:        opts.Encoding.Palette,
+		ColorMode:      colorMode,
+		Threshold:      opts.Threshold,
+		RenderFlags:    opts.Flags.String(),
+		Background:     backgroundString(opts.Background),
+		Crop:           crop,
+		PageBox:        pageBox,
+	}
+}
+
+// completedPages returns the pages of a previous run that can be reused:
+// same input and options, and an output file that still matches its hash.
+// The result is keyed by page number.
+func (m *Manifest) completedPages(inputSHA256 string, options ManifestOptions, outputDir string) map[int]ManifestPage {
+	done := map[int]ManifestPage{}
+	if m.InputSHA256 != inputSHA256 || m.Options != options {
+		return done
+	}
+	for _, page := range m.Pages {
+		if page.Status != PageStatusDone || page.File == "" || !filepath.IsLocal(page.File) {
+			continue
+		}
+		sum, size, err := fileSHA256(filepath.Join(outputDir, page.File))
+		if err != nil || sum != page.SHA256 || size != page.Size {
+			continue
+		}
+		done[page.Page] = page
+	}
+	return done
+}
+
+// fileSHA256 returns the hex SHA-256 and size of a file
+func fileSHA256(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// manifestWriter keeps the manifest of a running conversion up to date.
+// Pages may be recorded concurrently; the file is rewritten at most once per
+// manifestFlushInterval and on close.
+type manifestWriter struct {
+	path  string
+	mu    sync.Mutex
+	m     Manifest
+	pages map[int]ManifestPage
+	saved time.Time
+}
+
+func newManifestWriter(path string, m Manifest, pages map[int]ManifestPage) *manifestWriter {
+	if pages == nil {
+		pages = map[int]ManifestPage{}
+	}
+	return &manifestWriter{path: path, m: m, pages: pages}
+}
+
+// record stores the outcome of a page, replacing any earlier entry
+func (w *manifestWriter) record(page ManifestPage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pages[page.Page] = page
+	if time.Since(w.saved) >= manifestFlushInterval {
+		// A failed intermediate write is retried on the next flush
+		_ = w.saveLocked()
+	}
+}
+
+// close writes the final manifest
+func (w *manifestWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.saveLocked()
+}
+
+// saveLocked writes the manifest through a temporary file so that a crash
+// never leaves a truncated manifest behind. It gets the permissions of the
+// images.
+func (w *manifestWriter) saveLocked() error {
+	w.m.Pages = make([]ManifestPage, 0, len(w.pages))
+	for _, page := range w.pages {
+		w.m.Pages = append(w.m.Pages, page)
+	}
+	sort.Slice(w.m.Pages, func(i, j int) bool { return w.m.Pages[i].Page < w.m.Pages[j].Page })
+	w.m.UpdatedAt = time.Now().UTC()
+	w.saved = time.Now()
+
+	data, err := json.MarshalIndent(&w.m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), ".pdf2img-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
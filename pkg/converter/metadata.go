@@ -0,0 +1,35 @@
+package converter
+
+import (
+	"context"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/tu-usuario/pdf2img/pkg/metadata"
+)
+
+// Metadata returns the document information and XMP packet of an in-memory
+// PDF. Use the metadata package to change them.
+func (c *Converter) Metadata(ctx context.Context, pdf []byte, pass string) (*metadata.Metadata, error) {
+	var meta *metadata.Metadata
+	err := c.runContext(ctx, func(instance pdfium.Pdfium) error {
+		doc, err := openDocument(instance, &pdf, pass)
+		if err != nil {
+			return err
+		}
+		defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: doc.Document,
+		})
+
+		meta, err = metadata.Read(instance, doc.Document)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// PDFium does not expose the XMP packet. Best effort: a PDF pdfcpu
+	// cannot read has none.
+	meta.XMP, _ = metadata.ReadXMP(pdf, pass)
+	return meta, nil
+}
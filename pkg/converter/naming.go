@@ -0,0 +1,210 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultNameTemplate names pages page_0001.png, page_0002.png... as
+// earlier versions did
+const DefaultNameTemplate = "{prefix}{page}.{ext}"
+
+// nameTemplate is a parsed ConvertOptions.NameTemplate. Placeholders:
+//
+//	{basename}  input file name without extension
+//	{prefix}    ConvertOptions.Prefix
+//	{page}      page number, zero-padded to the digits of {total} (at least 4)
+//	{label}     page label defined by the PDF (e.g. "iv"), the page number if none
+//	{total}     number of pages in the document
+//	{dpi}       rendering DPI (not with Width, Height or MaxPixels)
+//	{width}     image width in pixels
+//	{height}    image height in pixels
+//	{hash}      SHA-256 of the encoded image (16 hex digits)
+//	{ext}       output format
+//
+// Numeric placeholders take a zero-padded width ({page:05}) and {hash} a
+// length in hex digits ({hash:8}). "{{" and "}}" are literal braces.
+type nameTemplate struct {
+	parts []namePart
+}
+
+// namePart is a literal (field == "") or a placeholder
+type namePart struct {
+	literal string
+	field   string
+	width   int // padding for numbers, length for hash; 0 = default
+}
+
+// nameValues are the values substituted into a name template
+type nameValues struct {
+	basename string
+	ext      string
+	label    string
+	hash     string
+	page     int
+	total    int
+	width    int
+	height   int
+	dpi      float64
+}
+
+var nameFields = map[string]bool{
+	"basename": false, "prefix": false, "ext": false, "label": false, "hash": true,
+	"page": true, "total": true, "width": true, "height": true, "dpi": true,
+}
+
+// parseNameTemplate parses a file name template, filling in {prefix}, and
+// checks that it stays inside the output directory
+func parseNameTemplate(tmpl, prefix string) (*nameTemplate, error) {
+	if tmpl == "" {
+		tmpl = DefaultNameTemplate
+	}
+
+	t := &nameTemplate{}
+	var literal strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		switch {
+		case c == '{' && strings.HasPrefix(tmpl[i:], "{{"):
+			literal.WriteByte('{')
+			i++
+		case c == '}' && strings.HasPrefix(tmpl[i:], "}}"):
+			literal.WriteByte('}')
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("name template %q: unmatched '}'", tmpl)
+		case c == '{':
+			end := strings.IndexByte(tmpl[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("name template %q: unclosed '{'", tmpl)
+			}
+			part, err := parseNamePart(tmpl[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("name template %q: %w", tmpl, err)
+			}
+			i += end
+			if part.field == "prefix" {
+				literal.WriteString(prefix)
+				continue
+			}
+			if literal.Len() > 0 {
+				t.parts = append(t.parts, namePart{literal: literal.String()})
+				literal.Reset()
+			}
+			t.parts = append(t.parts, part)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, namePart{literal: literal.String()})
+	}
+
+	// Catch literal parts such as "../" or "/tmp/" before rendering anything
+	sample := &nameValues{basename: "document", ext: "png", label: "1", hash: "0", page: 1, total: 1, width: 1, height: 1, dpi: 150}
+	if _, err := t.expand(sample); err != nil {
+		return nil, fmt.Errorf("name template %q: %w", tmpl, err)
+	}
+
+	return t, nil
+}
+
+func parseNamePart(spec string) (namePart, error) {
+	field, format, hasFormat := strings.Cut(spec, ":")
+	numeric, ok := nameFields[field]
+	if !ok {
+		return namePart{}, fmt.Errorf("unknown placeholder {%s}", spec)
+	}
+
+	part := namePart{field: field}
+	if !hasFormat {
+		return part, nil
+	}
+	if !numeric {
+		return namePart{}, fmt.Errorf("{%s} does not take a width", field)
+	}
+	width, err := strconv.Atoi(format)
+	if err != nil || width < 1 || width > 64 {
+		return namePart{}, fmt.Errorf("invalid width in {%s}", spec)
+	}
+	part.width = width
+	return part, nil
+}
+
+// uses reports whether the template contains the placeholder field
+func (t *nameTemplate) uses(field string) bool {
+	for _, part := range t.parts {
+		if part.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// expand renders the file name, relative to the output directory
+func (t *nameTemplate) expand(v *nameValues) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		switch part.field {
+		case "":
+			b.WriteString(part.literal)
+		case "basename":
+			b.WriteString(sanitizeName(v.basename))
+		case "ext":
+			b.WriteString(v.ext)
+		case "label":
+			label := v.label
+			if label == "" {
+				label = strconv.Itoa(v.page)
+			}
+			b.WriteString(sanitizeName(label))
+		case "hash":
+			n := part.width
+			if n == 0 {
+				n = 16
+			}
+			b.WriteString(v.hash[:min(n, len(v.hash))])
+		case "page":
+			width := part.width
+			if width == 0 {
+				width = max(4, len(strconv.Itoa(v.total)))
+			}
+			fmt.Fprintf(&b, "%0*d", width, v.page)
+		case "total":
+			fmt.Fprintf(&b, "%0*d", part.width, v.total)
+		case "width":
+			fmt.Fprintf(&b, "%0*d", part.width, v.width)
+		case "height":
+			fmt.Fprintf(&b, "%0*d", part.width, v.height)
+		case "dpi":
+			dpi := strconv.FormatFloat(v.dpi, 'f', -1, 64)
+			if pad := part.width - len(dpi); pad > 0 {
+				dpi = strings.Repeat("0", pad) + dpi
+			}
+			b.WriteString(dpi)
+		}
+	}
+
+	name := b.String()
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("file name %q must stay inside the output directory", name)
+	}
+	return filepath.Clean(name), nil
+}
+
+// sanitizeName makes a value taken from the document or the input path
+// safe to use as part of a file name
+func sanitizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if strings.Trim(s, ".") == "" {
+		return strings.Repeat("_", len(s))
+	}
+	return s
+}
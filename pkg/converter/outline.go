@@ -0,0 +1,276 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+)
+
+// OutlineItem is an entry of the document outline (bookmarks)
+type OutlineItem struct {
+	Title string `json:"title"`
+	Level int    `json:"level"` // 1 for top-level entries
+
+	// Page is the page the entry points to (1-indexed) and EndPage the last
+	// page of its section: the page before the next entry at the same or a
+	// higher level, or that entry's page when it starts mid-page, and at
+	// least the end of the entry's children. "Page-EndPage" selects the
+	// section. Both are 0 for entries that point outside the document.
+	Page    int    `json:"page,omitempty"`
+	EndPage int    `json:"end_page,omitempty"`
+	URI     string `json:"uri,omitempty"` // Link of entries that open a web page
+
+	Children []OutlineItem `json:"children,omitempty"`
+
+	midPage bool // Starts below the top of Page, after the end of other content
+}
+
+const (
+	// maxOutlineDepth and maxOutlineItems bound the walk over outlines
+	// that are broken or nest into themselves
+	maxOutlineDepth = 32
+	maxOutlineItems = 10000
+)
+
+// Outline returns the outline (bookmarks) of an in-memory PDF as a tree,
+// or nil if it has none
+func (c *Converter) Outline(ctx context.Context, pdf []byte, pass string) ([]OutlineItem, error) {
+	var outline []OutlineItem
+	err := c.runContext(ctx, func(instance pdfium.Pdfium) error {
+		doc, err := openDocument(instance, &pdf, pass)
+		if err != nil {
+			return err
+		}
+		defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: doc.Document,
+		})
+
+		outline, err = documentOutline(instance, doc.Document)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outline, nil
+}
+
+// documentOutline reads the outline of a document open in PDFium
+func documentOutline(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT) ([]OutlineItem, error) {
+	pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{Document: doc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page count: %w", err)
+	}
+
+	w := &outlineWalker{instance: instance, doc: doc}
+	outline, err := w.children(nil, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outline: %w", err)
+	}
+
+	// go-pdfium's FPDFBookmark_GetDest searches bookmarks by title instead
+	// of returning their destination, so the destinations come from
+	// GetBookmarks. It walks the outline without limits: only call it once
+	// the outline is known to be finite.
+	if !w.truncated {
+		if bookmarks, err := instance.GetBookmarks(&requests.GetBookmarks{Document: doc}); err == nil {
+			setDestPages(outline, bookmarks.Bookmarks, w.midPage)
+		}
+	}
+
+	setEndPages(outline, pageCount.PageCount)
+	return outline, nil
+}
+
+// outlineWalker reads outline entries, counting them against maxOutlineItems
+type outlineWalker struct {
+	instance  pdfium.Pdfium
+	doc       references.FPDF_DOCUMENT
+	items     int
+	truncated bool // A limit was reached
+}
+
+// children returns the children of parent, or the top-level entries if
+// parent is nil
+func (w *outlineWalker) children(parent *references.FPDF_BOOKMARK, level int) ([]OutlineItem, error) {
+	first, err := w.instance.FPDFBookmark_GetFirstChild(&requests.FPDFBookmark_GetFirstChild{Document: w.doc, Bookmark: parent})
+	if err != nil {
+		return nil, err
+	}
+	if first.Bookmark != nil && level > maxOutlineDepth {
+		w.truncated = true
+		return nil, nil
+	}
+
+	var items []OutlineItem
+	for bookmark := first.Bookmark; bookmark != nil; {
+		if w.items == maxOutlineItems {
+			w.truncated = true
+			break
+		}
+		w.items++
+		item, err := w.item(*bookmark, level)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		next, err := w.instance.FPDFBookmark_GetNextSibling(&requests.FPDFBookmark_GetNextSibling{Document: w.doc, Bookmark: *bookmark})
+		if err != nil {
+			return nil, err
+		}
+		bookmark = next.Bookmark
+	}
+	return items, nil
+}
+
+// item reads an outline entry and its children. Entries that point to a
+// destination directly get their page from setDestPages.
+func (w *outlineWalker) item(bookmark references.FPDF_BOOKMARK, level int) (OutlineItem, error) {
+	title, err := w.instance.FPDFBookmark_GetTitle(&requests.FPDFBookmark_GetTitle{Bookmark: bookmark})
+	if err != nil {
+		return OutlineItem{}, err
+	}
+	item := OutlineItem{Title: strings.Join(strings.Fields(cleanText(title.Title)), " "), Level: level}
+
+	action, err := w.instance.FPDFBookmark_GetAction(&requests.FPDFBookmark_GetAction{Bookmark: bookmark})
+	if err != nil {
+		return OutlineItem{}, err
+	}
+	if action.Action != nil {
+		item.Page, item.URI = actionTarget(w.instance, w.doc, *action.Action)
+		if item.Page != 0 {
+			dest, err := w.instance.FPDFAction_GetDest(&requests.FPDFAction_GetDest{Document: w.doc, Action: *action.Action})
+			if err == nil && dest.Dest != nil {
+				item.midPage = w.midPage(*dest.Dest, item.Page)
+			}
+		}
+	}
+
+	if item.Children, err = w.children(&bookmark, level+1); err != nil {
+		return OutlineItem{}, err
+	}
+	return item, nil
+}
+
+// setDestPages sets the page of the entries without an action from the
+// destination of the matching bookmark, and whether it starts mid-page if
+// midPage is given
+func setDestPages(items []OutlineItem, bookmarks []responses.GetBookmarksBookmark, midPage func(dest references.FPDF_DEST, pageNum int) bool) {
+	for i := range min(len(items), len(bookmarks)) {
+		dest := bookmarks[i].DestInfo
+		if items[i].Page == 0 && items[i].URI == "" && dest != nil && dest.PageIndex >= 0 {
+			items[i].Page = dest.PageIndex + 1
+			if midPage != nil {
+				items[i].midPage = midPage(dest.Reference, items[i].Page)
+			}
+		}
+		setDestPages(items[i].Children, bookmarks[i].Children, midPage)
+	}
+}
+
+// midPage reports whether dest points below the top quarter of its page
+// (1-indexed), where a section starts after the end of the previous one.
+// Destinations without a vertical position point to the top.
+func (w *outlineWalker) midPage(dest references.FPDF_DEST, pageNum int) bool {
+	loc, err := w.instance.FPDFDest_GetLocationInPage(&requests.FPDFDest_GetLocationInPage{Dest: dest})
+	if err != nil || loc.Y == nil {
+		return false
+	}
+	page := requests.Page{ByIndex: &requests.PageByIndex{Document: w.doc, Index: pageNum - 1}}
+	rotation, err := w.instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{Page: page})
+	if err != nil || rotation.PageRotation%2 != 0 {
+		return false // Y runs across rotated pages
+	}
+	boxes, err := pageBoxes(w.instance, page)
+	if err != nil {
+		return false
+	}
+	top, height := boxes.crop.Y1, boxes.crop.Y1-boxes.crop.Y0
+	if rotation.PageRotation == enums.FPDF_PAGE_ROTATION_180_CW {
+		return float64(*loc.Y) > boxes.crop.Y0+height/4
+	}
+	return float64(*loc.Y) < top-height/4
+}
+
+// destPage returns the page (1-indexed) a destination points to, or 0 if
+// it cannot be resolved
+func destPage(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT, dest references.FPDF_DEST) int {
+	index, err := instance.FPDFDest_GetDestPageIndex(&requests.FPDFDest_GetDestPageIndex{Document: doc, Dest: dest})
+	if err != nil || index.Index < 0 {
+		return 0
+	}
+	return index.Index + 1
+}
+
+// actionTarget returns the page (1-indexed) a go-to action points to, or
+// the URI a URI action opens. Other actions have neither.
+func actionTarget(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT, action references.FPDF_ACTION) (page int, uri string) {
+	actionType, err := instance.FPDFAction_GetType(&requests.FPDFAction_GetType{Action: action})
+	if err != nil {
+		return 0, ""
+	}
+
+	switch actionType.Type {
+	case enums.FPDF_ACTION_ACTION_GOTO:
+		dest, err := instance.FPDFAction_GetDest(&requests.FPDFAction_GetDest{Document: doc, Action: action})
+		if err == nil && dest.Dest != nil {
+			return destPage(instance, doc, *dest.Dest), ""
+		}
+	case enums.FPDF_ACTION_ACTION_URI:
+		path, err := instance.FPDFAction_GetURIPath(&requests.FPDFAction_GetURIPath{Document: doc, Action: action})
+		if err == nil && path.URIPath != nil {
+			return 0, *path.URIPath
+		}
+	}
+	return 0, ""
+}
+
+// setEndPages sets the EndPage of every entry that points to a page: the
+// page before the next entry at the same or a higher level that starts on
+// a later page, that entry's page if it starts mid-page, or the last page
+// of the document. Entries end no earlier than their children.
+func setEndPages(items []OutlineItem, pageCount int) {
+	var flat []*OutlineItem
+	var walk func(items []OutlineItem)
+	walk = func(items []OutlineItem) {
+		for i := range items {
+			flat = append(flat, &items[i])
+			walk(items[i].Children)
+		}
+	}
+	walk(items)
+
+	for i, item := range flat {
+		if item.Page == 0 {
+			continue
+		}
+		item.EndPage = pageCount
+		for _, next := range flat[i+1:] {
+			if next.Level > item.Level || next.Page < item.Page || next.Page == item.Page && !next.midPage {
+				continue
+			}
+			item.EndPage = next.Page - 1
+			if next.midPage {
+				item.EndPage = next.Page
+			}
+			break
+		}
+	}
+
+	// Children come after their parent, so walking backwards extends
+	// every entry by its children once they are final
+	for i := len(flat) - 1; i >= 0; i-- {
+		if flat[i].Page == 0 {
+			continue
+		}
+		for _, child := range flat[i].Children {
+			flat[i].EndPage = max(flat[i].EndPage, child.EndPage)
+		}
+	}
+}
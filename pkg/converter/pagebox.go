@@ -0,0 +1,131 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+)
+
+// PageBox selects the page boundary that defines the rendered area
+type PageBox string
+
+const (
+	PageBoxCrop  PageBox = "crop"  // Visible area, what viewers show (default)
+	PageBoxMedia PageBox = "media" // Physical page, including printer's marks
+	PageBoxBleed PageBox = "bleed" // Trimmed page plus the bleed
+	PageBoxTrim  PageBox = "trim"  // Finished page after trimming
+	PageBoxArt   PageBox = "art"   // Meaningful content as intended by the creator
+)
+
+// ParsePageBox parses a page box name. "" is PageBoxCrop, and a "box"
+// suffix is allowed: "TrimBox" is PageBoxTrim.
+func ParsePageBox(name string) (PageBox, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return PageBoxCrop, nil
+	}
+	switch box := PageBox(strings.TrimSuffix(name, "box")); box {
+	case PageBoxCrop, PageBoxMedia, PageBoxBleed, PageBoxTrim, PageBoxArt:
+		return box, nil
+	}
+	return "", fmt.Errorf("page box must be 'media', 'crop', 'bleed', 'trim' or 'art'")
+}
+
+// customBox reports whether box renders something other than the crop box
+// PDFium renders by default
+func customBox(box PageBox) bool {
+	box, _ = ParsePageBox(string(box))
+	return box != PageBoxCrop && box != ""
+}
+
+// pageBoxSet holds the boundaries of a page in user space, with the
+// defaults of the PDF specification applied: the crop box defaults to the
+// media box, the others to the crop box, and all are clipped to the media
+// box
+type pageBoxSet struct {
+	media, crop, bleed, trim, art Rect
+}
+
+// get returns the boundary selected by box
+func (s *pageBoxSet) get(box PageBox) Rect {
+	box, _ = ParsePageBox(string(box))
+	switch box {
+	case PageBoxMedia:
+		return s.media
+	case PageBoxBleed:
+		return s.bleed
+	case PageBoxTrim:
+		return s.trim
+	case PageBoxArt:
+		return s.art
+	}
+	return s.crop
+}
+
+// pageBoxes returns the boundaries of a page. The crop box is the one
+// PDFium displays.
+func pageBoxes(instance pdfium.Pdfium, page requests.Page) (*pageBoxSet, error) {
+	mediaBox, err := instance.FPDFPage_GetMediaBox(&requests.FPDFPage_GetMediaBox{Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media box: %w", err)
+	}
+	s := &pageBoxSet{media: boxRect(mediaBox.Left, mediaBox.Bottom, mediaBox.Right, mediaBox.Top)}
+
+	s.crop = s.media
+	if box, err := instance.FPDFPage_GetCropBox(&requests.FPDFPage_GetCropBox{Page: page}); err == nil {
+		s.crop = intersectRect(s.media, boxRect(box.Left, box.Bottom, box.Right, box.Top))
+	}
+
+	// PDFium reports an error for boxes the page doesn't set
+	s.bleed, s.trim, s.art = s.crop, s.crop, s.crop
+	if box, err := instance.FPDFPage_GetBleedBox(&requests.FPDFPage_GetBleedBox{Page: page}); err == nil {
+		s.bleed = intersectRect(s.media, boxRect(box.Left, box.Bottom, box.Right, box.Top))
+	}
+	if box, err := instance.FPDFPage_GetTrimBox(&requests.FPDFPage_GetTrimBox{Page: page}); err == nil {
+		s.trim = intersectRect(s.media, boxRect(box.Left, box.Bottom, box.Right, box.Top))
+	}
+	if box, err := instance.FPDFPage_GetArtBox(&requests.FPDFPage_GetArtBox{Page: page}); err == nil {
+		s.art = intersectRect(s.media, boxRect(box.Left, box.Bottom, box.Right, box.Top))
+	}
+	return s, nil
+}
+
+// boxRect converts a PDFium box to a Rect, whatever corners it is given by
+func boxRect(left, bottom, right, top float32) Rect {
+	return Rect{
+		X0: float64(min(left, right)),
+		Y0: float64(min(bottom, top)),
+		X1: float64(max(left, right)),
+		Y1: float64(max(bottom, top)),
+	}
+}
+
+// displayedBox returns the page box selected by opts in displayed points,
+// relative to the top left corner of the page PDFium renders (the crop
+// box, rotated). Parts of the box outside the crop box have negative
+// coordinates or lie beyond the page size.
+func displayedBox(instance pdfium.Pdfium, page requests.Page, size *responses.GetPageSize, opts *RenderOptions) (Rect, error) {
+	if !customBox(opts.PageBox) {
+		return Rect{X1: size.Width, Y1: size.Height}, nil
+	}
+
+	boxes, err := pageBoxes(instance, page)
+	if err != nil {
+		return Rect{}, err
+	}
+	rotation, err := instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{Page: page})
+	if err != nil {
+		return Rect{}, fmt.Errorf("failed to get rotation: %w", err)
+	}
+
+	t := &pageTransform{
+		box:      boxes.crop,
+		rotation: int(rotation.PageRotation) % 4,
+		width:    size.Width,
+		height:   size.Height,
+	}
+	return t.rectToPoints(boxes.get(opts.PageBox)), nil
+}
@@ -0,0 +1,149 @@
+package converter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/webassembly"
+	"github.com/tetratelabs/wazero"
+)
+
+// instancePool leases PDFium instances and replaces the ones stuck in a
+// render that never returns. The webassembly pool only frees an instance
+// when it is closed, and a stuck instance can't be closed while it runs,
+// so the pool it came from is retired: later leases come from a fresh
+// pool, and the retired one is closed once all its instances are back.
+type instancePool struct {
+	size  int                     // Instances per pool
+	cache wazero.CompilationCache // Compiles PDFium once for all pools
+
+	mu      sync.Mutex
+	current *poolGeneration // nil until the next lease after a retirement
+	closed  bool
+}
+
+// poolGeneration is one webassembly pool and the instances leased from it
+type poolGeneration struct {
+	pool    pdfium.Pool
+	leased  int  // Instances not closed yet, stuck ones included
+	retired bool // No longer leased from
+}
+
+// pooledInstance is an instance leased from a poolGeneration
+type pooledInstance struct {
+	pdfium.Pdfium
+	pool *instancePool
+	gen  *poolGeneration
+}
+
+// newInstancePool creates a pool of size instances
+func newInstancePool(size int) (*instancePool, error) {
+	p := &instancePool{size: size, cache: wazero.NewCompilationCache()}
+	gen, err := p.newGeneration()
+	if err != nil {
+		return nil, err
+	}
+	p.current = gen
+	return p, nil
+}
+
+// newGeneration initializes a webassembly pool
+func (p *instancePool) newGeneration() (*poolGeneration, error) {
+	pool, err := webassembly.Init(webassembly.Config{
+		MinIdle:       1,
+		MaxIdle:       p.size,
+		MaxTotal:      p.size,
+		RuntimeConfig: wazero.NewRuntimeConfig().WithCompilationCache(p.cache),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PDFium pool: %w", err)
+	}
+	return &poolGeneration{pool: pool}, nil
+}
+
+// GetInstance leases an instance, waiting up to timeout for one to be free
+func (p *instancePool) GetInstance(timeout time.Duration) (pdfium.Pdfium, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("converter is closed")
+	}
+	if p.current == nil {
+		gen, err := p.newGeneration()
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		p.current = gen
+	}
+	gen := p.current
+	gen.leased++
+	p.mu.Unlock()
+
+	instance, err := gen.pool.GetInstance(timeout)
+	if err != nil {
+		p.release(gen)
+		return nil, err
+	}
+	return &pooledInstance{Pdfium: instance, pool: p, gen: gen}, nil
+}
+
+// Close returns the instance to its pool
+func (i *pooledInstance) Close() error {
+	err := i.Pdfium.Close()
+	i.pool.release(i.gen)
+	return err
+}
+
+// release records that an instance of gen was closed, and closes gen once
+// it is retired and idle
+func (p *instancePool) release(gen *poolGeneration) {
+	p.mu.Lock()
+	gen.leased--
+	idle := gen.leased == 0 && gen.retired
+	p.mu.Unlock()
+
+	if idle {
+		gen.pool.Close()
+	}
+}
+
+// abandon gives up on an instance that is still running. wait blocks until
+// it returns; the instance is closed then. Its pool is retired so the
+// instance can't starve later leases if it never returns.
+func (p *instancePool) abandon(instance pdfium.Pdfium, wait func()) {
+	if pi, ok := instance.(*pooledInstance); ok {
+		p.mu.Lock()
+		pi.gen.retired = true
+		if p.current == pi.gen {
+			p.current = nil
+		}
+		p.mu.Unlock()
+	}
+
+	go func() {
+		wait()
+		instance.Close()
+	}()
+}
+
+// Close closes the pools whose instances are all back. The others are
+// closed when their last instance is; a stuck instance keeps its pool open.
+func (p *instancePool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	gen := p.current
+	p.current = nil
+	var idle bool
+	if gen != nil {
+		gen.retired = true
+		idle = gen.leased == 0
+	}
+	p.mu.Unlock()
+
+	if idle {
+		gen.pool.Close()
+	}
+}
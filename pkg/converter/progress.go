@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"sync"
+	"time"
+)
+
+// ProgressEventType identifies what happened in a ProgressEvent
+type ProgressEventType string
+
+const (
+	PageStarted       ProgressEventType = "page_started"       // A worker began rendering a page
+	PageDone          ProgressEventType = "page_done"          // A page was rendered and saved
+	PageFailed        ProgressEventType = "page_failed"        // A page could not be rendered or saved
+	InstanceRefreshed ProgressEventType = "instance_refreshed" // A worker moved on to a fresh PDFium instance
+	PageRetry         ProgressEventType = "page_retry"         // A failed page is rendered again at reduced DPI
+)
+
+// ProgressEvent describes a step of a running conversion
+type ProgressEvent struct {
+	Type     ProgressEventType
+	Page     int           // Page number (1-indexed), 0 for InstanceRefreshed
+	Path     string        // Output file (PageDone)
+	Size     int64         // Output file size in bytes (PageDone)
+	Duration time.Duration // Time spent rendering and saving the page (PageDone, PageFailed)
+	Error    string        // Failure reason (PageFailed)
+
+	Completed int // Pages done or failed so far, retries are not counted twice
+	Total     int // Pages selected for conversion
+}
+
+// ProgressFunc receives progress events. Calls are serialized, so the
+// function does not need to be safe for concurrent use, but it should
+// return quickly since render workers wait for it.
+type ProgressFunc func(ProgressEvent)
+
+// progressReporter fans events from concurrent workers into a ProgressFunc
+type progressReporter struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	finished map[int]bool // Pages that already counted towards Completed
+	total    int
+}
+
+func newProgressReporter(fn ProgressFunc, total int) *progressReporter {
+	return &progressReporter{
+		fn:       fn,
+		finished: make(map[int]bool),
+		total:    total,
+	}
+}
+
+// emit fills in the counters and delivers the event
+func (p *progressReporter) emit(ev ProgressEvent) {
+	if p == nil || p.fn == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ev.Type == PageDone || ev.Type == PageFailed {
+		p.finished[ev.Page] = true
+	}
+	ev.Completed = len(p.finished)
+	ev.Total = p.total
+	p.fn(ev)
+}
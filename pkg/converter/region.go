@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// Region is a rectangle of a page as it is displayed: X and Y are the top
+// left corner measured from the top left of the page, y growing downwards,
+// after the page rotation is applied
+type Region struct {
+	X, Y          float64
+	Width, Height float64
+	Fraction      bool // Values are fractions of the page size (0-1) instead of points
+}
+
+// ParseRegion parses "x,y,width,height". Values are points unless all of
+// them are between 0 and 1, which makes them fractions of the page:
+// "0,0.5,1,0.5" is the bottom half. "" returns nil, the whole page.
+func ParseRegion(s string) (*Region, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid region %q: expected x,y,width,height", s)
+	}
+	var v [4]float64
+	fraction := true
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid region %q: expected x,y,width,height", s)
+		}
+		v[i] = f
+		fraction = fraction && f >= 0 && f <= 1
+	}
+	r := &Region{X: v[0], Y: v[1], Width: v[2], Height: v[3], Fraction: fraction}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// String formats the region as ParseRegion accepts it
+func (r Region) String() string {
+	return fmt.Sprintf("%g,%g,%g,%g", r.X, r.Y, r.Width, r.Height)
+}
+
+// validate checks the region without knowing the page
+func (r *Region) validate() error {
+	if r.X < 0 || r.Y < 0 {
+		return fmt.Errorf("region cannot start at negative coordinates")
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return fmt.Errorf("region width and height must be positive")
+	}
+	if r.Fraction && (r.X >= 1 || r.Y >= 1) {
+		return fmt.Errorf("region fractions must start inside the page")
+	}
+	return nil
+}
+
+// points returns the region in points on a displayed page of widthPt x
+// heightPt points, clipped to the page
+func (r *Region) points(widthPt, heightPt float64) (Rect, error) {
+	rect := Rect{X0: r.X, Y0: r.Y, X1: r.X + r.Width, Y1: r.Y + r.Height}
+	if r.Fraction {
+		rect = Rect{X0: rect.X0 * widthPt, Y0: rect.Y0 * heightPt, X1: rect.X1 * widthPt, Y1: rect.Y1 * heightPt}
+	}
+	rect.X1, rect.Y1 = min(rect.X1, widthPt), min(rect.Y1, heightPt)
+	if rect.X0 >= rect.X1 || rect.Y0 >= rect.Y1 {
+		return Rect{}, fmt.Errorf("region %s is outside the page (%gx%g points)", r, widthPt, heightPt)
+	}
+	return rect, nil
+}
+
+// RenderRegion renders a rectangle of a single page (1-indexed) of an
+// in-memory PDF, e.g. a figure or a signature at 600 DPI, without
+// rendering the rest of the page. Only the region is sized by opts:
+// Width, Height and MaxPixels apply to it rather than to the page. With
+// opts.PageBox the region is measured in the selected box.
+func (c *Converter) RenderRegion(ctx context.Context, pdf []byte, pageNum int, region Region, opts *RenderOptions) (image.Image, error) {
+	opts = opts.withDefaults()
+	opts.Crop = &region
+	return c.RenderPage(ctx, pdf, pageNum, opts)
+}
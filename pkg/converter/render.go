@@ -0,0 +1,259 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"iter"
+	"math"
+	"time"
+
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+)
+
+// RenderOptions controls how a page is rendered in memory
+type RenderOptions struct {
+	DPI          float64       // DPI for rendering (default 150)
+	Width        int           // Render pages this many pixels wide instead of using DPI
+	Height       int           // Render pages this many pixels tall instead of using DPI (with Width: fit inside the box)
+	MaxPixels    int           // Scale down pages whose width*height would exceed this (0 = no limit)
+	PageTimeout  time.Duration // Give up on a page after this long (0 = no limit)
+	RefreshEvery int           // RenderPages: refresh the PDFium instance every N pages (default 50)
+	Encoding     EncodeOptions // EncodePage: encoder settings
+	Password     string        // Password of an encrypted PDF (user or owner password)
+
+	// ColorMode renders pages in color (default), 8-bit gray or 1-bit
+	// black and white. Threshold is the gray level (1-255, default 128)
+	// below which bilevel and dithered pixels turn black.
+	ColorMode ColorMode
+	Threshold int
+
+	// Flags switches annotations, form fields, print mode and anti-aliasing
+	Flags RenderFlags
+
+	// Background fills the page before it is drawn (nil = white). A
+	// transparent color leaves unpainted areas transparent in PNG, WebP
+	// and TIFF; JPEG output always gets an opaque background.
+	Background color.Color
+
+	// Crop renders only this rectangle of the page (nil = whole page).
+	// Width, Height and MaxPixels then size the rectangle.
+	Crop *Region
+
+	// PageBox is the page boundary rendered (default PageBoxCrop, what
+	// viewers show). PageBoxBleed and PageBoxMedia include content
+	// outside the crop box; Crop is relative to the selected box.
+	PageBox PageBox
+}
+
+// RenderedPage is a page produced by RenderPages
+type RenderedPage struct {
+	Page  int         // Page number (1-indexed)
+	Image image.Image // Rendered page, owned by the caller
+}
+
+// RenderPage renders a single page (1-indexed) of an in-memory PDF and
+// returns the image without writing anything to disk
+func (c *Converter) RenderPage(ctx context.Context, pdf []byte, pageNum int, opts *RenderOptions) (image.Image, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	w, err := newRenderWorker(c.pool, pdf, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	if err := w.checkPage(pageNum); err != nil {
+		return nil, err
+	}
+
+	img, release, err := w.renderImage(ctx, pageNum, opts)
+	if err != nil {
+		return nil, fmt.Errorf("page %d: %w", pageNum, err)
+	}
+	defer release()
+
+	return pageImage(img, opts), nil
+}
+
+// RenderPages renders the pages picked by a page selection expression
+// ("" for all pages, "1-3,7", "last 5"... see pagesel) of an in-memory PDF
+// one after another on a single PDFium instance. Failed pages are yielded
+// with their error and iteration continues until the consumer stops or ctx
+// is done:
+//
+//	for page, err := range conv.RenderPages(ctx, pdf, "1-3,odd", nil) {
+//		...
+//	}
+func (c *Converter) RenderPages(ctx context.Context, pdf []byte, pages string, opts *RenderOptions) iter.Seq2[RenderedPage, error] {
+	opts = opts.withDefaults()
+
+	return func(yield func(RenderedPage, error) bool) {
+		if err := opts.validate(); err != nil {
+			yield(RenderedPage{}, err)
+			return
+		}
+		sel, err := pagesel.Parse(pages)
+		if err != nil {
+			yield(RenderedPage{}, err)
+			return
+		}
+
+		w, err := newRenderWorker(c.pool, pdf, opts.Password)
+		if err != nil {
+			yield(RenderedPage{}, err)
+			return
+		}
+		defer w.close()
+
+		pageCount, err := w.pageCount()
+		if err != nil {
+			yield(RenderedPage{}, err)
+			return
+		}
+		pageNums, err := sel.Pages(pageCount)
+		if err != nil {
+			yield(RenderedPage{}, err)
+			return
+		}
+
+		for i, pageNum := range pageNums {
+			if err := ctx.Err(); err != nil {
+				yield(RenderedPage{Page: pageNum}, err)
+				return
+			}
+
+			// Refresh periodically, and always after an abandoned render
+			if w.instance == nil || (i > 0 && i%opts.RefreshEvery == 0) {
+				if err := w.refresh(); err != nil {
+					yield(RenderedPage{Page: pageNum}, err)
+					return
+				}
+			}
+
+			page := RenderedPage{Page: pageNum}
+			img, release, err := w.renderImage(ctx, pageNum, opts)
+			if err != nil {
+				err = fmt.Errorf("page %d: %w", pageNum, err)
+			} else {
+				page.Image = pageImage(img, opts)
+				release()
+			}
+
+			if !yield(page, err) || ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// EncodePage renders a single page and writes it to out in the given format
+func (c *Converter) EncodePage(ctx context.Context, out io.Writer, pdf []byte, pageNum int, format string, opts *RenderOptions) error {
+	format, err := normalizeFormat(format)
+	if err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	if err := opts.Encoding.validate(); err != nil {
+		return err
+	}
+	opts.Background = formatBackground(format, opts.Background)
+
+	img, err := c.RenderPage(ctx, pdf, pageNum, opts)
+	if err != nil {
+		return err
+	}
+
+	return encodeImage(out, img, format, &opts.Encoding)
+}
+
+// withDefaults returns a copy of the options with defaults filled in
+func (o *RenderOptions) withDefaults() *RenderOptions {
+	opts := RenderOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.DPI <= 0 {
+		opts.DPI = 150
+	}
+	if opts.RefreshEvery <= 0 {
+		opts.RefreshEvery = 50
+	}
+	return &opts
+}
+
+// validate checks the color and crop settings
+func (o *RenderOptions) validate() error {
+	if err := validateColor(o.ColorMode, o.Threshold, &o.Encoding); err != nil {
+		return err
+	}
+	box, err := ParsePageBox(string(o.PageBox))
+	if err != nil {
+		return err
+	}
+	o.PageBox = box
+	if o.Crop != nil {
+		return o.Crop.validate()
+	}
+	return nil
+}
+
+// sized reports whether pages need their size in points to be rendered
+func (o *RenderOptions) sized() bool {
+	return o.Width > 0 || o.Height > 0 || o.MaxPixels > 0
+}
+
+// pageSizeInPixels computes the pixel size of a page of widthPt x heightPt
+// points. Width and/or Height select the scale (both: fit inside the box,
+// keeping the aspect ratio), otherwise DPI does; MaxPixels then caps the
+// area. ok is false when the page can simply be rendered at DPI.
+func pageSizeInPixels(widthPt, heightPt float64, opts *RenderOptions) (width, height int, ok bool) {
+	if widthPt <= 0 || heightPt <= 0 {
+		return 0, 0, false
+	}
+
+	var scale float64
+	switch {
+	case opts.Width > 0 && opts.Height > 0:
+		scale = math.Min(float64(opts.Width)/widthPt, float64(opts.Height)/heightPt)
+	case opts.Width > 0:
+		scale = float64(opts.Width) / widthPt
+	case opts.Height > 0:
+		scale = float64(opts.Height) / heightPt
+	default:
+		scale = opts.DPI / 72
+		if opts.MaxPixels <= 0 || widthPt*scale*heightPt*scale <= float64(opts.MaxPixels) {
+			return 0, 0, false
+		}
+	}
+
+	if area := widthPt * scale * heightPt * scale; opts.MaxPixels > 0 && area > float64(opts.MaxPixels) {
+		scale *= math.Sqrt(float64(opts.MaxPixels) / area)
+	}
+
+	width = max(1, int(math.Round(widthPt*scale)))
+	height = max(1, int(math.Round(heightPt*scale)))
+
+	// Rounding may overshoot the cap by a row or column
+	for opts.MaxPixels > 0 && width*height > opts.MaxPixels && width > 1 && height > 1 {
+		if width >= height {
+			width--
+		} else {
+			height--
+		}
+	}
+	return width, height, true
+}
+
+// cloneImage copies a rendered image out of WASM memory so it stays valid
+// after the render is released
+func cloneImage(img *image.RGBA) *image.RGBA {
+	clone := *img
+	clone.Pix = append([]uint8(nil), img.Pix...)
+	return &clone
+}
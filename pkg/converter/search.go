@@ -0,0 +1,327 @@
+package converter
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+)
+
+// SearchOptions controls Search. The pixel settings match ConvertOptions:
+// with the same DPI, Width, Height, MaxPixels, PageBox and Crop the hit
+// rectangles line up with the images Convert writes.
+type SearchOptions struct {
+	Regex         bool    // The query is a regular expression (RE2 syntax) instead of plain text
+	CaseSensitive bool    // Match case (default: case-insensitive)
+	ContextChars  int     // Characters of context on each side of a hit in its snippet (default 40)
+	MaxHits       int     // Stop after this many hits (0 = no limit)
+	DPI           float64 // DPI of the images the pixel rectangles refer to (default 150)
+	Width         int     // Images rendered this many pixels wide
+	Height        int     // Images rendered this many pixels tall
+	MaxPixels     int     // Images scaled down to at most this many pixels
+	PageBox       PageBox // Page boundary the images show (default PageBoxCrop)
+	Crop          *Region // Images show only this rectangle of the page box
+	Password      string  // Password of an encrypted PDF (user or owner password)
+
+	// HighlightDir, if set, receives an image of every page with hits,
+	// rendered with the settings above and the hits highlighted. Files are
+	// named hits_0001.png, hits_0002.png...
+	HighlightDir string
+	Format       string // Format of the highlighted images (default "png")
+}
+
+// SearchResult lists the hits of a search, grouped by page
+type SearchResult struct {
+	Query      string       `json:"query"`
+	TotalPages int          `json:"total_pages"`         // Pages in the document
+	Hits       int          `json:"hits"`                // Hits on all pages
+	Truncated  bool         `json:"truncated,omitempty"` // SearchOptions.MaxHits was reached
+	Pages      []SearchPage `json:"pages"`               // Pages with hits in ascending order
+}
+
+// SearchPage holds the hits on one page
+type SearchPage struct {
+	Page        int         `json:"page"`         // Page number (1-indexed)
+	PixelWidth  int         `json:"pixel_width"`  // Width of the rendered image
+	PixelHeight int         `json:"pixel_height"` // Height of the rendered image
+	Image       string      `json:"image,omitempty"`
+	Hits        []SearchHit `json:"hits"`
+}
+
+// SearchHit is one occurrence of the query
+type SearchHit struct {
+	Text    string `json:"text"`    // Matched text
+	Snippet string `json:"snippet"` // Matched text with the text around it, on one line
+	Boxes   []Box  `json:"boxes"`   // One rectangle per line the hit spans
+}
+
+// defaultSearchContext is the snippet context on each side of a hit
+const defaultSearchContext = 40
+
+// highlightColor marks hits like a highlighter pen: translucent yellow
+var highlightColor = color.NRGBA{R: 255, G: 214, B: 0, A: 110}
+
+// Search finds query on the selected pages (see pagesel, "" = all pages) of
+// an in-memory PDF. Plain text queries match across line breaks and any run
+// of whitespace in the query matches any run of whitespace on the page.
+func (c *Converter) Search(ctx context.Context, pdf []byte, pages, query string, opts *SearchOptions) (*SearchResult, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	re, err := compileQuery(query, opts.Regex, opts.CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ContextChars < 0 || opts.MaxHits < 0 {
+		return nil, fmt.Errorf("context chars and max hits cannot be negative")
+	}
+	contextChars := opts.ContextChars
+	if contextChars == 0 {
+		contextChars = defaultSearchContext
+	}
+
+	var format string
+	var names *nameTemplate
+	if opts.HighlightDir != "" {
+		if format, err = normalizeFormat(cmp.Or(opts.Format, "png")); err != nil {
+			return nil, err
+		}
+		if names, err = parseNameTemplate(DefaultNameTemplate, "hits_"); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(opts.HighlightDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	render := (&RenderOptions{
+		DPI:       opts.DPI,
+		Width:     opts.Width,
+		Height:    opts.Height,
+		MaxPixels: opts.MaxPixels,
+		PageBox:   opts.PageBox,
+		Crop:      opts.Crop,
+	}).withDefaults()
+	if err := render.validate(); err != nil {
+		return nil, err
+	}
+
+	sel, err := pagesel.Parse(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newRenderWorker(c.pool, pdf, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	pageCount, err := w.pageCount()
+	if err != nil {
+		return nil, err
+	}
+	pageNums, err := sel.Pages(pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Query: query, TotalPages: pageCount, Pages: []SearchPage{}}
+	for _, pageNum := range pageNums {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		t, chars, err := w.pageChars(pageNum, render)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pageNum, err)
+		}
+
+		text := newSearchText(chars)
+		page := SearchPage{Page: pageNum, PixelWidth: t.pixelWidth, PixelHeight: t.pixelHeight}
+		for _, m := range re.FindAllStringIndex(text.text, -1) {
+			if m[0] == m[1] {
+				continue
+			}
+			if opts.MaxHits > 0 && result.Hits == opts.MaxHits {
+				result.Truncated = true
+				break
+			}
+			page.Hits = append(page.Hits, text.hit(chars, m[0], m[1], contextChars))
+			result.Hits++
+		}
+		if len(page.Hits) == 0 {
+			continue
+		}
+
+		if names != nil {
+			values := &nameValues{ext: format, page: pageNum, total: pageCount}
+			if page.Image, err = w.saveHighlighted(ctx, pageNum, render, page.Hits, names, values, opts.HighlightDir); err != nil {
+				return nil, fmt.Errorf("page %d: %w", pageNum, err)
+			}
+		}
+		result.Pages = append(result.Pages, page)
+		if result.Truncated {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// compileQuery turns a search query into a regular expression
+func compileQuery(query string, isRegex, caseSensitive bool) (*regexp.Regexp, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+	expr := query
+	if !isRegex {
+		// Page text has whitespace collapsed to single spaces (see newSearchText)
+		expr = regexp.QuoteMeta(strings.Join(strings.Fields(query), " "))
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+	return re, nil
+}
+
+// searchText is the text of a page as it is searched: the characters in
+// reading order with line breaks and whitespace runs collapsed to a single
+// space. index maps every byte of text to the character it comes from.
+type searchText struct {
+	text  string
+	index []int
+}
+
+func newSearchText(chars []TextChar) searchText {
+	var b strings.Builder
+	var index []int
+	space := true // Drop leading whitespace
+	for i, c := range chars {
+		s := cleanText(c.Text)
+		switch {
+		case s == "":
+			continue
+		case strings.TrimSpace(s) == "":
+			if space {
+				continue
+			}
+			b.WriteByte(' ')
+			index = append(index, i)
+			space = true
+		default:
+			b.WriteString(s)
+			for range len(s) {
+				index = append(index, i)
+			}
+			space = false
+		}
+	}
+	return searchText{text: b.String(), index: index}
+}
+
+// hit describes the match text[start:end]
+func (s searchText) hit(chars []TextChar, start, end, contextChars int) SearchHit {
+	return SearchHit{
+		Text:    s.text[start:end],
+		Snippet: snippet(s.text, start, end, contextChars),
+		Boxes:   hitBoxes(chars, s.index[start], s.index[end-1]),
+	}
+}
+
+// snippet returns text[start:end] with up to contextChars characters on
+// each side, marking cut text with an ellipsis
+func snippet(text string, start, end, contextChars int) string {
+	before, after := text[:start], text[end:]
+	if utf8.RuneCountInString(before) > contextChars {
+		runes := []rune(before)
+		before = "…" + strings.TrimLeft(string(runes[len(runes)-contextChars:]), " ")
+	}
+	if utf8.RuneCountInString(after) > contextChars {
+		after = strings.TrimRight(string([]rune(after)[:contextChars]), " ") + "…"
+	}
+	return before + text[start:end] + after
+}
+
+// hitBoxes returns the rectangles covering chars[first:last+1], one per
+// line: whitespace is left out and a new rectangle starts after a line
+// break or a gap that splits a line (see wideGap)
+func hitBoxes(chars []TextChar, first, last int) []Box {
+	var boxes []Box
+	var line []Box
+	var prev *TextChar
+	newLine := false
+	for i := first; i <= last; i++ {
+		c := &chars[i]
+		r, _ := utf8.DecodeRuneInString(c.Text)
+		if c.Text == "" || unicode.IsSpace(r) || unicode.IsControl(r) {
+			newLine = newLine || r == '\r' || r == '\n'
+			continue
+		}
+		if len(line) > 0 && (newLine || wideGap(prev.Points, *c)) {
+			boxes = append(boxes, unionBoxes(line))
+			line = nil
+		}
+		line = append(line, c.Box)
+		prev, newLine = c, false
+	}
+	if len(line) > 0 {
+		boxes = append(boxes, unionBoxes(line))
+	}
+	return boxes
+}
+
+// saveHighlighted renders pageNum with the hits highlighted and writes it
+// to dir, returning the path of the image
+func (w *renderWorker) saveHighlighted(ctx context.Context, pageNum int, render *RenderOptions, hits []SearchHit, names *nameTemplate, values *nameValues, dir string) (string, error) {
+	rendered, release, err := w.renderImage(ctx, pageNum, render)
+	if err != nil {
+		return "", err
+	}
+	img := cloneImage(rendered)
+	release()
+
+	highlight(img, hits)
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, values.ext, &render.Encoding); err != nil {
+		return "", err
+	}
+	values.width, values.height = img.Bounds().Dx(), img.Bounds().Dy()
+	name, err := names.expand(values)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to create output file: %w", err)
+	}
+	return path, nil
+}
+
+// highlight paints highlightColor over the pixel rectangles of the hits
+func highlight(img draw.Image, hits []SearchHit) {
+	src := image.NewUniform(highlightColor)
+	for _, hit := range hits {
+		for _, box := range hit.Boxes {
+			p := box.Pixels
+			r := image.Rect(int(math.Floor(p.X0))-1, int(math.Floor(p.Y0))-1, int(math.Ceil(p.X1))+1, int(math.Ceil(p.Y1))+1)
+			draw.Draw(img, r.Intersect(img.Bounds()), src, image.Point{}, draw.Over)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+)
+
+// TextOptions controls text extraction
+type TextOptions struct {
+	MaxChars int    // Truncate the text of each page to this many characters (0 = no limit)
+	Password string // Password of an encrypted PDF (user or owner password)
+}
+
+// TextResult is the text of the selected pages of a document
+type TextResult struct {
+	TotalPages int        // Pages in the document
+	Pages      []PageText // Selected pages in ascending order
+}
+
+// PageText is the plain text of one page
+type PageText struct {
+	Page      int    `json:"page"`                // Page number (1-indexed)
+	Text      string `json:"text"`                // Text in reading order, lines separated by "\n"
+	Chars     int    `json:"chars"`               // Characters on the page before truncation
+	Truncated bool   `json:"truncated,omitempty"` // Text was cut to TextOptions.MaxChars
+}
+
+// ExtractText returns the plain text of the selected pages (see pagesel,
+// "" = all pages) of an in-memory PDF. Pages without a text layer, such as
+// scans, have empty text.
+func (c *Converter) ExtractText(ctx context.Context, pdf []byte, pages string, opts *TextOptions) (*TextResult, error) {
+	if opts == nil {
+		opts = &TextOptions{}
+	}
+	if opts.MaxChars < 0 {
+		return nil, fmt.Errorf("max chars cannot be negative")
+	}
+	sel, err := pagesel.Parse(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newRenderWorker(c.pool, pdf, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+
+	pageCount, err := w.pageCount()
+	if err != nil {
+		return nil, err
+	}
+	pageNums, err := sel.Pages(pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TextResult{TotalPages: pageCount, Pages: make([]PageText, 0, len(pageNums))}
+	for _, pageNum := range pageNums {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		text, err := w.pageText(pageNum)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		page := PageText{Page: pageNum, Text: text, Chars: utf8.RuneCountInString(text)}
+		page.Text, page.Truncated = truncateText(text, opts.MaxChars)
+		result.Pages = append(result.Pages, page)
+	}
+
+	return result, nil
+}
+
+// pageText returns the cleaned up text of pageNum (1-indexed)
+func (w *renderWorker) pageText(pageNum int) (string, error) {
+	res, err := w.instance.GetPageText(&requests.GetPageText{
+		Page: requests.Page{
+			ByIndex: &requests.PageByIndex{
+				Document: w.doc,
+				Index:    pageNum - 1,
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get text: %w", err)
+	}
+	return cleanText(res.Text), nil
+}
+
+// cleanText normalizes the line breaks PDFium generates to "\n" and drops
+// the control characters it uses as markers, e.g. for hyphenation
+func cleanText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r':
+			return '\n'
+		case r == '\n' || r == '\t' || r == '\f':
+			return r
+		case r < 0x20 || r == 0x7f || r == 0xfffe || r == 0xffff:
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// truncateText cuts s to at most maxChars characters (0 = no limit)
+func truncateText(s string, maxChars int) (string, bool) {
+	if maxChars <= 0 || utf8.RuneCountInString(s) <= maxChars {
+		return s, false
+	}
+	i, n := 0, 0
+	for i = range s {
+		if n == maxChars {
+			break
+		}
+		n++
+	}
+	return s[:i], true
+}
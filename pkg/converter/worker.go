@@ -0,0 +1,257 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+	"github.com/tu-usuario/pdf2img/pkg/password"
+)
+
+// renderWorker owns a PDFium instance leased from the pool together with
+// the document opened in it. A worker is used by a single goroutine only.
+type renderWorker struct {
+	pool     *instancePool
+	instance pdfium.Pdfium
+	doc      references.FPDF_DOCUMENT
+	pdfBytes []byte
+	password string
+}
+
+// newRenderWorker leases an instance from the pool and opens the document in it
+func newRenderWorker(pool *instancePool, pdfBytes []byte, pass string) (*renderWorker, error) {
+	w := &renderWorker{
+		pool:     pool,
+		pdfBytes: pdfBytes,
+		password: pass,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *renderWorker) open() error {
+	instance, err := w.pool.GetInstance(time.Second * 30)
+	if err != nil {
+		return fmt.Errorf("failed to get PDFium instance: %w", err)
+	}
+
+	doc, err := openDocument(instance, &w.pdfBytes, w.password)
+	if err != nil {
+		instance.Close()
+		return err
+	}
+
+	w.instance = instance
+	w.doc = doc.Document
+	return nil
+}
+
+// openDocument opens a PDF in instance. Password problems are reported as
+// password.ErrRequired or password.ErrWrong.
+func openDocument(instance pdfium.Pdfium, pdfBytes *[]byte, pass string) (*responses.OpenDocument, error) {
+	req := &requests.OpenDocument{File: pdfBytes}
+	if pass != "" {
+		req.Password = &pass
+	}
+	doc, err := instance.OpenDocument(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", password.Check(err, pass))
+	}
+	return doc, nil
+}
+
+// refresh closes the document, returns the instance to the pool and reopens
+// the document in a fresh instance to clear accumulated WASM state
+func (w *renderWorker) refresh() error {
+	w.close()
+	return w.open()
+}
+
+// close releases the document and returns the instance to the pool
+func (w *renderWorker) close() {
+	if w.instance == nil {
+		return
+	}
+	w.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+		Document: w.doc,
+	})
+	w.instance.Close()
+	w.instance = nil
+}
+
+// errNoImage is returned when PDFium reports success without an image
+var errNoImage = errors.New("no image generated")
+
+// pageCount returns the number of pages of the worker's document
+func (w *renderWorker) pageCount() (int, error) {
+	res, err := w.instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+		Document: w.doc,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get page count: %w", err)
+	}
+	return res.PageCount, nil
+}
+
+// checkPage returns an error if pageNum (1-indexed) is not in the document
+func (w *renderWorker) checkPage(pageNum int) error {
+	pageCount, err := w.pageCount()
+	if err != nil {
+		return err
+	}
+	if pageNum < 1 || pageNum > pageCount {
+		return fmt.Errorf("page %d out of range (document has %d pages)", pageNum, pageCount)
+	}
+	return nil
+}
+
+// pageLabel returns the label the document defines for pageNum (1-indexed),
+// or "" when it has none
+func (w *renderWorker) pageLabel(pageNum int) string {
+	res, err := w.instance.FPDF_GetPageLabel(&requests.FPDF_GetPageLabel{
+		Document: w.doc,
+		Page:     pageNum - 1,
+	})
+	if err != nil {
+		return ""
+	}
+	return res.Label
+}
+
+// renderImage renders a page as described by opts. The image aliases WASM
+// memory: it is only valid until release is called, and release must be
+// called before the worker renders again or is closed.
+func (w *renderWorker) renderImage(ctx context.Context, pageNum int, opts *RenderOptions) (img *image.RGBA, release func(), err error) {
+	res, err := w.renderContext(ctx, pageNum, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.img == nil {
+		res.cleanup()
+		return nil, nil, errNoImage
+	}
+
+	return res.img, res.cleanup, nil
+}
+
+// renderPageFunc renders a page for renderContext; tests replace it to
+// simulate renders that never return
+var renderPageFunc = renderPage
+
+// renderResult carries a rendered page and the function that releases it
+type renderResult struct {
+	img     *image.RGBA
+	cleanup func()
+	err     error
+}
+
+// renderContext renders a page but gives up when ctx is done or the render
+// takes longer than opts.PageTimeout (0 = no limit). The worker gives up
+// its instance in that case and must be reopened before it is used again.
+func (w *renderWorker) renderContext(ctx context.Context, pageNum int, opts *RenderOptions) (renderResult, error) {
+	if opts.PageTimeout <= 0 && ctx.Done() == nil {
+		res := renderPageFunc(w.instance, w.doc, pageNum, opts)
+		return res, res.err
+	}
+
+	instance, doc := w.instance, w.doc
+	done := make(chan renderResult, 1)
+	go func() {
+		done <- renderPageFunc(instance, doc, pageNum, opts)
+	}()
+
+	var expired <-chan time.Time
+	if opts.PageTimeout > 0 {
+		timer := time.NewTimer(opts.PageTimeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case res := <-done:
+		return res, res.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-expired:
+		err = ErrPageTimeout
+	}
+
+	w.abandon(done)
+	return renderResult{}, err
+}
+
+// abandon detaches the worker from an instance that is still rendering.
+// The pool leases later instances from a fresh pool, so a render that
+// never returns doesn't starve the other pages, and closes the instance
+// once the render returns. Kill can't be used here: on the webassembly
+// runtime it never hands the worker back to the pool.
+func (w *renderWorker) abandon(done <-chan renderResult) {
+	instance := w.instance
+	w.instance = nil
+
+	w.pool.abandon(instance, func() {
+		if res := <-done; res.err == nil {
+			res.cleanup()
+		}
+	})
+}
+
+// renderPage renders a single page (1-indexed) of doc. Pages are rendered
+// at opts.DPI unless a target size or pixel cap applies, in which case the
+// pixel size is computed from the page size and rendered exactly. Pages
+// get a white background unless opts.Background sets another, opts.PageBox
+// selects the page boundary and opts.Crop renders part of it only.
+func renderPage(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT, pageNum int, opts *RenderOptions) renderResult {
+	page := requests.Page{
+		ByIndex: &requests.PageByIndex{
+			Document: doc,
+			Index:    pageNum - 1,
+		},
+	}
+
+	if opts.Background != nil || opts.Crop != nil || customBox(opts.PageBox) {
+		return renderBitmap(instance, doc, page, opts)
+	}
+
+	if opts.sized() {
+		size, err := instance.GetPageSize(&requests.GetPageSize{Page: page})
+		if err != nil {
+			return renderResult{err: fmt.Errorf("failed to get page size: %w", err)}
+		}
+
+		if width, height, ok := pageSizeInPixels(size.Width, size.Height, opts); ok {
+			res, err := instance.RenderPageInPixels(&requests.RenderPageInPixels{
+				Page:        page,
+				Width:       width,
+				Height:      height,
+				RenderFlags: renderFlags(opts),
+				RenderForm:  opts.Flags.Forms,
+			})
+			if err != nil {
+				return renderResult{err: err}
+			}
+			return renderResult{img: res.Result.Image, cleanup: res.Cleanup}
+		}
+	}
+
+	res, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+		DPI:         int(opts.DPI),
+		Page:        page,
+		RenderFlags: renderFlags(opts),
+		RenderForm:  opts.Flags.Forms,
+	})
+	if err != nil {
+		return renderResult{err: err}
+	}
+	return renderResult{img: res.Result.Image, cleanup: res.Cleanup}
+}
@@ -0,0 +1,464 @@
+// Package forms lists and fills the fields of AcroForm PDF forms.
+//
+// Listing and filling use pdfcpu, which writes the appearance of the filled
+// fields. Flatten, which turns the fields into page content, uses PDFium
+// like the rest of pdf2img.
+package forms
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/form"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/tu-usuario/pdf2img/pkg/password"
+)
+
+// Field types
+const (
+	TypeText     = "text"
+	TypeDate     = "date"
+	TypeCheckBox = "checkbox"
+	TypeRadio    = "radio"
+	TypeComboBox = "combobox"
+	TypeListBox  = "listbox"
+)
+
+// Field is a form field
+type Field struct {
+	Name    string `json:"name"`               // Fully qualified name, e.g. "applicant.name"
+	ID      string `json:"id"`                 // Object numbers of the field, for fields without a unique name
+	AltName string `json:"alt_name,omitempty"` // Description shown as tooltip, often the label
+	Type    string `json:"type"`               // One of the Type constants
+
+	// Value is a string, a bool for checkboxes and a list of strings for
+	// list boxes
+	Value   any      `json:"value"`
+	Options []string `json:"options,omitempty"` // Choices of radio buttons, combo and list boxes
+
+	Format    string   `json:"format,omitempty"`    // Date format, e.g. "dd.mm.yyyy"
+	MaxLen    int      `json:"max_len,omitempty"`   // Maximum length of a text field
+	Multiline bool     `json:"multiline,omitempty"` // Text field with several lines
+	Editable  bool     `json:"editable,omitempty"`  // Combo box that accepts values outside Options
+	Multi     bool     `json:"multi,omitempty"`     // List box that accepts several values
+	ReadOnly  bool     `json:"read_only,omitempty"` // Cannot be filled
+	Widgets   []Widget `json:"widgets"`             // Where the field is shown
+}
+
+// Widget is a place where a field is shown
+type Widget struct {
+	Page int  `json:"page"` // Page number (1-indexed)
+	Rect Rect `json:"rect"` // In PDF user space
+}
+
+// Rect is a rectangle in PDF user space (x0,y0 = left,bottom)
+type Rect struct {
+	X0 float64 `json:"x0"`
+	Y0 float64 `json:"y0"`
+	X1 float64 `json:"x1"`
+	Y1 float64 `json:"y1"`
+}
+
+// List returns the fields of a PDF form in reading order: by page, then
+// from top to bottom and left to right. A PDF without a form has none.
+func List(pdf []byte, pass string) ([]Field, error) {
+	ctx, err := readContext(pdf, pass)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Form == nil {
+		return []Field{}, nil
+	}
+
+	group, _, err := form.ExportForm(ctx.XRefTable, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form: %w", err)
+	}
+	fields := exportedFields(group.Forms[0])
+
+	widgets, err := fieldWidgets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fields {
+		fields[i].Widgets = widgets[fields[i].ID]
+		if fields[i].Widgets == nil {
+			fields[i].Widgets = []Widget{}
+		}
+	}
+	sortFields(fields)
+	return fields, nil
+}
+
+// exportedFields converts the fields pdfcpu exports
+func exportedFields(f form.Form) []Field {
+	var fields []Field
+	for _, tf := range f.TextFields {
+		fields = append(fields, Field{Name: tf.Name, ID: tf.ID, AltName: tf.AltName, Type: TypeText, Value: tf.Value,
+			MaxLen: tf.MaxLen, Multiline: tf.Multiline, ReadOnly: tf.Locked})
+	}
+	for _, df := range f.DateFields {
+		fields = append(fields, Field{Name: df.Name, ID: df.ID, AltName: df.AltName, Type: TypeDate, Value: df.Value,
+			Format: df.Format, ReadOnly: df.Locked})
+	}
+	for _, cb := range f.CheckBoxes {
+		fields = append(fields, Field{Name: cb.Name, ID: cb.ID, AltName: cb.AltName, Type: TypeCheckBox, Value: cb.Value,
+			ReadOnly: cb.Locked})
+	}
+	for _, rb := range f.RadioButtonGroups {
+		fields = append(fields, Field{Name: rb.Name, ID: rb.ID, AltName: rb.AltName, Type: TypeRadio, Value: rb.Value,
+			Options: rb.Options, ReadOnly: rb.Locked})
+	}
+	for _, cb := range f.ComboBoxes {
+		fields = append(fields, Field{Name: cb.Name, ID: cb.ID, AltName: cb.AltName, Type: TypeComboBox, Value: cb.Value,
+			Options: cb.Options, Editable: cb.Editable, ReadOnly: cb.Locked})
+	}
+	for _, lb := range f.ListBoxes {
+		values := lb.Values
+		if values == nil {
+			values = []string{}
+		}
+		fields = append(fields, Field{Name: lb.Name, ID: lb.ID, AltName: lb.AltName, Type: TypeListBox, Value: values,
+			Options: lb.Options, Multi: lb.Multi, ReadOnly: lb.Locked})
+	}
+	return fields
+}
+
+// fieldWidgets returns the widgets of every field, keyed by field ID. The
+// field of a widget is found as pdfcpu does: radio buttons and text fields
+// may have several widgets below the field, other fields are merged with
+// their widget.
+func fieldWidgets(ctx *model.Context) (map[string][]Widget, error) {
+	widgets := map[string][]Widget{}
+	for pageNum := 1; pageNum <= ctx.PageCount; pageNum++ {
+		d, _, _, err := ctx.PageDict(pageNum, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", pageNum, err)
+		}
+		obj, ok := d.Find("Annots")
+		if !ok {
+			continue
+		}
+		annots, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read annotations of page %d: %w", pageNum, err)
+		}
+
+		for _, obj := range annots {
+			ref, ok := obj.(types.IndirectRef)
+			if !ok {
+				continue
+			}
+			annot, err := ctx.DereferenceDict(ref)
+			if err != nil || annot == nil || annot.NameEntry("Subtype") == nil || *annot.NameEntry("Subtype") != "Widget" {
+				continue
+			}
+
+			field := ref
+			if parent := annot.IndirectRefEntry("Parent"); parent != nil {
+				if p, err := ctx.DereferenceDict(*parent); err == nil && p != nil {
+					if ft := p.NameEntry("FT"); ft != nil && (*ft == "Btn" || *ft == "Tx") {
+						field = *parent
+					}
+				}
+			}
+			id, err := fieldID(ctx, field)
+			if err != nil {
+				continue
+			}
+			widgets[id] = append(widgets[id], Widget{Page: pageNum, Rect: widgetRect(ctx, annot)})
+		}
+	}
+	return widgets, nil
+}
+
+// maxFieldDepth bounds the walk up the field tree of broken forms
+const maxFieldDepth = 32
+
+// fieldID returns the ID pdfcpu gives a field: the object numbers from the
+// top of the field tree down to the field, joined by dots
+func fieldID(ctx *model.Context, ref types.IndirectRef) (string, error) {
+	ids := []string{ref.ObjectNumber.String()}
+	for range maxFieldDepth {
+		d, err := ctx.DereferenceDict(ref)
+		if err != nil || d == nil {
+			return "", fmt.Errorf("corrupt field")
+		}
+		parent := d.IndirectRefEntry("Parent")
+		if parent == nil {
+			slices.Reverse(ids)
+			return strings.Join(ids, "."), nil
+		}
+		ref = *parent
+		ids = append(ids, ref.ObjectNumber.String())
+	}
+	return "", fmt.Errorf("field tree too deep")
+}
+
+// widgetRect returns the rectangle of a widget, rounded to 1/100 point
+func widgetRect(ctx *model.Context, annot types.Dict) Rect {
+	obj, ok := annot.Find("Rect")
+	if !ok {
+		return Rect{}
+	}
+	arr, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return Rect{}
+	}
+	r, err := ctx.RectForArray(arr)
+	if err != nil || r == nil {
+		return Rect{}
+	}
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return Rect{
+		X0: round(min(r.LL.X, r.UR.X)), Y0: round(min(r.LL.Y, r.UR.Y)),
+		X1: round(max(r.LL.X, r.UR.X)), Y1: round(max(r.LL.Y, r.UR.Y)),
+	}
+}
+
+// sortFields sorts fields by their first widget: by page, then from top to
+// bottom and left to right. Fields without widgets go last.
+func sortFields(fields []Field) {
+	slices.SortStableFunc(fields, func(a, b Field) int {
+		if len(a.Widgets) == 0 || len(b.Widgets) == 0 {
+			return len(b.Widgets) - len(a.Widgets)
+		}
+		wa, wb := a.Widgets[0], b.Widgets[0]
+		if wa.Page != wb.Page {
+			return wa.Page - wb.Page
+		}
+		if c := cmp.Compare(wb.Rect.Y1, wa.Rect.Y1); c != 0 {
+			return c
+		}
+		return cmp.Compare(wa.Rect.X0, wb.Rect.X0)
+	})
+}
+
+// Fill sets the values of the fields named in values (fully qualified name
+// or ID) and returns the filled PDF. Values are typed like Field.Value;
+// numbers are accepted for text fields and "yes"/"no" for checkboxes.
+// The file is rewritten, which drops digital signatures. Encryption is kept.
+func Fill(pdf []byte, pass string, values map[string]any) ([]byte, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no field values given")
+	}
+	fields, err := List(pdf, pass)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fillForm(fields, values)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(form.FormGroup{Forms: []form.Form{*f}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode form data: %w", err)
+	}
+	conf := password.Configuration(pass)
+	conf.ValidationMode = model.ValidationRelaxed
+	var buf bytes.Buffer
+	if err := api.FillForm(bytes.NewReader(pdf), bytes.NewReader(data), &buf, conf); err != nil {
+		return nil, fmt.Errorf("failed to fill form: %w", password.Check(err, pass))
+	}
+	return buf.Bytes(), nil
+}
+
+// fillForm builds the pdfcpu form data that sets values
+func fillForm(fields []Field, values map[string]any) (*form.Form, error) {
+	f := &form.Form{}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		i := slices.IndexFunc(fields, func(field Field) bool { return field.Name == name })
+		if i < 0 {
+			i = slices.IndexFunc(fields, func(field Field) bool { return field.ID == name })
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		field := fields[i]
+		if field.ReadOnly {
+			return nil, fmt.Errorf("field %q is read-only", name)
+		}
+		if err := addValue(f, field, values[name]); err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+// addValue adds the value of field to f
+func addValue(f *form.Form, field Field, value any) error {
+	pages := []int{}
+	for _, w := range field.Widgets {
+		pages = append(pages, w.Page)
+	}
+
+	switch field.Type {
+	case TypeText, TypeDate:
+		s, err := textValue(value)
+		if err != nil {
+			return err
+		}
+		if field.MaxLen > 0 && len([]rune(s)) > field.MaxLen {
+			return fmt.Errorf("value longer than %d characters", field.MaxLen)
+		}
+		if field.Type == TypeDate {
+			f.DateFields = append(f.DateFields, &form.DateField{Pages: pages, ID: field.ID, Name: field.Name, Format: field.Format, Value: s})
+		} else {
+			f.TextFields = append(f.TextFields, &form.TextField{Pages: pages, ID: field.ID, Name: field.Name, Value: s, Multiline: field.Multiline})
+		}
+	case TypeCheckBox:
+		b, err := boolValue(value)
+		if err != nil {
+			return err
+		}
+		f.CheckBoxes = append(f.CheckBoxes, &form.CheckBox{Pages: pages, ID: field.ID, Name: field.Name, Value: b})
+	case TypeRadio, TypeComboBox:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", value)
+		}
+		if s != "" && !field.Editable && !slices.Contains(field.Options, s) {
+			return fmt.Errorf("%q is not one of %s", s, strings.Join(field.Options, ", "))
+		}
+		if field.Type == TypeRadio {
+			f.RadioButtonGroups = append(f.RadioButtonGroups, &form.RadioButtonGroup{Pages: pages, ID: field.ID, Name: field.Name, Options: field.Options, Value: s})
+		} else {
+			f.ComboBoxes = append(f.ComboBoxes, &form.ComboBox{Pages: pages, ID: field.ID, Name: field.Name, Editable: field.Editable, Options: field.Options, Value: s})
+		}
+	case TypeListBox:
+		ss, err := listValue(value)
+		if err != nil {
+			return err
+		}
+		if len(ss) > 1 && !field.Multi {
+			return fmt.Errorf("only one value can be selected")
+		}
+		for _, s := range ss {
+			if !slices.Contains(field.Options, s) {
+				return fmt.Errorf("%q is not one of %s", s, strings.Join(field.Options, ", "))
+			}
+		}
+		f.ListBoxes = append(f.ListBoxes, &form.ListBox{Pages: pages, ID: field.ID, Name: field.Name, Multi: field.Multi, Options: field.Options, Values: ss})
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type)
+	}
+	return nil
+}
+
+// textValue converts the value of a text field
+func textValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	}
+	return "", fmt.Errorf("expected a string, got %T", value)
+}
+
+// boolValue converts the value of a checkbox
+func boolValue(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0", "":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("expected true or false, got %v", value)
+}
+
+// listValue converts the value of a list box
+func listValue(value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return []string{}, nil
+		}
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []any:
+		ss := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, got %T in it", item)
+			}
+			ss = append(ss, s)
+		}
+		return ss, nil
+	}
+	return nil, fmt.Errorf("expected a list of strings, got %T", value)
+}
+
+// Flatten turns the form fields and other annotations of a document open
+// in PDFium into page content, so that they can no longer be changed, and
+// returns the resulting PDF. Encrypted documents are refused: PDFium would
+// write them unencrypted.
+func Flatten(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT) ([]byte, error) {
+	security, err := instance.FPDF_GetSecurityHandlerRevision(&requests.FPDF_GetSecurityHandlerRevision{Document: doc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption: %w", err)
+	}
+	if security.SecurityHandlerRevision != -1 {
+		return nil, fmt.Errorf("cannot flatten an encrypted PDF: it would be written without encryption")
+	}
+
+	pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{Document: doc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page count: %w", err)
+	}
+	for i := 0; i < pageCount.PageCount; i++ {
+		page := requests.Page{ByIndex: &requests.PageByIndex{Document: doc, Index: i}}
+		res, err := instance.FPDFPage_Flatten(&requests.FPDFPage_Flatten{Page: page, Usage: requests.FPDFPage_FlattenUsagePrint})
+		if err != nil {
+			return nil, fmt.Errorf("failed to flatten page %d: %w", i+1, err)
+		}
+		if res.Result == responses.FPDFPage_FlattenResultFail {
+			return nil, fmt.Errorf("failed to flatten page %d", i+1)
+		}
+	}
+
+	saved, err := instance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{Document: doc, Flags: requests.SaveFlagNoIncremental})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return *saved.FileBytes, nil
+}
+
+// readContext parses a PDF with pdfcpu. Validation loads the form.
+func readContext(pdf []byte, pass string) (*model.Context, error) {
+	conf := password.Configuration(pass)
+	conf.ValidationMode = model.ValidationRelaxed
+	ctx, err := api.ReadAndValidate(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", password.Check(err, pass))
+	}
+	return ctx, nil
+}
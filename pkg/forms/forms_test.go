@@ -0,0 +1,149 @@
+package forms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tu-usuario/pdf2img/internal/testpdf"
+)
+
+// minimalForm builds a one-page PDF with a text field "name" above a
+// checkbox "agree"
+func minimalForm() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [4 0 R 5 0 R] /DA (/Helv 0 Tf 0 g) /DR << /Font << /Helv 6 0 R >> >> >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Annots [5 0 R 4 0 R] /Resources << >> >>",
+		"<< /Type /Annot /Subtype /Widget /FT /Tx /T (name) /TU (Full name) /V (Ana) /MaxLen 10 /DA (/Helv 12 Tf 0 g) /Rect [20 150 180 170] /P 3 0 R /F 4 >>",
+		"<< /Type /Annot /Subtype /Widget /FT /Btn /T (agree) /V /Off /AS /Off /Rect [20 100 32 112] /P 3 0 R /F 4 " +
+			"/AP << /N << /Yes 7 0 R /Off 7 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /XObject /Subtype /Form /BBox [0 0 12 12] /Length 0 >>\nstream\n\nendstream",
+	}
+	return testpdf.Build(objects, "")
+}
+
+// TestList tests listing the fields of a form in reading order
+func TestList(t *testing.T) {
+	fields, err := List(minimalForm(), "")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("List() = %d fields, want 2", len(fields))
+	}
+
+	name, agree := fields[0], fields[1]
+	if name.Name != "name" || name.Type != TypeText || name.Value != "Ana" || name.AltName != "Full name" || name.MaxLen != 10 {
+		t.Errorf("fields[0] = %+v", name)
+	}
+	want := []Widget{{Page: 1, Rect: Rect{X0: 20, Y0: 150, X1: 180, Y1: 170}}}
+	if fmt.Sprint(name.Widgets) != fmt.Sprint(want) {
+		t.Errorf("fields[0].Widgets = %v, want %v", name.Widgets, want)
+	}
+	if agree.Name != "agree" || agree.Type != TypeCheckBox || agree.Value != false {
+		t.Errorf("fields[1] = %+v", agree)
+	}
+}
+
+// TestFill tests filling a form and reading the values back
+func TestFill(t *testing.T) {
+	out, err := Fill(minimalForm(), "", map[string]any{"name": "Luis", "agree": true})
+	if err != nil {
+		t.Fatalf("Fill() error = %v", err)
+	}
+	fields, err := List(out, "")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	got := map[string]any{}
+	for _, f := range fields {
+		got[f.Name] = f.Value
+	}
+	if got["name"] != "Luis" || got["agree"] != true {
+		t.Errorf("values after Fill() = %v", got)
+	}
+
+	if _, err := Fill(minimalForm(), "", nil); err == nil {
+		t.Error("Fill() without values: expected an error")
+	}
+}
+
+// TestFillForm tests matching and validating field values
+func TestFillForm(t *testing.T) {
+	fields := []Field{
+		{Name: "name", ID: "4", Type: TypeText, MaxLen: 5},
+		{Name: "born", ID: "5", Type: TypeDate, Format: "yyyy-mm-dd"},
+		{Name: "agree", ID: "6", Type: TypeCheckBox},
+		{Name: "gender", ID: "7", Type: TypeRadio, Options: []string{"female", "male"}},
+		{Name: "city", ID: "8", Type: TypeComboBox, Options: []string{"Madrid"}},
+		{Name: "town", ID: "9", Type: TypeComboBox, Options: []string{"Madrid"}, Editable: true},
+		{Name: "langs", ID: "10", Type: TypeListBox, Options: []string{"en", "es"}, Multi: true},
+		{Name: "lang", ID: "11", Type: TypeListBox, Options: []string{"en", "es"}},
+		{Name: "locked", ID: "12", Type: TypeText, ReadOnly: true},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]any
+		wantErr string
+	}{
+		{"text", map[string]any{"name": "Ana"}, ""},
+		{"number as text", map[string]any{"name": 42.0}, ""},
+		{"by ID", map[string]any{"4": "Ana"}, ""},
+		{"text too long", map[string]any{"name": "Ana María"}, "longer than 5"},
+		{"date", map[string]any{"born": "2000-01-31"}, ""},
+		{"checkbox", map[string]any{"agree": true}, ""},
+		{"checkbox as string", map[string]any{"agree": "yes"}, ""},
+		{"checkbox invalid", map[string]any{"agree": "maybe"}, "expected true or false"},
+		{"radio", map[string]any{"gender": "male"}, ""},
+		{"radio unknown option", map[string]any{"gender": "other"}, "not one of"},
+		{"combo box unknown option", map[string]any{"city": "Lugo"}, "not one of"},
+		{"editable combo box", map[string]any{"town": "Lugo"}, ""},
+		{"list box", map[string]any{"langs": []any{"en", "es"}}, ""},
+		{"list box single string", map[string]any{"lang": "es"}, ""},
+		{"list box several values", map[string]any{"lang": []any{"en", "es"}}, "only one value"},
+		{"list box unknown option", map[string]any{"langs": []any{"fr"}}, "not one of"},
+		{"unknown field", map[string]any{"nope": "x"}, "unknown field"},
+		{"read-only", map[string]any{"locked": "x"}, "read-only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fillForm(fields, tt.values)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("fillForm() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fillForm() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSortFields tests the reading order of fields
+func TestSortFields(t *testing.T) {
+	widget := func(page int, x, y float64) []Widget {
+		return []Widget{{Page: page, Rect: Rect{X0: x, Y0: y - 10, X1: x + 10, Y1: y}}}
+	}
+	fields := []Field{
+		{Name: "none"},
+		{Name: "p2", Widgets: widget(2, 10, 700)},
+		{Name: "bottom", Widgets: widget(1, 10, 100)},
+		{Name: "right", Widgets: widget(1, 300, 700)},
+		{Name: "left", Widgets: widget(1, 10, 700)},
+	}
+	sortFields(fields)
+
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if got, want := strings.Join(names, ","), "left,right,bottom,p2,none"; got != want {
+		t.Errorf("sortFields() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package imgenc
+
+import (
+	"image"
+)
+
+// faxCode is a CCITT code word: the low n bits of bits, most significant first
+type faxCode struct {
+	bits uint32
+	n    uint
+}
+
+// Mode codes from ITU-T T.6 Table 1
+var (
+	faxPass       = faxCode{0x1, 4}
+	faxHorizontal = faxCode{0x1, 3}
+	faxEOL        = faxCode{0x1, 12}
+	// faxVertical is indexed by b1-a1+3, from VR3 to VL3
+	faxVertical = [7]faxCode{
+		{0x3, 7}, {0x3, 6}, {0x3, 3}, {0x1, 1}, {0x2, 3}, {0x2, 6}, {0x2, 7},
+	}
+)
+
+// Terminating codes for run lengths 0-63 (T.4 Table 2)
+var faxWhiteTerm = [64]faxCode{
+	{0x0035, 8}, {0x0007, 6}, {0x0007, 4}, {0x0008, 4}, {0x000b, 4}, {0x000c, 4},
+	{0x000e, 4}, {0x000f, 4}, {0x0013, 5}, {0x0014, 5}, {0x0007, 5}, {0x0008, 5},
+	{0x0008, 6}, {0x0003, 6}, {0x0034, 6}, {0x0035, 6}, {0x002a, 6}, {0x002b, 6},
+	{0x0027, 7}, {0x000c, 7}, {0x0008, 7}, {0x0017, 7}, {0x0003, 7}, {0x0004, 7},
+	{0x0028, 7}, {0x002b, 7}, {0x0013, 7}, {0x0024, 7}, {0x0018, 7}, {0x0002, 8},
+	{0x0003, 8}, {0x001a, 8}, {0x001b, 8}, {0x0012, 8}, {0x0013, 8}, {0x0014, 8},
+	{0x0015, 8}, {0x0016, 8}, {0x0017, 8}, {0x0028, 8}, {0x0029, 8}, {0x002a, 8},
+	{0x002b, 8}, {0x002c, 8}, {0x002d, 8}, {0x0004, 8}, {0x0005, 8}, {0x000a, 8},
+	{0x000b, 8}, {0x0052, 8}, {0x0053, 8}, {0x0054, 8}, {0x0055, 8}, {0x0024, 8},
+	{0x0025, 8}, {0x0058, 8}, {0x0059, 8}, {0x005a, 8}, {0x005b, 8}, {0x004a, 8},
+	{0x004b, 8}, {0x0032, 8}, {0x0033, 8}, {0x0034, 8},
+}
+
+// Make-up codes for run lengths 64-2560 in steps of 64 (T.4 Table 3)
+var faxWhiteMakeup = [40]faxCode{
+	{0x001b, 5}, {0x0012, 5}, {0x0017, 6}, {0x0037, 7}, {0x0036, 8}, {0x0037, 8},
+	{0x0064, 8}, {0x0065, 8}, {0x0068, 8}, {0x0067, 8}, {0x00cc, 9}, {0x00cd, 9},
+	{0x00d2, 9}, {0x00d3, 9}, {0x00d4, 9}, {0x00d5, 9}, {0x00d6, 9}, {0x00d7, 9},
+	{0x00d8, 9}, {0x00d9, 9}, {0x00da, 9}, {0x00db, 9}, {0x0098, 9}, {0x0099, 9},
+	{0x009a, 9}, {0x0018, 6}, {0x009b, 9}, {0x0008, 11}, {0x000c, 11}, {0x000d,
+		11}, {0x0012, 12}, {0x0013, 12}, {0x0014, 12}, {0x0015, 12}, {0x0016, 12},
+	{0x0017, 12}, {0x001c, 12}, {0x001d, 12}, {0x001e, 12}, {0x001f, 12},
+}
+
+var faxBlackTerm = [64]faxCode{
+	{0x0037, 10}, {0x0002, 3}, {0x0003, 2}, {0x0002, 2}, {0x0003, 3}, {0x0003, 4},
+	{0x0002, 4}, {0x0003, 5}, {0x0005, 6}, {0x0004, 6}, {0x0004, 7}, {0x0005, 7},
+	{0x0007, 7}, {0x0004, 8}, {0x0007, 8}, {0x0018, 9}, {0x0017, 10}, {0x0018, 10},
+	{0x0008, 10}, {0x0067, 11}, {0x0068, 11}, {0x006c, 11}, {0x0037, 11}, {0x0028,
+		11}, {0x0017, 11}, {0x0018, 11}, {0x00ca, 12}, {0x00cb, 12}, {0x00cc, 12},
+	{0x00cd, 12}, {0x0068, 12}, {0x0069, 12}, {0x006a, 12}, {0x006b, 12}, {0x00d2,
+		12}, {0x00d3, 12}, {0x00d4, 12}, {0x00d5, 12}, {0x00d6, 12}, {0x00d7, 12},
+	{0x006c, 12}, {0x006d, 12}, {0x00da, 12}, {0x00db, 12}, {0x0054, 12}, {0x0055,
+		12}, {0x0056, 12}, {0x0057, 12}, {0x0064, 12}, {0x0065, 12}, {0x0052, 12},
+	{0x0053, 12}, {0x0024, 12}, {0x0037, 12}, {0x0038, 12}, {0x0027, 12}, {0x0028,
+		12}, {0x0058, 12}, {0x0059, 12}, {0x002b, 12}, {0x002c, 12}, {0x005a, 12},
+	{0x0066, 12}, {0x0067, 12},
+}
+
+var faxBlackMakeup = [40]faxCode{
+	{0x000f, 10}, {0x00c8, 12}, {0x00c9, 12}, {0x005b, 12}, {0x0033, 12}, {0x0034,
+		12}, {0x0035, 12}, {0x006c, 13}, {0x006d, 13}, {0x004a, 13}, {0x004b, 13},
+	{0x004c, 13}, {0x004d, 13}, {0x0072, 13}, {0x0073, 13}, {0x0074, 13}, {0x0075,
+		13}, {0x0076, 13}, {0x0077, 13}, {0x0052, 13}, {0x0053, 13}, {0x0054, 13},
+	{0x0055, 13}, {0x005a, 13}, {0x005b, 13}, {0x0064, 13}, {0x0065, 13}, {0x0008,
+		11}, {0x000c, 11}, {0x000d, 11}, {0x0012, 12}, {0x0013, 12}, {0x0014, 12},
+	{0x0015, 12}, {0x0016, 12}, {0x0017, 12}, {0x001c, 12}, {0x001d, 12}, {0x001e,
+		12}, {0x001f, 12},
+}
+
+// faxWriter packs CCITT code words most significant bit first (FillOrder 1)
+type faxWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *faxWriter) put(c faxCode) {
+	w.acc = w.acc<<c.n | uint64(c.bits)
+	w.nbits += c.n
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.nbits))
+	}
+}
+
+func (w *faxWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc<<(8-w.nbits)))
+		w.nbits = 0
+	}
+	return w.buf
+}
+
+// putRun writes a white or black run using make-up and terminating codes
+func (w *faxWriter) putRun(run int, black bool) {
+	term, makeup := faxWhiteTerm[:], faxWhiteMakeup[:]
+	if black {
+		term, makeup = faxBlackTerm[:], faxBlackMakeup[:]
+	}
+	for run >= 2624 {
+		w.put(makeup[len(makeup)-1])
+		run -= 2560
+	}
+	if run >= 64 {
+		w.put(makeup[run/64-1])
+		run %= 64
+	}
+	w.put(term[run])
+}
+
+// findChange returns the first position at or after start whose pixel
+// differs from color, or len(line) if there is none
+func findChange(line []byte, start int, color byte) int {
+	for x := start; x < len(line); x++ {
+		if line[x] != color {
+			return x
+		}
+	}
+	return len(line)
+}
+
+// nextChange returns the changing element following the one at start
+func nextChange(line []byte, start int) int {
+	if start >= len(line) {
+		return len(line)
+	}
+	return findChange(line, start, line[start])
+}
+
+// encodeG4 compresses a bilevel bitmap (one byte per pixel, 1 = black) with
+// CCITT Group 4 (ITU-T T.6) two-dimensional coding
+func encodeG4(pix []byte, width, height int) []byte {
+	w := &faxWriter{}
+	ref := make([]byte, width) // imaginary all-white line above the page
+
+	for y := 0; y < height; y++ {
+		cur := pix[y*width : (y+1)*width]
+		a0 := 0
+		a1 := findChange(cur, 0, 0)
+		b1 := findChange(ref, 0, 0)
+		for {
+			b2 := nextChange(ref, b1)
+			if b2 >= a1 {
+				if d := b1 - a1; d < -3 || d > 3 {
+					a2 := nextChange(cur, a1)
+					w.put(faxHorizontal)
+					black := a0+a1 != 0 && cur[a0] == 1
+					w.putRun(a1-a0, black)
+					w.putRun(a2-a1, !black)
+					a0 = a2
+				} else {
+					w.put(faxVertical[d+3])
+					a0 = a1
+				}
+			} else {
+				w.put(faxPass)
+				a0 = b2
+			}
+			if a0 >= width {
+				break
+			}
+			color := cur[a0]
+			a1 = findChange(cur, a0, color)
+			b1 = findChange(ref, a0, color^1)
+			b1 = findChange(ref, b1, color)
+		}
+		ref = cur
+	}
+
+	// End of facsimile block
+	w.put(faxEOL)
+	w.put(faxEOL)
+	return w.flush()
+}
+
+// bilevelPixels returns img as one byte per pixel (1 = black) when every
+// pixel is opaque pure black or pure white
+func bilevelPixels(img *image.RGBA) ([]byte, bool) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	pix := make([]byte, 0, width*height)
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+4*width]
+		for i := 0; i < len(row); i += 4 {
+			switch v := row[i]; {
+			case row[i+3] != 0xff || row[i+1] != v || row[i+2] != v:
+				return nil, false
+			case v == 0:
+				pix = append(pix, 1)
+			case v == 0xff:
+				pix = append(pix, 0)
+			default:
+				return nil, false
+			}
+		}
+	}
+	return pix, true
+}
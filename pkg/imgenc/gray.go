@@ -0,0 +1,80 @@
+package imgenc
+
+import (
+	"image"
+	"image/color"
+)
+
+// BilevelPalette is the palette of Threshold and Dither results: index 0
+// is black, index 1 white. Encoders store such images with 1 bit per pixel.
+var BilevelPalette = color.Palette{color.Gray{Y: 0}, color.Gray{Y: 0xff}}
+
+// Grayscale converts img to 8-bit gray using the same luma weights as
+// color.GrayModel. Transparent areas are composited onto white.
+func Grayscale(img image.Image) *image.Gray {
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	out := image.NewGray(rgba.Rect)
+	for y := 0; y < height; y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+4*width]
+		dst := out.Pix[y*out.Stride : y*out.Stride+width]
+		for x := range dst {
+			r, g, b, a := uint32(row[4*x]), uint32(row[4*x+1]), uint32(row[4*x+2]), uint32(row[4*x+3])
+			// RGBA is premultiplied, so white shows through by 255-a
+			luma := (19595*r + 38470*g + 7471*b + 1<<15) >> 16
+			dst[x] = uint8(min(luma+0xff-a, 0xff))
+		}
+	}
+	return out
+}
+
+// Threshold converts img to black and white: pixels whose gray level is
+// below level turn black, the others white
+func Threshold(img image.Image, level uint8) *image.Paletted {
+	gray := Grayscale(img)
+	out := image.NewPaletted(gray.Rect, BilevelPalette)
+	for y := 0; y < gray.Rect.Dy(); y++ {
+		src := gray.Pix[y*gray.Stride : y*gray.Stride+gray.Rect.Dx()]
+		dst := out.Pix[y*out.Stride:]
+		for x, v := range src {
+			if v >= level {
+				dst[x] = 1
+			}
+		}
+	}
+	return out
+}
+
+// Dither converts img to black and white with Floyd–Steinberg error
+// diffusion around level, which keeps the tones of photos and shading
+// that Threshold would flatten
+func Dither(img image.Image, level uint8) *image.Paletted {
+	gray := Grayscale(img)
+	width, height := gray.Rect.Dx(), gray.Rect.Dy()
+	out := image.NewPaletted(gray.Rect, BilevelPalette)
+
+	// Errors carried into the current and the next row, with a pixel of
+	// padding on both sides
+	cur := make([]int, width+2)
+	next := make([]int, width+2)
+	for y := 0; y < height; y++ {
+		src := gray.Pix[y*gray.Stride : y*gray.Stride+width]
+		dst := out.Pix[y*out.Stride:]
+		for x, v := range src {
+			want := int(v) + cur[x+1]/16
+			got := 0
+			if want >= int(level) {
+				got = 0xff
+				dst[x] = 1
+			}
+			e := want - got
+			cur[x+2] += e * 7
+			next[x] += e * 3
+			next[x+1] += e * 5
+			next[x+2] += e
+		}
+		cur, next = next, cur
+		clear(next)
+	}
+	return out
+}
@@ -0,0 +1,20 @@
+// Package imgenc implements the pure-Go image encoders used for output
+// formats that the standard library does not provide (TIFF and WebP).
+package imgenc
+
+import (
+	"image"
+	"image/draw"
+)
+
+// toRGBA returns img as an *image.RGBA whose origin is (0, 0), converting
+// it only when necessary
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, b.Min, draw.Src)
+	return rgba
+}
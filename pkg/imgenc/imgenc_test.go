@@ -0,0 +1,316 @@
+package imgenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// testImages returns small images covering the layouts the encoders pick
+func testImages() map[string]image.Image {
+	rng := rand.New(rand.NewSource(1))
+
+	bilevel := image.NewRGBA(image.Rect(0, 0, 203, 61))
+	gray := image.NewRGBA(image.Rect(0, 0, 64, 33))
+	noise := image.NewRGBA(image.Rect(0, 0, 97, 45))
+	alpha := image.NewRGBA(image.Rect(0, 0, 40, 40))
+
+	for y := 0; y < 61; y++ {
+		for x := 0; x < 203; x++ {
+			// Long white runs, short black strokes and a solid band
+			v := uint8(255)
+			if (x/7+y/5)%5 == 0 || (y > 40 && y < 45) || rng.Intn(50) == 0 {
+				v = 0
+			}
+			bilevel.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	for y := 0; y < 33; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(x * 4)
+			gray.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	for y := 0; y < 45; y++ {
+		for x := 0; x < 97; x++ {
+			c := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+			if x > 60 {
+				c = color.RGBA{250, 250, 245, 255}
+			}
+			noise.Set(x, y, c)
+		}
+	}
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			alpha.Set(x, y, color.NRGBA{uint8(x * 6), 80, uint8(y * 6), uint8(x * y % 256)})
+		}
+	}
+
+	return map[string]image.Image{
+		"bilevel": bilevel,
+		"gray":    gray,
+		"noise":   noise,
+		"alpha":   alpha,
+	}
+}
+
+// sameImage compares two images in 8-bit premultiplied RGBA, allowing each
+// channel to differ by tolerance
+func sameImage(t *testing.T, want, got image.Image, tolerance int) {
+	t.Helper()
+	if want.Bounds().Size() != got.Bounds().Size() {
+		t.Fatalf("size = %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	wb, gb := want.Bounds(), got.Bounds()
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			w := color.RGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.RGBA)
+			g := color.RGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.RGBA)
+			if diff(w.R, g.R) > tolerance || diff(w.G, g.G) > tolerance ||
+				diff(w.B, g.B) > tolerance || diff(w.A, g.A) > tolerance {
+				t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func diff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// TestEncodeWebP checks that lossless WebP output decodes to the same pixels
+func TestEncodeWebP(t *testing.T) {
+	for name, img := range testImages() {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, img); err != nil {
+				t.Fatalf("EncodeWebP() error = %v", err)
+			}
+			got, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("webp.Decode() error = %v", err)
+			}
+			// WebP stores straight alpha, so translucent pixels may be off
+			// by one after premultiplying again
+			tolerance := 0
+			if name == "alpha" {
+				tolerance = 1
+			}
+			sameImage(t, img, got, tolerance)
+		})
+	}
+}
+
+// TestEncodeTIFF checks that single-page TIFF output decodes to the same
+// pixels and that bilevel images are stored as 1-bit CCITT G4
+func TestEncodeTIFF(t *testing.T) {
+	for name, img := range testImages() {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeTIFF(&buf, img); err != nil {
+				t.Fatalf("EncodeTIFF() error = %v", err)
+			}
+			got, err := tiff.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("tiff.Decode() error = %v", err)
+			}
+			sameImage(t, img, got, 0)
+
+			page, _ := NewTIFFPage(img)
+			if wantG4 := name == "bilevel"; (page.compression == compressionG4) != wantG4 {
+				t.Errorf("compression = %d, want G4 %v", page.compression, wantG4)
+			}
+		})
+	}
+}
+
+// TestTIFFWriterMultiPage checks the IFD chain and page numbers of a
+// multi-page file
+func TestTIFFWriterMultiPage(t *testing.T) {
+	images := testImages()
+	order := []string{"bilevel", "gray", "noise"}
+
+	var buf bytes.Buffer
+	tw, err := NewTIFFWriter(&buf, len(order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range order {
+		page, err := NewTIFFPage(images[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.WritePage(page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	le := binary.LittleEndian
+	offset := le.Uint32(data[4:])
+	for i, name := range order {
+		if offset == 0 || offset%2 != 0 {
+			t.Fatalf("page %d: bad IFD offset %d", i, offset)
+		}
+		n := int(le.Uint16(data[offset:]))
+		var width, pageNum uint32
+		for e := 0; e < n; e++ {
+			entry := data[int(offset)+2+12*e:]
+			switch le.Uint16(entry) {
+			case tagImageWidth:
+				width = le.Uint32(entry[8:])
+			case tagPageNumber:
+				pageNum = uint32(le.Uint16(entry[8:]))
+			}
+		}
+		if want := uint32(images[name].Bounds().Dx()); width != want {
+			t.Errorf("page %d: width = %d, want %d", i, width, want)
+		}
+		if pageNum != uint32(i) {
+			t.Errorf("page %d: PageNumber = %d", i, pageNum)
+		}
+		offset = le.Uint32(data[int(offset)+2+12*n:])
+	}
+	if offset != 0 {
+		t.Errorf("last IFD points to %d, want 0", offset)
+	}
+
+	if err := tw.WritePage(&TIFFPage{}); err == nil {
+		t.Error("WritePage() past the announced page count should fail")
+	}
+}
+
+// TestWebPPrefix tests the VP8L length/distance prefix coding
+func TestWebPPrefix(t *testing.T) {
+	for v := 1; v <= 4096; v++ {
+		code, nbits, extra := webpPrefix(v)
+		// Decode as described in the specification
+		got := code + 1
+		if code >= 4 {
+			n := (code - 2) >> 1
+			if uint(n) != nbits {
+				t.Fatalf("webpPrefix(%d) extra bits = %d, want %d", v, nbits, n)
+			}
+			got = (2+code&1)<<n + extra + 1
+		}
+		if got != v {
+			t.Fatalf("webpPrefix(%d) decodes to %d", v, got)
+		}
+	}
+}
+
+// TestQuantize checks exact palettes for images with few colors and the
+// palette size limit otherwise
+func TestQuantize(t *testing.T) {
+	images := testImages()
+
+	gray := Quantize(images["gray"], 256)
+	if len(gray.Palette) != 64 {
+		t.Errorf("gray palette has %d colors, want 64", len(gray.Palette))
+	}
+	sameImage(t, images["gray"], gray, 0)
+
+	noise := Quantize(images["noise"], 16)
+	if len(noise.Palette) > 16 {
+		t.Errorf("noise palette has %d colors, want at most 16", len(noise.Palette))
+	}
+	if noise.Bounds() != images["noise"].Bounds() {
+		t.Errorf("bounds = %v, want %v", noise.Bounds(), images["noise"].Bounds())
+	}
+}
+
+// TestEncodeTIFFPaletted checks that paletted images keep their color map
+func TestEncodeTIFFPaletted(t *testing.T) {
+	img := Quantize(testImages()["noise"], 64)
+
+	page, err := NewTIFFPage(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.photometric != photometricPalette {
+		t.Errorf("photometric = %d, want palette", page.photometric)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeTIFF(&buf, img); err != nil {
+		t.Fatalf("EncodeTIFF() error = %v", err)
+	}
+	got, err := tiff.Decode(&buf)
+	if err != nil {
+		t.Fatalf("tiff.Decode() error = %v", err)
+	}
+	sameImage(t, img, got, 0)
+}
+
+// TestGrayscale checks that gray pixels are kept and transparent ones
+// turn white
+func TestGrayscale(t *testing.T) {
+	images := testImages()
+	sameImage(t, images["gray"], Grayscale(images["gray"]), 0)
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(1, 0, color.RGBA{255, 0, 0, 255})
+	got := Grayscale(img)
+	if got.Pix[0] != 0xff {
+		t.Errorf("transparent pixel = %d, want 255", got.Pix[0])
+	}
+	if want := color.GrayModel.Convert(color.RGBA{255, 0, 0, 255}).(color.Gray).Y; got.Pix[1] != want {
+		t.Errorf("red pixel = %d, want %d", got.Pix[1], want)
+	}
+}
+
+// TestThreshold checks the split of a gray ramp at the given level and
+// that bilevel results are stored as 1-bit CCITT G4 in TIFF
+func TestThreshold(t *testing.T) {
+	ramp := testImages()["gray"] // x*4 gray levels
+	got := Threshold(ramp, 128)
+	for x := 0; x < 64; x++ {
+		want := uint8(0)
+		if x*4 >= 128 {
+			want = 1
+		}
+		if got.Pix[x] != want {
+			t.Fatalf("pixel %d = %d, want %d", x, got.Pix[x], want)
+		}
+	}
+
+	page, err := NewTIFFPage(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.compression != compressionG4 || page.bits != 1 {
+		t.Errorf("compression = %d, bits = %d, want 1-bit G4", page.compression, page.bits)
+	}
+}
+
+// TestDither checks that dithering keeps the mean tone of flat areas
+func TestDither(t *testing.T) {
+	for _, v := range []uint8{0, 64, 128, 192, 255} {
+		flat := image.NewGray(image.Rect(0, 0, 50, 50))
+		for i := range flat.Pix {
+			flat.Pix[i] = v
+		}
+		got := Dither(flat, 128)
+
+		white := 0
+		for _, p := range got.Pix {
+			white += int(p)
+		}
+		mean := float64(white) * 255 / float64(len(got.Pix))
+		if diff := mean - float64(v); diff < -8 || diff > 8 {
+			t.Errorf("level %d: dithered mean = %.1f", v, mean)
+		}
+	}
+}
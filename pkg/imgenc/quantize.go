@@ -0,0 +1,172 @@
+package imgenc
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Quantize reduces img to at most n colors (2-256) and returns it as a
+// paletted image. Images that already use n colors or fewer keep their
+// exact colors; otherwise a median cut over a 15-bit RGB histogram picks
+// the palette. No dithering is applied, which keeps text edges clean.
+func Quantize(img image.Image, n int) *image.Paletted {
+	n = max(2, min(n, 256))
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	out := image.NewPaletted(rgba.Rect, nil)
+
+	if pal, index := exactPalette(rgba, n); pal != nil {
+		out.Palette = pal
+		for y := 0; y < height; y++ {
+			row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+4*width]
+			for x := 0; x < width; x++ {
+				c := uint32(row[4*x])<<24 | uint32(row[4*x+1])<<16 | uint32(row[4*x+2])<<8 | uint32(row[4*x+3])
+				out.Pix[y*out.Stride+x] = index[c]
+			}
+		}
+		return out
+	}
+
+	// Histogram over 5 bits per channel, with summed channels for the means
+	var hist [1 << 15]bucket
+	for y := 0; y < height; y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+4*width]
+		for i := 0; i < len(row); i += 4 {
+			b := &hist[bucketKey(row[i], row[i+1], row[i+2])]
+			b.count++
+			b.r += uint64(row[i])
+			b.g += uint64(row[i+1])
+			b.b += uint64(row[i+2])
+			b.a += uint64(row[i+3])
+		}
+	}
+
+	var keys []uint16
+	for key := range hist {
+		if hist[key].count > 0 {
+			keys = append(keys, uint16(key))
+		}
+	}
+
+	boxes := medianCut(keys, &hist, n)
+	out.Palette = make(color.Palette, len(boxes))
+	var lookup [1 << 15]uint8
+	for i, box := range boxes {
+		var sum bucket
+		for _, key := range box {
+			b := hist[key]
+			sum.count += b.count
+			sum.r += b.r
+			sum.g += b.g
+			sum.b += b.b
+			sum.a += b.a
+			lookup[key] = uint8(i)
+		}
+		out.Palette[i] = color.RGBA{
+			uint8(sum.r / sum.count), uint8(sum.g / sum.count),
+			uint8(sum.b / sum.count), uint8(sum.a / sum.count),
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+4*width]
+		for x := 0; x < width; x++ {
+			out.Pix[y*out.Stride+x] = lookup[bucketKey(row[4*x], row[4*x+1], row[4*x+2])]
+		}
+	}
+	return out
+}
+
+type bucket struct {
+	count      uint64
+	r, g, b, a uint64
+}
+
+func bucketKey(r, g, b uint8) uint16 {
+	return uint16(r>>3)<<10 | uint16(g>>3)<<5 | uint16(b>>3)
+}
+
+// exactPalette returns the colors of img and their indexes when there are
+// no more than n of them
+func exactPalette(img *image.RGBA, n int) (color.Palette, map[uint32]uint8) {
+	index := map[uint32]uint8{}
+	var pal color.Palette
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+4*width]
+		for i := 0; i < len(row); i += 4 {
+			c := uint32(row[i])<<24 | uint32(row[i+1])<<16 | uint32(row[i+2])<<8 | uint32(row[i+3])
+			if _, ok := index[c]; ok {
+				continue
+			}
+			if len(pal) == n {
+				return nil, nil
+			}
+			index[c] = uint8(len(pal))
+			pal = append(pal, color.RGBA{row[i], row[i+1], row[i+2], row[i+3]})
+		}
+	}
+	return pal, index
+}
+
+// medianCut splits the occupied histogram buckets into at most n boxes,
+// always cutting the box with the most pixels along its widest channel
+func medianCut(keys []uint16, hist *[1 << 15]bucket, n int) [][]uint16 {
+	boxes := [][]uint16{keys}
+	for len(boxes) < n {
+		best, bestCount := -1, uint64(0)
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			var count uint64
+			for _, key := range box {
+				count += hist[key].count
+			}
+			if count > bestCount {
+				best, bestCount = i, count
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		shift := widestChannel(box)
+		sort.Slice(box, func(i, j int) bool {
+			return box[i]>>shift&0x1f < box[j]>>shift&0x1f
+		})
+
+		// Split at the pixel-weighted median
+		var seen uint64
+		cut := 1
+		for i, key := range box[:len(box)-1] {
+			seen += hist[key].count
+			cut = i + 1
+			if seen*2 >= bestCount {
+				break
+			}
+		}
+		boxes[best] = box[:cut:cut]
+		boxes = append(boxes, box[cut:])
+	}
+	return boxes
+}
+
+// widestChannel returns the bit offset (10 = red, 5 = green, 0 = blue) of
+// the channel with the largest range in box
+func widestChannel(box []uint16) uint {
+	bestShift, bestRange := uint(0), -1
+	for _, shift := range []uint{10, 5, 0} {
+		lo, hi := 31, 0
+		for _, key := range box {
+			v := int(key >> shift & 0x1f)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > bestRange {
+			bestShift, bestRange = shift, hi-lo
+		}
+	}
+	return bestShift
+}
@@ -0,0 +1,358 @@
+package imgenc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// TIFF tag numbers, field types and values used by the writer
+const (
+	tagNewSubfileType  = 254
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagPageNumber      = 297
+	tagPredictor       = 317
+	tagColorMap        = 320
+	tagExtraSamples    = 338
+
+	typeShort = 3
+	typeLong  = 4
+
+	compressionG4      = 4
+	compressionDeflate = 8
+
+	photometricWhiteIsZero = 0
+	photometricBlackIsZero = 1
+	photometricRGB         = 2
+	photometricPalette     = 3
+)
+
+// TIFFPage is a page compressed and ready to be written into a TIFF file.
+// Bilevel pages use CCITT Group 4; everything else is 8-bit Deflate.
+// Paletted images with up to 256 opaque colors keep their palette.
+type TIFFPage struct {
+	Width, Height int
+	Data          []byte // compressed strip
+
+	samples     int
+	bits        int
+	compression uint16
+	photometric uint16
+	alpha       bool
+	predictor   bool
+	colorMap    []uint32
+}
+
+// NewTIFFPage compresses img using the smallest layout that keeps it
+// lossless: 1-bit G4 for pure black and white, 8-bit gray, RGB or RGBA
+func NewTIFFPage(img image.Image) (*TIFFPage, error) {
+	if p, ok := img.(*image.Paletted); ok && tiffPalette(p.Palette) {
+		return newPalettedTIFFPage(p)
+	}
+
+	rgba := toRGBA(img)
+	page := &TIFFPage{Width: rgba.Rect.Dx(), Height: rgba.Rect.Dy()}
+	if page.Width == 0 || page.Height == 0 {
+		return nil, errors.New("cannot encode an empty image as TIFF")
+	}
+
+	if pix, ok := bilevelPixels(rgba); ok {
+		page.samples, page.bits = 1, 1
+		page.compression = compressionG4
+		page.photometric = photometricWhiteIsZero
+		page.Data = encodeG4(pix, page.Width, page.Height)
+		return page, nil
+	}
+
+	gray, opaque := true, true
+	for y := 0; y < page.Height && opaque; y++ {
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+4*page.Width]
+		for i := 0; i < len(row); i += 4 {
+			if row[i+3] != 0xff {
+				opaque, gray = false, false
+				break
+			}
+			if row[i] != row[i+1] || row[i+1] != row[i+2] {
+				gray = false
+			}
+		}
+	}
+
+	page.bits = 8
+	page.compression = compressionDeflate
+	page.predictor = true
+	switch {
+	case gray:
+		page.samples = 1
+		page.photometric = photometricBlackIsZero
+	case opaque:
+		page.samples = 3
+		page.photometric = photometricRGB
+	default:
+		page.samples = 4
+		page.photometric = photometricRGB
+		page.alpha = true
+	}
+
+	data, err := deflateRows(rgba, page.samples)
+	if err != nil {
+		return nil, err
+	}
+	page.Data = data
+	return page, nil
+}
+
+// tiffPalette reports whether a palette can be stored as a TIFF color map:
+// at most 256 opaque colors that are not just black and white (those
+// compress better as G4)
+func tiffPalette(pal color.Palette) bool {
+	if len(pal) == 0 || len(pal) > 256 {
+		return false
+	}
+	bilevel := true
+	for _, c := range pal {
+		r, g, b, a := c.RGBA()
+		if a != 0xffff {
+			return false
+		}
+		if !(r == g && g == b && (r == 0 || r == 0xffff)) {
+			bilevel = false
+		}
+	}
+	return !bilevel
+}
+
+func newPalettedTIFFPage(img *image.Paletted) (*TIFFPage, error) {
+	page := &TIFFPage{
+		Width:       img.Rect.Dx(),
+		Height:      img.Rect.Dy(),
+		samples:     1,
+		bits:        8,
+		compression: compressionDeflate,
+		photometric: photometricPalette,
+		colorMap:    make([]uint32, 3*256),
+	}
+	if page.Width == 0 || page.Height == 0 {
+		return nil, errors.New("cannot encode an empty image as TIFF")
+	}
+
+	for i, c := range img.Palette {
+		r, g, b, _ := c.RGBA()
+		page.colorMap[i], page.colorMap[256+i], page.colorMap[512+i] = r, g, b
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	for y := 0; y < page.Height; y++ {
+		if _, err := zw.Write(img.Pix[y*img.Stride : y*img.Stride+page.Width]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	page.Data = buf.Bytes()
+	return page, nil
+}
+
+// deflateRows packs img into samples bytes per pixel, applies horizontal
+// differencing (TIFF predictor 2) and compresses the result with zlib
+func deflateRows(img *image.RGBA, samples int) ([]byte, error) {
+	width := img.Rect.Dx()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	row := make([]byte, width*samples)
+
+	for y := 0; y < img.Rect.Dy(); y++ {
+		// TIFF associated alpha matches Go's premultiplied RGBA
+		src := img.Pix[y*img.Stride : y*img.Stride+4*width]
+		for x, i := 0, 0; x < width; x, i = x+1, i+samples {
+			copy(row[i:i+samples], src[4*x:4*x+samples])
+		}
+		for i := len(row) - 1; i >= samples; i-- {
+			row[i] -= row[i-samples]
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeTIFF writes img as a single-page TIFF image
+func EncodeTIFF(w io.Writer, img image.Image) error {
+	page, err := NewTIFFPage(img)
+	if err != nil {
+		return err
+	}
+
+	tw, err := NewTIFFWriter(w, 1)
+	if err != nil {
+		return err
+	}
+	if err := tw.WritePage(page); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// TIFFWriter streams pages into a (multi-page) little-endian TIFF file.
+// Each page's IFD is written just before its strip data, so the file can be
+// produced in a single pass without seeking.
+type TIFFWriter struct {
+	w       io.Writer
+	offset  int64
+	total   int
+	written int
+}
+
+// NewTIFFWriter writes the TIFF header for a file that will hold total pages
+func NewTIFFWriter(w io.Writer, total int) (*TIFFWriter, error) {
+	if total < 1 {
+		return nil, errors.New("a TIFF file needs at least one page")
+	}
+
+	header := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &TIFFWriter{w: w, offset: int64(len(header)), total: total}, nil
+}
+
+type ifdEntry struct {
+	tag    uint16
+	typ    uint16
+	values []uint32
+}
+
+// WritePage appends the next page to the file
+func (t *TIFFWriter) WritePage(page *TIFFPage) error {
+	if t.written == t.total {
+		return fmt.Errorf("TIFF file already has %d pages", t.total)
+	}
+
+	bits := make([]uint32, page.samples)
+	for i := range bits {
+		bits[i] = uint32(page.bits)
+	}
+
+	entries := []ifdEntry{
+		{tagNewSubfileType, typeLong, []uint32{0}},
+		{tagImageWidth, typeLong, []uint32{uint32(page.Width)}},
+		{tagImageLength, typeLong, []uint32{uint32(page.Height)}},
+		{tagBitsPerSample, typeShort, bits},
+		{tagCompression, typeShort, []uint32{uint32(page.compression)}},
+		{tagPhotometric, typeShort, []uint32{uint32(page.photometric)}},
+		{tagStripOffsets, typeLong, []uint32{0}}, // patched below
+		{tagSamplesPerPixel, typeShort, []uint32{uint32(page.samples)}},
+		{tagRowsPerStrip, typeLong, []uint32{uint32(page.Height)}},
+		{tagStripByteCounts, typeLong, []uint32{uint32(len(page.Data))}},
+		{tagPlanarConfig, typeShort, []uint32{1}},
+	}
+	if t.total > 1 {
+		entries[0].values[0] = 2 // one page of a multi-page image
+		entries = append(entries, ifdEntry{tagPageNumber, typeShort, []uint32{uint32(t.written), uint32(t.total)}})
+	}
+	if page.predictor {
+		entries = append(entries, ifdEntry{tagPredictor, typeShort, []uint32{2}})
+	}
+	if page.colorMap != nil {
+		entries = append(entries, ifdEntry{tagColorMap, typeShort, page.colorMap})
+	}
+	if page.alpha {
+		entries = append(entries, ifdEntry{tagExtraSamples, typeShort, []uint32{1}}) // associated alpha
+	}
+
+	// Layout: IFD, out-of-line values, strip data, padding to a word boundary
+	ifdSize := int64(2 + 12*len(entries) + 4)
+	var extra []byte
+	for _, e := range entries {
+		if e.size() > 4 {
+			extra = append(extra, e.bytes()...)
+		}
+	}
+	dataOffset := t.offset + ifdSize + int64(len(extra))
+	end := dataOffset + int64(len(page.Data))
+	pad := end % 2
+	if end+pad > 1<<32-1 {
+		return errors.New("TIFF file exceeds 4 GiB")
+	}
+	entries[6].values[0] = uint32(dataOffset)
+
+	var next uint32
+	if t.written+1 < t.total {
+		next = uint32(end + pad)
+	}
+
+	ifd := make([]byte, 0, ifdSize)
+	ifd = binary.LittleEndian.AppendUint16(ifd, uint16(len(entries)))
+	extraOffset := uint32(t.offset + ifdSize)
+	for _, e := range entries {
+		ifd = binary.LittleEndian.AppendUint16(ifd, e.tag)
+		ifd = binary.LittleEndian.AppendUint16(ifd, e.typ)
+		ifd = binary.LittleEndian.AppendUint32(ifd, uint32(len(e.values)))
+		if e.size() > 4 {
+			ifd = binary.LittleEndian.AppendUint32(ifd, extraOffset)
+			extraOffset += uint32(e.size())
+		} else {
+			value := e.bytes()
+			ifd = append(ifd, value...)
+			ifd = append(ifd, make([]byte, 4-len(value))...)
+		}
+	}
+	ifd = binary.LittleEndian.AppendUint32(ifd, next)
+
+	for _, chunk := range [][]byte{ifd, extra, page.Data, make([]byte, pad)} {
+		if _, err := t.w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	t.offset = end + pad
+	t.written++
+	return nil
+}
+
+func (e ifdEntry) size() int {
+	if e.typ == typeShort {
+		return 2 * len(e.values)
+	}
+	return 4 * len(e.values)
+}
+
+func (e ifdEntry) bytes() []byte {
+	out := make([]byte, 0, e.size())
+	for _, v := range e.values {
+		if e.typ == typeShort {
+			out = binary.LittleEndian.AppendUint16(out, uint16(v))
+		} else {
+			out = binary.LittleEndian.AppendUint32(out, v)
+		}
+	}
+	return out
+}
+
+// Close checks that every announced page was written
+func (t *TIFFWriter) Close() error {
+	if t.written != t.total {
+		return fmt.Errorf("TIFF file has %d of %d pages", t.written, t.total)
+	}
+	return nil
+}
@@ -0,0 +1,454 @@
+package imgenc
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"math/bits"
+)
+
+// VP8L limits and alphabet sizes from the WebP lossless bitstream
+// specification
+const (
+	webpMaxDimension = 1 << 14
+	webpLengthCodes  = 24
+	webpDistCodes    = 40
+	webpMaxLength    = 4096
+	webpMinMatch     = 3
+
+	// Distance codes 1 and 2 address the pixel above and the pixel to
+	// the left, whatever the image width
+	webpDistAbove = 1
+	webpDistLeft  = 2
+)
+
+// Order in which code length code lengths are stored
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// webpToken is either a literal ARGB pixel (length 0) or a backward
+// reference copying length pixels from distance code dist
+type webpToken struct {
+	argb   uint32
+	length uint16
+	dist   uint8
+}
+
+// EncodeWebP writes img as a lossless WebP (VP8L) image. It applies the
+// subtract-green transform and backward references to the pixel to the
+// left or above, which suits rendered pages with large flat areas well.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return errors.New("cannot encode an empty image as WebP")
+	}
+	if width > webpMaxDimension || height > webpMaxDimension {
+		return errors.New("image is too large for WebP (max 16384x16384)")
+	}
+
+	argb, hasAlpha := webpPixels(rgba)
+	tokens := webpTokenize(argb, width)
+
+	// Histograms for the five prefix codes: green + length, red, blue,
+	// alpha and distance
+	histos := [5][]int{
+		make([]int, 256+webpLengthCodes),
+		make([]int, 256),
+		make([]int, 256),
+		make([]int, 256),
+		make([]int, webpDistCodes),
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			histos[0][t.argb>>8&0xff]++
+			histos[1][t.argb>>16&0xff]++
+			histos[2][t.argb&0xff]++
+			histos[3][t.argb>>24]++
+			continue
+		}
+		code, _, _ := webpPrefix(int(t.length))
+		histos[0][256+code]++
+		code, _, _ = webpPrefix(int(t.dist))
+		histos[4][code]++
+	}
+
+	bw := &lsbWriter{}
+	bw.write(0x2f, 8) // VP8L signature
+	bw.write(uint64(width-1), 14)
+	bw.write(uint64(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+
+	bw.write(1, 1) // transform present
+	bw.write(2, 2) // subtract green
+	bw.write(0, 1) // no more transforms
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single prefix code group
+
+	var codes [5]prefixCode
+	for i, h := range histos {
+		codes[i] = writePrefixCode(bw, h)
+	}
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].write(bw, int(t.argb>>8&0xff))
+			codes[1].write(bw, int(t.argb>>16&0xff))
+			codes[2].write(bw, int(t.argb&0xff))
+			codes[3].write(bw, int(t.argb>>24))
+			continue
+		}
+		code, n, extra := webpPrefix(int(t.length))
+		codes[0].write(bw, 256+code)
+		bw.write(uint64(extra), n)
+		code, n, extra = webpPrefix(int(t.dist))
+		codes[4].write(bw, code)
+		bw.write(uint64(extra), n)
+	}
+	data := bw.flush()
+
+	chunk := len(data) + len(data)%2
+	header := make([]byte, 0, 20)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+chunk))
+	header = append(header, "WEBPVP8L"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// webpPixels converts img to non-premultiplied ARGB with the subtract-green
+// transform applied, and reports whether any pixel is translucent
+func webpPixels(img *image.RGBA) ([]uint32, bool) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	argb := make([]uint32, 0, width*height)
+	hasAlpha := false
+
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+4*width]
+		for i := 0; i < len(row); i += 4 {
+			r, g, b, a := uint32(row[i]), uint32(row[i+1]), uint32(row[i+2]), uint32(row[i+3])
+			if a != 0xff {
+				hasAlpha = true
+				if a == 0 {
+					r, g, b = 0, 0, 0
+				} else {
+					r, g, b = (r*0xff+a/2)/a, (g*0xff+a/2)/a, (b*0xff+a/2)/a
+				}
+			}
+			r, b = (r-g)&0xff, (b-g)&0xff
+			argb = append(argb, a<<24|r<<16|g<<8|b)
+		}
+	}
+	return argb, hasAlpha
+}
+
+// webpTokenize greedily replaces runs that repeat the pixel to the left or
+// the row above with backward references
+func webpTokenize(argb []uint32, width int) []webpToken {
+	var tokens []webpToken
+	for i := 0; i < len(argb); {
+		limit := min(len(argb)-i, webpMaxLength)
+
+		left := 0
+		if i > 0 {
+			for left < limit && argb[i+left] == argb[i+left-1] {
+				left++
+			}
+		}
+		above := 0
+		if i >= width {
+			for above < limit && argb[i+above] == argb[i+above-width] {
+				above++
+			}
+		}
+
+		switch {
+		case above >= webpMinMatch && above >= left:
+			tokens = append(tokens, webpToken{length: uint16(above), dist: webpDistAbove})
+			i += above
+		case left >= webpMinMatch:
+			tokens = append(tokens, webpToken{length: uint16(left), dist: webpDistLeft})
+			i += left
+		default:
+			tokens = append(tokens, webpToken{argb: argb[i]})
+			i++
+		}
+	}
+	return tokens
+}
+
+// webpPrefix splits a length or distance value (>= 1) into its prefix code
+// and extra bits
+func webpPrefix(v int) (code int, nbits uint, extra int) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := bits.Len(uint(d)) - 1
+	second := d >> (h - 1) & 1
+	nbits = uint(h - 1)
+	return 2*h + second, nbits, d & (1<<nbits - 1)
+}
+
+// lsbWriter packs bits least significant first, as VP8L expects
+type lsbWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *lsbWriter) write(v uint64, n uint) {
+	w.acc |= v << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *lsbWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
+
+// prefixCode is a canonical Huffman code ready for writing, with each code
+// word stored bit-reversed for the LSB-first writer
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+func newPrefixCode(lengths []uint8) prefixCode {
+	var count [16]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+
+	var next [16]int
+	code := 0
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	codes := make([]uint16, len(lengths))
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		codes[sym] = uint16(bits.Reverse16(uint16(c)) >> (16 - l))
+	}
+	return prefixCode{lengths: lengths, codes: codes}
+}
+
+func (p prefixCode) write(w *lsbWriter, sym int) {
+	w.write(uint64(p.codes[sym]), uint(p.lengths[sym]))
+}
+
+// writePrefixCode chooses code lengths for histogram h, stores the code in
+// the bitstream and returns it for encoding symbols
+func writePrefixCode(w *lsbWriter, h []int) prefixCode {
+	var used []int
+	for sym, n := range h {
+		if n > 0 {
+			used = append(used, sym)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	// Simple code: one or two symbols below 256
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		w.write(1, 1)
+		w.write(uint64(len(used)-1), 1)
+		if used[0] < 2 {
+			w.write(0, 1)
+			w.write(uint64(used[0]), 1)
+		} else {
+			w.write(1, 1)
+			w.write(uint64(used[0]), 8)
+		}
+		lengths := make([]uint8, len(h))
+		if len(used) == 2 {
+			w.write(uint64(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+		}
+		// A single symbol is coded with zero bits
+		return newPrefixCode(lengths)
+	}
+
+	lengths := huffmanLengths(h, 15)
+	w.write(0, 1) // normal code
+
+	// Code lengths are themselves coded: 0-15 literally, 17 and 18 for
+	// runs of zeros
+	type clToken struct{ sym, extra int }
+	var tokens []clToken
+	clHisto := make([]int, 19)
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens = append(tokens, clToken{int(lengths[i]), 0})
+			clHisto[lengths[i]]++
+			i++
+			continue
+		}
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			tokens = append(tokens, clToken{18, run - 11})
+			clHisto[18]++
+		case run >= 3:
+			tokens = append(tokens, clToken{17, run - 3})
+			clHisto[17]++
+		default:
+			for j := 0; j < run; j++ {
+				tokens = append(tokens, clToken{0, 0})
+			}
+			clHisto[0] += run
+		}
+		i += run
+	}
+
+	clLengths := huffmanLengths(clHisto, 7)
+	clCode := newPrefixCode(clLengths)
+
+	n := 19
+	for n > 4 && clLengths[webpCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+	w.write(uint64(n-4), 4)
+	for _, sym := range webpCodeLengthOrder[:n] {
+		w.write(uint64(clLengths[sym]), 3)
+	}
+
+	w.write(0, 1) // code lengths cover the whole alphabet
+	for _, t := range tokens {
+		clCode.write(w, t.sym)
+		switch t.sym {
+		case 17:
+			w.write(uint64(t.extra), 3)
+		case 18:
+			w.write(uint64(t.extra), 7)
+		}
+	}
+	return newPrefixCode(lengths)
+}
+
+// huffmanLengths builds Huffman code lengths no longer than limit for the
+// histogram h. Frequencies are flattened until the tree fits. When only one
+// symbol is used a second one is given a code too, so that decoders never
+// see a degenerate single-code tree.
+func huffmanLengths(h []int, limit int) []uint8 {
+	freq := make([]int, len(h))
+	copy(freq, h)
+
+	used := 0
+	for _, f := range freq {
+		if f > 0 {
+			used++
+		}
+	}
+	if used == 1 {
+		for sym := range freq {
+			if freq[sym] == 0 {
+				freq[sym] = 1
+				break
+			}
+		}
+	}
+
+	for {
+		lengths, maxLen := huffmanTree(freq)
+		if maxLen <= limit {
+			return lengths
+		}
+		for i, f := range freq {
+			if f > 0 {
+				freq[i] = (f + 1) / 2
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	freq        int
+	sym         int // leaf symbol, or -1
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].sym > h[j].sym
+}
+func (h huffmanHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x any)   { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// huffmanTree returns unrestricted Huffman code lengths for freq and the
+// longest of them
+func huffmanTree(freq []int) ([]uint8, int) {
+	h := &huffmanHeap{}
+	for sym, f := range freq {
+		if f > 0 {
+			*h = append(*h, &huffmanNode{freq: f, sym: sym})
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanNode)
+		b := heap.Pop(h).(*huffmanNode)
+		heap.Push(h, &huffmanNode{freq: a.freq + b.freq, sym: -1, left: a, right: b})
+	}
+
+	lengths := make([]uint8, len(freq))
+	maxLen := 0
+	var walk func(n *huffmanNode, depth int)
+	walk = func(n *huffmanNode, depth int) {
+		if n.left == nil {
+			lengths[n.sym] = uint8(depth)
+			maxLen = max(maxLen, depth)
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	if h.Len() == 1 {
+		walk((*h)[0], 0)
+	}
+	return lengths, maxLen
+}
@@ -0,0 +1,343 @@
+// Package metadata reads and writes the document metadata of a PDF: the
+// document information dictionary (Title, Author...) and the XMP packet of
+// the catalog.
+//
+// Reading goes through PDFium, like the rest of pdf2img. Writing uses
+// pdfcpu: Set appends an incremental update, leaving the original bytes (and
+// any signatures over them) untouched, while Strip rewrites the whole file
+// so that earlier revisions go too.
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/tu-usuario/pdf2img/pkg/password"
+)
+
+// Metadata is the document metadata of a PDF
+type Metadata struct {
+	Title        string     `json:"title,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	Subject      string     `json:"subject,omitempty"`
+	Keywords     string     `json:"keywords,omitempty"`
+	Creator      string     `json:"creator,omitempty"`  // Application that created the original document
+	Producer     string     `json:"producer,omitempty"` // Application that converted it to PDF
+	CreationDate *time.Time `json:"creation_date,omitempty"`
+	ModDate      *time.Time `json:"mod_date,omitempty"`
+	XMP          string     `json:"xmp,omitempty"` // Raw XMP packet of the document
+}
+
+// Changes lists the metadata to write. Nil fields are left as they are; an
+// empty string removes the entry.
+//
+// The information dictionary and the XMP packet are independent: viewers
+// that read XMP keep showing its values unless XMP is changed as well.
+type Changes struct {
+	Title        *string
+	Author       *string
+	Subject      *string
+	Keywords     *string
+	Creator      *string
+	Producer     *string
+	CreationDate *time.Time
+	ModDate      *time.Time
+	XMP          *string // Replaces the XMP packet; "" removes it
+}
+
+// textKeys are the text entries of the information dictionary
+var textKeys = []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer"}
+
+// Read returns the information dictionary of a document open in PDFium.
+// XMP is left empty, see ReadXMP.
+func Read(instance pdfium.Pdfium, doc references.FPDF_DOCUMENT) (*Metadata, error) {
+	values := map[string]string{}
+	for _, key := range append(textKeys, "CreationDate", "ModDate") {
+		text, err := instance.FPDF_GetMetaText(&requests.FPDF_GetMetaText{Document: doc, Tag: key})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		values[key] = text.Value
+	}
+
+	return &Metadata{
+		Title:        values["Title"],
+		Author:       values["Author"],
+		Subject:      values["Subject"],
+		Keywords:     values["Keywords"],
+		Creator:      values["Creator"],
+		Producer:     values["Producer"],
+		CreationDate: parseDate(values["CreationDate"]),
+		ModDate:      parseDate(values["ModDate"]),
+	}, nil
+}
+
+// ReadXMP returns the XMP packet of a PDF, or "" if it has none
+func ReadXMP(pdf []byte, pass string) (string, error) {
+	ctx, err := readContext(pdf, pass)
+	if err != nil {
+		return "", err
+	}
+	sd, err := xmpStream(ctx)
+	if err != nil || sd == nil {
+		return "", err
+	}
+	if err := sd.Decode(); err != nil {
+		return "", fmt.Errorf("failed to decode XMP packet: %w", err)
+	}
+	return string(sd.Content), nil
+}
+
+// Set applies changes to a PDF and returns the updated PDF: the original
+// bytes followed by an incremental update with the new information
+// dictionary and XMP packet. Encryption is kept.
+func Set(pdf []byte, pass string, changes *Changes) ([]byte, error) {
+	if changes == nil || (!changes.changesInfo() && changes.XMP == nil) {
+		return nil, fmt.Errorf("no metadata changes given")
+	}
+	ctx, err := readContext(pdf, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	if changes.changesInfo() {
+		if err := setInfo(ctx, changes); err != nil {
+			return nil, err
+		}
+	}
+	if changes.XMP != nil {
+		if err := setXMP(ctx, *changes.XMP); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx.Write.Increment = true
+	ctx.Write.Offset = int64(len(pdf))
+	var buf bytes.Buffer
+	buf.Write(pdf)
+	if !bytes.HasSuffix(pdf, []byte("\n")) {
+		buf.WriteByte('\n')
+		ctx.Write.Offset++
+	}
+	if err := api.WriteIncrement(ctx, &buf); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Strip returns a copy of a PDF without the information dictionary and the
+// XMP packet, rewritten so that no earlier revision keeps them. Encryption
+// is kept.
+func Strip(pdf []byte, pass string) ([]byte, error) {
+	// pdfcpu only writes the objects validation has loaded
+	conf := password.Configuration(pass)
+	conf.ValidationMode = model.ValidationRelaxed
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", password.Check(err, pass))
+	}
+	ctx.Info = nil
+	ctx.RootDict.Delete("Metadata")
+
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	// pdfcpu always writes an information dictionary, with itself as
+	// Producer and the current time as CreationDate and ModDate
+	out := buf.Bytes()
+	if ctx.Info != nil {
+		if err := blankDict(out, ctx.Write.Table[ctx.Info.ObjectNumber.Value()]); err != nil {
+			return nil, fmt.Errorf("failed to remove the information dictionary: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// blankDict empties the dictionary object written at offset in place,
+// padding it with spaces so the cross-reference offsets stay valid
+func blankDict(pdf []byte, offset int64) error {
+	if offset <= 0 || offset >= int64(len(pdf)) {
+		return fmt.Errorf("object not found")
+	}
+	obj := pdf[offset:]
+	start := bytes.Index(obj, []byte("obj"))
+	end := bytes.Index(obj, []byte("endobj"))
+	if start < 0 || end < start+3+len("<<>>")+2 {
+		return fmt.Errorf("object not found")
+	}
+	body := obj[start+3 : end]
+	if open := bytes.IndexByte(body, '<'); open < 0 || !bytes.HasPrefix(body[open:], []byte("<<")) {
+		return fmt.Errorf("object is not a dictionary")
+	}
+	for i := range body {
+		body[i] = ' '
+	}
+	copy(body[1:], "<<>>")
+	return nil
+}
+
+// changesInfo reports whether changes touch the information dictionary
+func (c *Changes) changesInfo() bool {
+	return c.Title != nil || c.Author != nil || c.Subject != nil || c.Keywords != nil ||
+		c.Creator != nil || c.Producer != nil || c.CreationDate != nil || c.ModDate != nil
+}
+
+// setInfo applies changes to the information dictionary
+func setInfo(ctx *model.Context, changes *Changes) error {
+	info, err := infoDict(ctx)
+	if err != nil {
+		return err
+	}
+	text := []*string{changes.Title, changes.Author, changes.Subject, changes.Keywords, changes.Creator, changes.Producer}
+	for i, key := range textKeys {
+		if text[i] == nil {
+			continue
+		}
+		if err := setText(info, key, *text[i]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	if changes.CreationDate != nil {
+		info["CreationDate"] = types.StringLiteral(types.DateString(*changes.CreationDate))
+	}
+	if changes.ModDate != nil {
+		info["ModDate"] = types.StringLiteral(types.DateString(*changes.ModDate))
+	}
+	ctx.Write.IncrementWithObjNr(ctx.Info.ObjectNumber.Value())
+	return nil
+}
+
+// readContext parses a PDF with pdfcpu
+func readContext(pdf []byte, pass string) (*model.Context, error) {
+	conf := password.Configuration(pass)
+	conf.ValidationMode = model.ValidationRelaxed
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", password.Check(err, pass))
+	}
+	return ctx, nil
+}
+
+// infoDict returns the information dictionary of a PDF, adding an empty one
+// if it has none
+func infoDict(ctx *model.Context) (types.Dict, error) {
+	if ctx.Info != nil {
+		d, err := ctx.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read information dictionary: %w", err)
+		}
+		if d != nil {
+			return d, nil
+		}
+	}
+
+	d := types.NewDict()
+	ref, err := ctx.IndRefForNewObject(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add information dictionary: %w", err)
+	}
+	ctx.Info = ref
+	return d, nil
+}
+
+// setText sets a text entry of the information dictionary, or removes it if
+// value is empty. Text that is not ASCII is written as UTF-16.
+func setText(d types.Dict, key, value string) error {
+	if value == "" {
+		d.Delete(key)
+		return nil
+	}
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("text is not valid UTF-8")
+	}
+
+	escape := types.Escape
+	for _, r := range value {
+		if r >= utf8.RuneSelf {
+			escape = types.EscapedUTF16String
+			break
+		}
+	}
+	s, err := escape(value)
+	if err != nil {
+		return err
+	}
+	d[key] = types.StringLiteral(*s)
+	return nil
+}
+
+// xmpStream returns the XMP stream of the catalog, or nil if it has none
+func xmpStream(ctx *model.Context) (*types.StreamDict, error) {
+	obj, ok := ctx.RootDict.Find("Metadata")
+	if !ok {
+		return nil, nil
+	}
+	sd, _, err := ctx.DereferenceStreamDict(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XMP packet: %w", err)
+	}
+	return sd, nil
+}
+
+// setXMP points the catalog to a new XMP stream, or removes it if packet is
+// empty. The stream is not compressed, so that tools scanning the file for
+// XMP find it.
+func setXMP(ctx *model.Context, packet string) error {
+	ctx.Write.IncrementWithObjNr(ctx.Root.ObjectNumber.Value())
+	if packet == "" {
+		ctx.RootDict.Delete("Metadata")
+		return nil
+	}
+
+	sd := &types.StreamDict{Dict: types.NewDict(), Content: []byte(packet)}
+	sd.InsertName("Type", "Metadata")
+	sd.InsertName("Subtype", "XML")
+	if err := sd.Encode(); err != nil {
+		return fmt.Errorf("failed to encode XMP packet: %w", err)
+	}
+	ref, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return fmt.Errorf("failed to add XMP packet: %w", err)
+	}
+	ctx.RootDict["Metadata"] = *ref
+	ctx.Write.IncrementWithObjNr(ref.ObjectNumber.Value())
+	return nil
+}
+
+// ParseDate parses a date given by a user: RFC 3339
+// (2024-01-31T12:00:00+01:00), a plain date (2024-01-31) or "now"
+func ParseDate(s string) (*time.Time, error) {
+	if s == "now" {
+		now := time.Now()
+		return &now, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not an RFC 3339 date, a YYYY-MM-DD date or \"now\"", s)
+}
+
+// parseDate parses a PDF date string, e.g. "D:20240131120000+01'00'".
+// Dates that cannot be parsed are dropped.
+func parseDate(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, ok := types.DateTime(s, true)
+	if !ok {
+		return nil
+	}
+	return &t
+}
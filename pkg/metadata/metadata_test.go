@@ -0,0 +1,174 @@
+package metadata
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/tu-usuario/pdf2img/internal/testpdf"
+)
+
+// minimalPDF builds a one-page PDF with the given information dictionary
+// entries, e.g. "/Title (Old)"
+func minimalPDF(info string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] >>",
+		"<< " + info + " >>",
+	}
+	return testpdf.Build(objects, "/Info 4 0 R")
+}
+
+// infoEntries returns the text entries of the information dictionary of pdf
+func infoEntries(t *testing.T, pdf []byte) map[string]string {
+	t.Helper()
+	ctx, err := readContext(pdf, "")
+	if err != nil {
+		t.Fatalf("readContext() error = %v", err)
+	}
+	d, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil {
+		t.Fatalf("DereferenceDict() error = %v", err)
+	}
+	entries := map[string]string{}
+	for key, value := range d {
+		s, err := types.StringOrHexLiteral(value)
+		if err != nil {
+			t.Fatalf("entry %s: %v", key, err)
+		}
+		entries[key] = *s
+	}
+	return entries
+}
+
+// TestSet tests changing entries as an incremental update
+func TestSet(t *testing.T) {
+	pdf := minimalPDF("/Title (Old) /Author (Someone) /Subject (Kept)")
+	title, author, xmp := "Título (nuevo)", "", `<x:xmpmeta xmlns:x="adobe:ns:meta/"/>`
+	created := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	out, err := Set(pdf, "", &Changes{Title: &title, Author: &author, CreationDate: &created, XMP: &xmp})
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !bytes.HasPrefix(out, pdf) {
+		t.Errorf("Set() did not keep the original bytes")
+	}
+
+	entries := infoEntries(t, out)
+	want := map[string]string{"Title": title, "Subject": "Kept", "CreationDate": "D:20240131120000+00'00'"}
+	for key, value := range want {
+		if entries[key] != value {
+			t.Errorf("%s = %q, want %q", key, entries[key], value)
+		}
+	}
+	if _, ok := entries["Author"]; ok {
+		t.Errorf("Author was not removed")
+	}
+
+	got, err := ReadXMP(out, "")
+	if err != nil || got != xmp {
+		t.Errorf("ReadXMP() = %q, %v, want %q", got, err, xmp)
+	}
+
+	if _, err := Set(pdf, "", &Changes{}); err == nil {
+		t.Errorf("Set() without changes: expected an error")
+	}
+}
+
+// TestStrip tests removing the information dictionary and XMP packet
+func TestStrip(t *testing.T) {
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"/>`
+	pdf, err := Set(minimalPDF("/Title (Secret) /Author (Someone)"), "", &Changes{XMP: &xmp})
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	out, err := Strip(pdf, "")
+	if err != nil {
+		t.Fatalf("Strip() error = %v", err)
+	}
+	if bytes.Contains(out, []byte("Secret")) || bytes.Contains(out, []byte("xmpmeta")) {
+		t.Errorf("Strip() kept the old metadata in the file")
+	}
+	if bytes.Contains(out, []byte("pdfcpu")) {
+		t.Errorf("Strip() left the writer's Producer in the file")
+	}
+	if entries := infoEntries(t, out); len(entries) != 0 {
+		t.Errorf("Strip() left information entries %v", entries)
+	}
+}
+
+// TestSetText tests how text entries are encoded
+func TestSetText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Plain", "Plain"},
+		{"With (parens)", `With \(parens\)`},
+		{"Año", "\xfe\xff\x00A\x00\xf1\x00o"},
+	}
+
+	for _, tt := range tests {
+		d := types.NewDict()
+		if err := setText(d, "Title", tt.value); err != nil {
+			t.Fatalf("setText(%q) error = %v", tt.value, err)
+		}
+		if got := d["Title"].(types.StringLiteral).Value(); got != tt.want {
+			t.Errorf("setText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	d := types.NewDict()
+	d.InsertString("Title", "Old")
+	if err := setText(d, "Title", ""); err != nil || d["Title"] != nil {
+		t.Errorf("setText() with an empty value did not remove the entry")
+	}
+	if err := setText(d, "Title", "\xff"); err == nil {
+		t.Errorf("setText() with invalid UTF-8: expected an error")
+	}
+}
+
+// TestParseDate tests parsing user dates
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-01-31T12:00:00+01:00", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC), false},
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"31/01/2024", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDate(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	if got, err := ParseDate("now"); err != nil || time.Since(*got) > time.Minute {
+		t.Errorf("ParseDate(\"now\") = %v, %v", got, err)
+	}
+}
+
+// TestParsePDFDate tests parsing PDF date strings
+func TestParsePDFDate(t *testing.T) {
+	if got := parseDate("D:20240131120000+01'00'"); got == nil || !got.Equal(time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseDate() = %v", got)
+	}
+	for _, s := range []string{"", "yesterday"} {
+		if got := parseDate(s); got != nil {
+			t.Errorf("parseDate(%q) = %v, want nil", s, got)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Package pagesel parses page selection expressions shared by the
+// converter, the splitter, the CLI and the MCP tools.
+//
+// An expression is a comma-separated list of terms:
+//
+//	7         a single page
+//	1-3       a range of pages
+//	10-       page 10 to the last page
+//	-1, -3-   pages counted from the end (-1 is the last page)
+//	first 5   the first five pages
+//	last 5    the last five pages (last = last 1)
+//	odd, even every odd or even page
+//	all       every page (also "*" or an empty expression)
+//	!4, !odd  exclude pages matched by a term
+//
+// The selected pages are the union of the included terms minus the
+// excluded ones; an expression with only exclusions starts from all pages.
+// Explicit page numbers beyond the document are reported as errors, while
+// "first N" and "last N" stop at the document boundary.
+package pagesel
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrOutOfRange is reported for page numbers that do not exist in the document
+var ErrOutOfRange = errors.New("page out of range")
+
+// Selection is a parsed page selection expression
+type Selection struct {
+	terms []term
+}
+
+type termKind int
+
+const (
+	termRange termKind = iota
+	termOdd
+	termEven
+	termFirst
+	termLast
+)
+
+// term is one comma-separated part of an expression. Range ends are page
+// numbers, negative when counted from the end; to == 0 means the last page.
+type term struct {
+	text    string
+	kind    termKind
+	exclude bool
+	from    int
+	to      int
+	count   int
+}
+
+// Parse parses a page selection expression. The pages it refers to are
+// only checked against the document by Pages.
+func Parse(expr string) (Selection, error) {
+	var sel Selection
+	expr = strings.TrimSpace(expr)
+	if expr == "" || expr == "*" {
+		return sel, nil
+	}
+
+	for _, part := range strings.Split(expr, ",") {
+		t, err := parseTerm(strings.TrimSpace(part))
+		if err != nil {
+			return Selection{}, fmt.Errorf("invalid page selection %q: %w", expr, err)
+		}
+		sel.terms = append(sel.terms, *t)
+	}
+	return sel, nil
+}
+
+// Range returns the selection startPage..endPage, where 0 means the first
+// and last page respectively. It backs the older StartPage/EndPage options.
+func Range(startPage, endPage int) Selection {
+	if startPage == 0 && endPage == 0 {
+		return Selection{}
+	}
+	if startPage == 0 {
+		startPage = 1
+	}
+	text := fmt.Sprintf("%d-", startPage)
+	if endPage != 0 {
+		text += strconv.Itoa(endPage)
+	}
+	return Selection{terms: []term{{text: text, kind: termRange, from: startPage, to: endPage}}}
+}
+
+// IsAll reports whether the selection picks every page without restriction
+func (s Selection) IsAll() bool {
+	return len(s.terms) == 0
+}
+
+// Pages resolves the selection against a document with total pages and
+// returns the selected page numbers in ascending order
+func (s Selection) Pages(total int) ([]int, error) {
+	if total < 1 {
+		return nil, errors.New("document has no pages")
+	}
+
+	selected := make([]bool, total+1)
+	included := false
+	for _, t := range s.terms {
+		if !t.exclude {
+			included = true
+		}
+	}
+	if !included {
+		for page := 1; page <= total; page++ {
+			selected[page] = true
+		}
+	}
+
+	// Exclusions win regardless of where they appear in the expression
+	for _, exclude := range []bool{false, true} {
+		for _, t := range s.terms {
+			if t.exclude != exclude {
+				continue
+			}
+			from, to, step, err := t.resolve(total)
+			if err != nil {
+				return nil, err
+			}
+			for page := from; page <= to; page += step {
+				selected[page] = !exclude
+			}
+		}
+	}
+
+	var pages []int
+	for page := 1; page <= total; page++ {
+		if selected[page] {
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("page selection %q matches no pages", s.String())
+	}
+	return pages, nil
+}
+
+// String returns the expression in its normalized form
+func (s Selection) String() string {
+	if len(s.terms) == 0 {
+		return "all"
+	}
+	parts := make([]string, len(s.terms))
+	for i, t := range s.terms {
+		parts[i] = t.text
+	}
+	return strings.Join(parts, ",")
+}
+
+// Resolve parses expr and resolves it against total pages
+func Resolve(expr string, total int) ([]int, error) {
+	sel, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Pages(total)
+}
+
+func parseTerm(part string) (*term, error) {
+	if part == "" {
+		return nil, errors.New("empty term")
+	}
+
+	t := &term{}
+	if strings.HasPrefix(part, "!") {
+		t.exclude = true
+		part = strings.TrimSpace(part[1:])
+	}
+
+	fields := strings.Fields(strings.ToLower(part))
+	t.text = strings.Join(fields, " ")
+	if t.exclude {
+		t.text = "!" + t.text
+	}
+
+	switch {
+	case len(fields) == 0:
+		return nil, errors.New("empty term")
+	case len(fields) == 1 && (fields[0] == "all" || fields[0] == "*"):
+		if t.exclude {
+			return nil, errors.New("cannot exclude all pages")
+		}
+		// "all" among other terms adds nothing that a full range would not
+		t.from, t.to = 1, 0
+		return t, nil
+	case len(fields) == 1 && fields[0] == "odd":
+		t.kind = termOdd
+		return t, nil
+	case len(fields) == 1 && fields[0] == "even":
+		t.kind = termEven
+		return t, nil
+	case len(fields) == 1 && fields[0] == "last":
+		t.kind, t.count = termLast, 1
+		return t, nil
+	case fields[0] == "first" || fields[0] == "last":
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q: expected %s N", part, fields[0])
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q: page count must be a positive number", part)
+		}
+		t.kind, t.count = termFirst, n
+		if fields[0] == "last" {
+			t.kind = termLast
+		}
+		return t, nil
+	}
+
+	// Ranges may be written with spaces around the dash
+	spec := strings.Join(fields, "")
+	t.text = spec
+	if t.exclude {
+		t.text = "!" + spec
+	}
+
+	from, rest, err := parseIndex(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", part, err)
+	}
+	t.from, t.to = from, from
+	if rest == "" {
+		return t, nil
+	}
+	if rest[0] != '-' {
+		return nil, fmt.Errorf("%q: unexpected %q", part, rest)
+	}
+	rest = rest[1:]
+	if rest == "" {
+		t.to = 0
+		return t, nil
+	}
+	to, rest, err := parseIndex(rest)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", part, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("%q: unexpected %q", part, rest)
+	}
+	t.to = to
+	return t, nil
+}
+
+// parseIndex reads a page number, negative when counted from the end, from
+// the start of s and returns the unparsed remainder
+func parseIndex(s string) (int, string, error) {
+	i := 0
+	if strings.HasPrefix(s, "-") {
+		i = 1
+	}
+	j := i
+	for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+		j++
+	}
+	if j == i {
+		return 0, "", fmt.Errorf("expected a page number")
+	}
+	n, err := strconv.Atoi(s[:j])
+	if err != nil {
+		return 0, "", fmt.Errorf("page number %s is too large", s[i:j])
+	}
+	if n == 0 {
+		return 0, "", errors.New("page numbers start at 1")
+	}
+	return n, s[j:], nil
+}
+
+// resolve returns the pages matched by t as from..to in steps of step
+func (t term) resolve(total int) (from, to, step int, err error) {
+	switch t.kind {
+	case termOdd:
+		return 1, total, 2, nil
+	case termEven:
+		return 2, total, 2, nil
+	case termFirst:
+		return 1, min(t.count, total), 1, nil
+	case termLast:
+		return max(total-t.count+1, 1), total, 1, nil
+	}
+
+	if from, err = page(t.from, total); err != nil {
+		return 0, 0, 0, err
+	}
+	to = total
+	if t.to != 0 {
+		if to, err = page(t.to, total); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	if from > to {
+		return 0, 0, 0, fmt.Errorf("page range %s is reversed (pages %d to %d)", strings.TrimPrefix(t.text, "!"), from, to)
+	}
+	return from, to, 1, nil
+}
+
+// page maps a page number, negative when counted from the end, to 1..total
+func page(n, total int) (int, error) {
+	p := n
+	if n < 0 {
+		p = total + 1 + n
+	}
+	if p < 1 || p > total {
+		return 0, fmt.Errorf("%w: %d (document has %d pages)", ErrOutOfRange, n, total)
+	}
+	return p, nil
+}
@@ -0,0 +1,112 @@
+package pagesel
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestResolve tests page selection expressions against a 10-page document
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []int
+	}{
+		{name: "empty", expr: "", want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{name: "all", expr: "all", want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{name: "single page", expr: "7", want: []int{7}},
+		{name: "list and ranges", expr: "1-3,7,9-", want: []int{1, 2, 3, 7, 9, 10}},
+		{name: "overlapping terms", expr: "5-8, 2-6, 6", want: []int{2, 3, 4, 5, 6, 7, 8}},
+		{name: "spaces around dash", expr: "2 - 4", want: []int{2, 3, 4}},
+		{name: "last page", expr: "-1", want: []int{10}},
+		{name: "from end open range", expr: "-3-", want: []int{8, 9, 10}},
+		{name: "from end closed range", expr: "2--8", want: []int{2, 3}},
+		{name: "first", expr: "first 2", want: []int{1, 2}},
+		{name: "last", expr: "last", want: []int{10}},
+		{name: "last n", expr: "Last 5", want: []int{6, 7, 8, 9, 10}},
+		{name: "last n beyond total", expr: "last 50", want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{name: "odd", expr: "odd", want: []int{1, 3, 5, 7, 9}},
+		{name: "even", expr: "even", want: []int{2, 4, 6, 8, 10}},
+		{name: "exclusions only", expr: "!1,!last 2", want: []int{2, 3, 4, 5, 6, 7, 8}},
+		{name: "exclusion before inclusion", expr: "!odd,1-5", want: []int{2, 4}},
+		{name: "exclude range", expr: "1-10,!3-8", want: []int{1, 2, 9, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.expr, 10)
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseErrors tests that malformed expressions are rejected
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"0", "1-0", "1,,2", "abc", "1-3-5", "last x", "first", "first 0", "!all", "3+", "99999999999999999999"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
+
+// TestPagesErrors tests selections that do not fit the document
+func TestPagesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expr       string
+		outOfRange bool
+	}{
+		{name: "page beyond total", expr: "1,11", outOfRange: true},
+		{name: "range end beyond total", expr: "5-20", outOfRange: true},
+		{name: "from end beyond first", expr: "-11", outOfRange: true},
+		{name: "open range beyond total", expr: "12-", outOfRange: true},
+		{name: "reversed range", expr: "8-3"},
+		{name: "nothing left", expr: "1-3,!1-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resolve(tt.expr, 10)
+			if err == nil {
+				t.Fatalf("Resolve(%q) should fail", tt.expr)
+			}
+			if errors.Is(err, ErrOutOfRange) != tt.outOfRange {
+				t.Errorf("Resolve(%q) error = %v, want ErrOutOfRange %v", tt.expr, err, tt.outOfRange)
+			}
+		})
+	}
+}
+
+// TestRange tests the StartPage/EndPage compatibility selection
+func TestRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		want       []int
+		wantErr    bool
+	}{
+		{name: "all pages", start: 0, end: 0, want: []int{1, 2, 3, 4, 5}},
+		{name: "from start", start: 3, end: 0, want: []int{3, 4, 5}},
+		{name: "to end", start: 0, end: 2, want: []int{1, 2}},
+		{name: "end beyond total", start: 2, end: 9, wantErr: true},
+		{name: "reversed", start: 4, end: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Range(tt.start, tt.end).Pages(5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Pages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Pages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
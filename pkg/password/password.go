@@ -0,0 +1,68 @@
+// Package password reports problems opening encrypted PDFs with errors that
+// callers can tell apart from other open failures.
+//
+// Neither PDFium nor pdfcpu distinguish a missing password from a wrong one,
+// so Check decides based on whether a password was supplied.
+package password
+
+import (
+	"errors"
+	"strings"
+
+	pdfiumerrors "github.com/klippa-app/go-pdfium/errors"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+var (
+	// ErrRequired is reported for encrypted PDFs opened without a password
+	ErrRequired = errors.New("PDF is password protected, a password is required")
+	// ErrWrong is reported when the password does not open the PDF
+	ErrWrong = errors.New("wrong password for PDF")
+	// ErrOwnerRequired is reported when the PDF opens with the user password
+	// but its permissions forbid the operation
+	ErrOwnerRequired = errors.New("PDF permissions restrict this operation, the owner password is required")
+)
+
+// restrictedMessage identifies pdfcpu's permission error, which has no
+// exported value to compare with
+const restrictedMessage = "operation restricted via pdfcpu's permission bits"
+
+// Check maps a PDFium or pdfcpu open error caused by the password to
+// ErrRequired, ErrWrong or ErrOwnerRequired. Other errors are returned
+// unchanged.
+func Check(err error, password string) error {
+	if err == nil || IsPasswordError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), restrictedMessage) {
+		return ErrOwnerRequired
+	}
+	if !fromBackend(err) {
+		return err
+	}
+	if password == "" {
+		return ErrRequired
+	}
+	return ErrWrong
+}
+
+// Configuration returns the pdfcpu configuration for a PDF protected with
+// pass, which may be either its user or its owner password ("" for none)
+func Configuration(pass string) *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = pass
+	conf.OwnerPW = pass
+	return conf
+}
+
+// IsPasswordError reports whether err is ErrRequired, ErrWrong or
+// ErrOwnerRequired
+func IsPasswordError(err error) bool {
+	return errors.Is(err, ErrRequired) || errors.Is(err, ErrWrong) || errors.Is(err, ErrOwnerRequired)
+}
+
+// fromBackend reports whether err is the password error of PDFium or pdfcpu
+func fromBackend(err error) bool {
+	return errors.Is(err, pdfiumerrors.ErrPassword) || errors.Is(err, pdfcpu.ErrWrongPassword)
+}
@@ -0,0 +1,45 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pdfiumerrors "github.com/klippa-app/go-pdfium/errors"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+)
+
+// TestCheck tests how open errors are mapped to ErrRequired, ErrWrong and
+// ErrOwnerRequired
+func TestCheck(t *testing.T) {
+	other := errors.New("3: incorrect format")
+
+	tests := []struct {
+		name     string
+		err      error
+		password string
+		want     error
+	}{
+		{name: "no error", err: nil, want: nil},
+		{name: "pdfium without password", err: pdfiumerrors.ErrPassword, want: ErrRequired},
+		{name: "pdfium with password", err: pdfiumerrors.ErrPassword, password: "x", want: ErrWrong},
+		{name: "pdfcpu without password", err: pdfcpu.ErrWrongPassword, want: ErrRequired},
+		{name: "pdfcpu with password", err: pdfcpu.ErrWrongPassword, password: "x", want: ErrWrong},
+		{name: "wrapped", err: fmt.Errorf("read failed: %w", pdfcpu.ErrWrongPassword), password: "x", want: ErrWrong},
+		{name: "pdfcpu permissions", err: fmt.Errorf("read failed: %w", errors.New("pdfcpu: operation restricted via pdfcpu's permission bits setting")), password: "x", want: ErrOwnerRequired},
+		{name: "already checked", err: fmt.Errorf("open: %w", ErrRequired), password: "x", want: ErrRequired},
+		{name: "other error", err: other, password: "x", want: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.err, tt.password)
+			if !errors.Is(got, tt.want) || (tt.want == nil && got != nil) {
+				t.Errorf("Check(%v, %q) = %v, want %v", tt.err, tt.password, got, tt.want)
+			}
+			if IsPasswordError(got) != (tt.want == ErrRequired || tt.want == ErrWrong || tt.want == ErrOwnerRequired) {
+				t.Errorf("IsPasswordError(%v) = %v", got, IsPasswordError(got))
+			}
+		})
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/tu-usuario/pdf2img/pkg/pagesel"
+	"github.com/tu-usuario/pdf2img/pkg/password"
 )
 
 // Splitter handles PDF page extraction operations
@@ -15,8 +19,10 @@ type Splitter struct{}
 type SplitOptions struct {
 	InputPath  string // Path to PDF file
 	OutputPath string // Output file path
-	StartPage  int    // Start page (1-indexed, 0 = first)
-	EndPage    int    // End page (1-indexed, 0 = last)
+	Pages      string // Page selection, e.g. "1-3,7,10-", "last 5", "odd" (see pagesel; default all)
+	StartPage  int    // Start page (1-indexed, 0 = first); ignored when Pages is set
+	EndPage    int    // End page (1-indexed, 0 = last); ignored when Pages is set
+	Password   string // Password of an encrypted PDF; the extracted PDF is written unencrypted
 }
 
 // SplitResult contains splitting results
@@ -38,28 +44,41 @@ func (s *Splitter) Split(opts *SplitOptions) (*SplitResult, error) {
 		return nil, err
 	}
 
+	conf := password.Configuration(opts.Password)
+
 	// Get total page count
-	pageCount, err := api.PageCountFile(opts.InputPath)
+	pageCount, err := pageCount(opts.InputPath, conf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get page count: %w", err)
+		return nil, fmt.Errorf("failed to get page count: %w", password.Check(err, opts.Password))
 	}
 
-	// Determine page range
-	startPage, endPage := determinePageRange(opts.StartPage, opts.EndPage, pageCount)
+	// Resolve the page selection against the document
+	sel, err := pageSelection(opts)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := sel.Pages(pageCount)
+	if err != nil {
+		return nil, err
+	}
 
-	// Build page selection string for pdfcpu
-	// pdfcpu uses 1-indexed pages
-	pageSelection := fmt.Sprintf("%d-%d", startPage, endPage)
+	// Pass the resolved page numbers to pdfcpu, which has its own
+	// (different) selection syntax
+	selected := make([]string, len(pages))
+	for i, page := range pages {
+		selected[i] = strconv.Itoa(page)
+	}
 
-	// Extract pages
-	err = api.ExtractPagesFile(opts.InputPath, opts.OutputPath, []string{pageSelection}, nil)
+	// Write the selected pages into a single PDF (ExtractPagesFile would
+	// write one file per page into a directory)
+	err = api.TrimFile(opts.InputPath, opts.OutputPath, selected, conf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract pages: %w", err)
+		return nil, fmt.Errorf("failed to extract pages: %w", password.Check(err, opts.Password))
 	}
 
 	result := &SplitResult{
 		TotalPages:     pageCount,
-		ExtractedPages: endPage - startPage + 1,
+		ExtractedPages: len(pages),
 		OutputPath:     opts.OutputPath,
 	}
 
@@ -81,6 +100,10 @@ func validateOptions(opts *SplitOptions) error {
 		return fmt.Errorf("output path is required")
 	}
 
+	if _, err := pageSelection(opts); err != nil {
+		return err
+	}
+
 	// Validate input file exists
 	if _, err := os.Stat(opts.InputPath); err != nil {
 		return fmt.Errorf("input file not found: %w", err)
@@ -97,23 +120,24 @@ func validateOptions(opts *SplitOptions) error {
 	return nil
 }
 
-func determinePageRange(startPage, endPage, totalPages int) (int, int) {
-	if startPage == 0 {
-		startPage = 1
-	}
-	if endPage == 0 {
-		endPage = totalPages
+// pageCount returns the number of pages of the PDF at path
+func pageCount(path string, conf *model.Configuration) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
 	}
+	defer f.Close()
+	return api.PageCount(f, conf)
+}
 
-	if startPage < 1 {
-		startPage = 1
-	}
-	if endPage > totalPages {
-		endPage = totalPages
+// pageSelection returns the pages requested either as a selection
+// expression or as the older StartPage/EndPage pair
+func pageSelection(opts *SplitOptions) (pagesel.Selection, error) {
+	if opts.Pages == "" {
+		return pagesel.Range(opts.StartPage, opts.EndPage), nil
 	}
-	if startPage > endPage {
-		startPage = endPage
+	if opts.StartPage != 0 || opts.EndPage != 0 {
+		return pagesel.Selection{}, fmt.Errorf("use either a page selection or start/end pages, not both")
 	}
-
-	return startPage, endPage
+	return pagesel.Parse(opts.Pages)
 }
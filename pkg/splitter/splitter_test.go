@@ -0,0 +1,86 @@
+package splitter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/tu-usuario/pdf2img/internal/testpdf"
+	"github.com/tu-usuario/pdf2img/pkg/password"
+)
+
+// TestSplitEncrypted tests splitting a PDF whose permissions forbid
+// everything but opening it with the user password
+func TestSplitEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "restricted.pdf")
+	writeRestrictedPDF(t, input, 3, "user", "owner")
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{name: "no password", want: password.ErrRequired},
+		{name: "wrong password", password: "nope", want: password.ErrWrong},
+		{name: "user password", password: "user", want: password.ErrOwnerRequired},
+		{name: "owner password", password: "owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Split(&SplitOptions{
+				InputPath:  input,
+				OutputPath: filepath.Join(dir, "out.pdf"),
+				Pages:      "2-3",
+				Password:   tt.password,
+			})
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Split() error = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			if result.TotalPages != 3 || result.ExtractedPages != 2 {
+				t.Errorf("Split() = %d of %d pages, want 2 of 3", result.ExtractedPages, result.TotalPages)
+			}
+		})
+	}
+}
+
+// writeRestrictedPDF writes a PDF of pageCount blank pages encrypted with
+// the given passwords and no permissions
+func writeRestrictedPDF(t *testing.T, path string, pageCount int, userPW, ownerPW string) {
+	t.Helper()
+
+	var objects []string
+	kids := ""
+	for i := range pageCount {
+		kids += fmt.Sprintf("%d 0 R ", 3+i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, pageCount),
+	)
+	for range pageCount {
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] >>")
+	}
+
+	conf := model.NewAESConfiguration(userPW, ownerPW, 256)
+	conf.Permissions = model.PermissionsNone
+	var out bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(testpdf.Build(objects, "")), &out, conf); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}